@@ -0,0 +1,140 @@
+package alerts
+
+import (
+	"github.com/yukitsune/minialert/db"
+	"sync"
+	"time"
+)
+
+const CriticalSeverity = "critical"
+
+// Escalation is a reminder that a group of critical alerts still hasn't been acknowledged.
+type Escalation struct {
+	Notification Notification
+	Step         db.EscalationStep
+
+	// StepNumber is the 1-based position of Step within the scrape config's escalation steps.
+	StepNumber int
+
+	// StartedAt is when the group started being escalated. Acknowledgements from before then don't apply.
+	StartedAt time.Time
+}
+
+// Escalator keeps track of which groups of critical alerts have been acknowledged, and decides when to escalate those which haven't.
+type Escalator interface {
+	Observe(notification Notification, steps []db.EscalationStep, now time.Time)
+	Acknowledge(guildId string, configName string, groupKey string) bool
+	Due(now time.Time) []Escalation
+	Clear(guildId string, configName string)
+}
+
+type escalation struct {
+	notification Notification
+	steps        []db.EscalationStep
+	startedAt    time.Time
+	nextStep     int
+	acknowledged bool
+}
+
+type inMemoryEscalator struct {
+	mu          sync.Mutex
+	escalations map[key]map[string]*escalation
+}
+
+func NewEscalator() Escalator {
+	return &inMemoryEscalator{
+		escalations: make(map[key]map[string]*escalation),
+	}
+}
+
+// Observe starts escalating the notification's group if it contains critical alerts, or stops escalating it once it doesn't.
+func (e *inMemoryEscalator) Observe(notification Notification, steps []db.EscalationStep, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	k := newKey(notification.GuildId, notification.ScrapeConfigName)
+	escalations, ok := e.escalations[k]
+	if !ok {
+		escalations = make(map[string]*escalation)
+		e.escalations[k] = escalations
+	}
+
+	if len(steps) == 0 || !hasCriticalAlerts(notification.Firing) {
+		delete(escalations, notification.GroupKey)
+		return
+	}
+
+	esc, ok := escalations[notification.GroupKey]
+	if !ok {
+		esc = &escalation{
+			startedAt: now,
+		}
+
+		escalations[notification.GroupKey] = esc
+	}
+
+	esc.notification = notification
+	esc.steps = steps
+}
+
+// Acknowledge stops escalating the group until it stops firing.
+// Returns false if the group wasn't being escalated.
+func (e *inMemoryEscalator) Acknowledge(guildId string, configName string, groupKey string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	esc, ok := e.escalations[newKey(guildId, configName)][groupKey]
+	if !ok {
+		return false
+	}
+
+	esc.acknowledged = true
+	return true
+}
+
+// Due returns the next escalation for each unacknowledged group whose delay has elapsed.
+func (e *inMemoryEscalator) Due(now time.Time) []Escalation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var due []Escalation
+	for _, escalations := range e.escalations {
+		for _, esc := range escalations {
+			if esc.acknowledged || esc.nextStep >= len(esc.steps) {
+				continue
+			}
+
+			step := esc.steps[esc.nextStep]
+			if now.Before(esc.startedAt.Add(time.Duration(step.DelayMinutes) * time.Minute)) {
+				continue
+			}
+
+			esc.nextStep++
+			due = append(due, Escalation{
+				Notification: esc.notification,
+				Step:         step,
+				StepNumber:   esc.nextStep,
+				StartedAt:    esc.startedAt,
+			})
+		}
+	}
+
+	return due
+}
+
+func (e *inMemoryEscalator) Clear(guildId string, configName string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.escalations, newKey(guildId, configName))
+}
+
+func hasCriticalAlerts(events []Event) bool {
+	for _, event := range events {
+		if event.Alert.Labels["severity"] == CriticalSeverity {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package alerts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/prometheus"
+	"testing"
+	"time"
+)
+
+var testEscalationSteps = []db.EscalationStep{
+	{DelayMinutes: 5, RoleId: "oncall"},
+	{DelayMinutes: 15, UserId: "lead"},
+}
+
+func newCriticalNotification() Notification {
+	return Notification{
+		GuildId:          "guild",
+		ScrapeConfigName: "config",
+		GroupKey:         "group",
+		Firing: []Event{
+			{Alert: prometheus.Alert{Labels: map[string]string{"alertname": "foo", "severity": "critical"}}},
+		},
+	}
+}
+
+func TestDueEscalatesThroughEachStep(t *testing.T) {
+
+	// Arrange
+	escalator := NewEscalator()
+	now := time.Now()
+	escalator.Observe(newCriticalNotification(), testEscalationSteps, now)
+
+	// Act
+	early := escalator.Due(now.Add(time.Minute))
+	first := escalator.Due(now.Add(5 * time.Minute))
+	waiting := escalator.Due(now.Add(10 * time.Minute))
+	second := escalator.Due(now.Add(15 * time.Minute))
+	done := escalator.Due(now.Add(time.Hour))
+
+	// Assert
+	assert.Empty(t, early)
+	assert.Len(t, first, 1)
+	assert.Equal(t, "oncall", first[0].Step.RoleId)
+	assert.Empty(t, waiting)
+	assert.Len(t, second, 1)
+	assert.Equal(t, "lead", second[0].Step.UserId)
+	assert.Equal(t, 2, second[0].StepNumber)
+	assert.Equal(t, now, second[0].StartedAt)
+	assert.Empty(t, done)
+}
+
+func TestDueStopsEscalatingOnceAcknowledged(t *testing.T) {
+
+	// Arrange
+	escalator := NewEscalator()
+	now := time.Now()
+	notification := newCriticalNotification()
+	escalator.Observe(notification, testEscalationSteps, now)
+
+	// Act
+	ok := escalator.Acknowledge(notification.GuildId, notification.ScrapeConfigName, notification.GroupKey)
+
+	// Repeated notifications shouldn't restart escalation
+	escalator.Observe(notification, testEscalationSteps, now.Add(time.Minute))
+
+	// Assert
+	assert.True(t, ok)
+	assert.Empty(t, escalator.Due(now.Add(time.Hour)))
+}
+
+func TestObserveIgnoresNonCriticalAlerts(t *testing.T) {
+
+	// Arrange
+	escalator := NewEscalator()
+	now := time.Now()
+	notification := newCriticalNotification()
+	escalator.Observe(notification, testEscalationSteps, now)
+
+	// Act
+	notification.Firing[0].Alert.Labels["severity"] = "warning"
+	escalator.Observe(notification, testEscalationSteps, now.Add(time.Minute))
+
+	// Assert
+	assert.Empty(t, escalator.Due(now.Add(time.Hour)))
+}
+
+func TestDueStopsEscalatingOnceGroupIsSilenced(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	grouper := NewGrouper()
+	escalator := NewEscalator()
+	now := time.Now()
+	criticalAlerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo", "severity": "critical"}},
+	}
+
+	events := tracker.Process("guild", "config", criticalAlerts, now)
+	grouper.Add("guild", "config", testGroupingOptions, events, now)
+	for _, notification := range grouper.Flush(now.Add(time.Minute)) {
+		escalator.Observe(notification, testEscalationSteps, now.Add(time.Minute))
+	}
+
+	// The alert is still firing, but has been filtered out by a silence
+	silencedAt := now.Add(2 * time.Minute)
+	tracker.Process("guild", "config", criticalAlerts, silencedAt)
+	grouper.Add("guild", "config", testGroupingOptions, nil, silencedAt)
+
+	// Act
+	notifications := grouper.Flush(now.Add(7 * time.Minute))
+	for _, notification := range notifications {
+		escalator.Observe(notification, testEscalationSteps, now.Add(7*time.Minute))
+	}
+
+	due := escalator.Due(now.Add(time.Hour))
+
+	// Assert
+	assert.Len(t, notifications, 1)
+	assert.Empty(t, notifications[0].Firing)
+	assert.Len(t, notifications[0].Resolved, 1)
+	assert.Empty(t, due)
+}
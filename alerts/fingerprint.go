@@ -0,0 +1,28 @@
+package alerts
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// Fingerprint returns a stable identifier for the given set of labels.
+// Two label sets with the same keys and values will always produce the same fingerprint, regardless of ordering.
+func Fingerprint(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	hash := fnv.New64a()
+	for _, k := range keys {
+		hash.Write([]byte(k))
+		hash.Write([]byte{0xff})
+		hash.Write([]byte(labels[k]))
+		hash.Write([]byte{0xff})
+	}
+
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
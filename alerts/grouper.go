@@ -0,0 +1,361 @@
+package alerts
+
+import (
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/prometheus"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	GroupByAll = "..."
+
+	DefaultGroupWait      = 30 * time.Second
+	DefaultGroupInterval  = 5 * time.Minute
+	DefaultRepeatInterval = 4 * time.Hour
+)
+
+var DefaultGroupBy = []string{"alertname"}
+
+type GroupingOptions struct {
+	GroupBy        []string
+	GroupWait      time.Duration
+	GroupInterval  time.Duration
+	RepeatInterval time.Duration
+}
+
+// NewGroupingOptions creates a GroupingOptions from the given scrape config, using the defaults for any unset values.
+func NewGroupingOptions(config *db.ScrapeConfig) GroupingOptions {
+	opts := GroupingOptions{
+		GroupBy:        config.GroupBy,
+		GroupWait:      time.Duration(config.GroupWaitSeconds) * time.Second,
+		GroupInterval:  time.Duration(config.GroupIntervalSeconds) * time.Second,
+		RepeatInterval: time.Duration(config.RepeatIntervalSeconds) * time.Second,
+	}
+
+	if len(opts.GroupBy) == 0 {
+		opts.GroupBy = DefaultGroupBy
+	}
+
+	if opts.GroupWait <= 0 {
+		opts.GroupWait = DefaultGroupWait
+	}
+
+	if opts.GroupInterval <= 0 {
+		opts.GroupInterval = DefaultGroupInterval
+	}
+
+	if opts.RepeatInterval <= 0 {
+		opts.RepeatInterval = DefaultRepeatInterval
+	}
+
+	return opts
+}
+
+func (o GroupingOptions) groupLabels(labels map[string]string) map[string]string {
+	groupLabels := make(map[string]string)
+	for _, name := range o.GroupBy {
+		if name == GroupByAll {
+			for k, v := range labels {
+				groupLabels[k] = v
+			}
+
+			return groupLabels
+		}
+
+		if v, ok := labels[name]; ok {
+			groupLabels[name] = v
+		}
+	}
+
+	return groupLabels
+}
+
+// Notification is a batch of alerts from a single group which are ready to be sent.
+type Notification struct {
+	GuildId          string
+	ScrapeConfigName string
+	GroupKey         string
+	GroupLabels      map[string]string
+	Firing           []Event
+	Resolved         []Event
+}
+
+// Grouper batches related alerts together, and decides when each batch should be sent.
+type Grouper interface {
+	Add(guildId string, configName string, opts GroupingOptions, events []Event, now time.Time)
+	Flush(now time.Time) []Notification
+	Clear(guildId string, configName string)
+}
+
+type groupedAlert struct {
+	event    Event
+	notified bool
+}
+
+type group struct {
+	guildId        string
+	configName     string
+	key            string
+	labels         map[string]string
+	opts           GroupingOptions
+	alerts         map[string]*groupedAlert
+	createdAt      time.Time
+	lastNotifiedAt time.Time
+	changed        bool
+}
+
+func (g *group) hasFiringAlerts() bool {
+	for _, alert := range g.alerts {
+		if alert.event.Type != ResolvedEvent {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *group) shouldNotify(now time.Time) bool {
+	if g.lastNotifiedAt.IsZero() {
+		return !now.Before(g.createdAt.Add(g.opts.GroupWait))
+	}
+
+	if g.changed {
+		return !now.Before(g.lastNotifiedAt.Add(g.opts.GroupInterval))
+	}
+
+	return g.hasFiringAlerts() && !now.Before(g.lastNotifiedAt.Add(g.opts.RepeatInterval))
+}
+
+type inMemoryGrouper struct {
+	mu     sync.Mutex
+	groups map[key]map[string]*group
+}
+
+func NewGrouper() Grouper {
+	return &inMemoryGrouper{
+		groups: make(map[key]map[string]*group),
+	}
+}
+
+func (g *inMemoryGrouper) Add(guildId string, configName string, opts GroupingOptions, events []Event, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	k := newKey(guildId, configName)
+	groups, ok := g.groups[k]
+	if !ok {
+		groups = make(map[string]*group)
+		g.groups[k] = groups
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range events {
+		groupLabels := opts.groupLabels(event.Alert.Labels)
+		groupKey := Fingerprint(groupLabels)
+		seen[event.Fingerprint] = true
+
+		grp, ok := groups[groupKey]
+		if event.Type == ResolvedEvent {
+			if !ok {
+				continue
+			}
+
+			alert, ok := grp.alerts[event.Fingerprint]
+			if !ok || alert.event.Type == ResolvedEvent {
+				continue
+			}
+
+			alert.event = event
+			if alert.notified {
+				grp.changed = true
+			}
+
+			continue
+		}
+
+		if !ok {
+			grp = &group{
+				guildId:    guildId,
+				configName: configName,
+				key:        groupKey,
+				labels:     groupLabels,
+				alerts:     make(map[string]*groupedAlert),
+				createdAt:  now,
+			}
+
+			groups[groupKey] = grp
+		}
+
+		grp.opts = opts
+
+		alert, ok := grp.alerts[event.Fingerprint]
+		if !ok || alert.event.Type == ResolvedEvent {
+			grp.alerts[event.Fingerprint] = &groupedAlert{event: event}
+			grp.changed = true
+			continue
+		}
+
+		alert.event = event
+	}
+
+	// Any firing alerts which weren't reported this time have been silenced or inhibited.
+	// Those which haven't been notified about yet can be dropped silently, but the others are reported as resolved, so that they stop being shown as firing and escalated.
+	for groupKey, grp := range groups {
+		for fingerprint, alert := range grp.alerts {
+			if seen[fingerprint] || alert.event.Type == ResolvedEvent {
+				continue
+			}
+
+			if !alert.notified {
+				delete(grp.alerts, fingerprint)
+				continue
+			}
+
+			alert.event.Type = ResolvedEvent
+			alert.event.ResolvedAt = now
+			grp.changed = true
+		}
+
+		if len(grp.alerts) == 0 {
+			delete(groups, groupKey)
+		}
+	}
+}
+
+func (g *inMemoryGrouper) Flush(now time.Time) []Notification {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var notifications []Notification
+	for _, groups := range g.groups {
+		for groupKey, grp := range groups {
+
+			// Nothing to tell anyone about if the alerts resolved before the first notification was sent
+			if grp.lastNotifiedAt.IsZero() && !grp.hasFiringAlerts() {
+				delete(groups, groupKey)
+				continue
+			}
+
+			if !grp.shouldNotify(now) {
+				continue
+			}
+
+			notification := Notification{
+				GuildId:          grp.guildId,
+				ScrapeConfigName: grp.configName,
+				GroupKey:         grp.key,
+				GroupLabels:      grp.labels,
+			}
+
+			for fingerprint, alert := range grp.alerts {
+				if alert.event.Type == ResolvedEvent {
+					if alert.notified {
+						notification.Resolved = append(notification.Resolved, alert.event)
+					}
+
+					delete(grp.alerts, fingerprint)
+					continue
+				}
+
+				alert.notified = true
+				notification.Firing = append(notification.Firing, alert.event)
+			}
+
+			sortEvents(notification.Firing)
+			sortEvents(notification.Resolved)
+
+			grp.lastNotifiedAt = now
+			grp.changed = false
+
+			if len(grp.alerts) == 0 {
+				delete(groups, groupKey)
+			}
+
+			if len(notification.Firing) > 0 || len(notification.Resolved) > 0 {
+				notifications = append(notifications, notification)
+			}
+		}
+	}
+
+	return notifications
+}
+
+func (g *inMemoryGrouper) Clear(guildId string, configName string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.groups, newKey(guildId, configName))
+}
+
+// GroupAlerts groups the firing alerts the same way a Grouper would, returning a Notification for each group straight away.
+// Group keys match the ones used by a Grouper, so that the groups can be acknowledged.
+func GroupAlerts(guildId string, configName string, opts GroupingOptions, firingAlerts prometheus.Alerts, now time.Time) []Notification {
+	groups := make(map[string]*Notification)
+	seen := make(map[string]bool)
+
+	var groupKeys []string
+	for _, alert := range firingAlerts {
+		fingerprint := Fingerprint(alert.Labels)
+
+		// The same alert may be reported more than once, only consider the first one
+		if seen[fingerprint] {
+			continue
+		}
+
+		seen[fingerprint] = true
+
+		groupLabels := opts.groupLabels(alert.Labels)
+		groupKey := Fingerprint(groupLabels)
+
+		notification, ok := groups[groupKey]
+		if !ok {
+			notification = &Notification{
+				GuildId:          guildId,
+				ScrapeConfigName: configName,
+				GroupKey:         groupKey,
+				GroupLabels:      groupLabels,
+			}
+
+			groups[groupKey] = notification
+			groupKeys = append(groupKeys, groupKey)
+		}
+
+		firingSince := alert.ActiveAt
+		if firingSince.IsZero() {
+			firingSince = now
+		}
+
+		notification.Firing = append(notification.Firing, Event{
+			Type:        FiringEvent,
+			Fingerprint: fingerprint,
+			Alert:       alert,
+			FiringSince: firingSince,
+		})
+	}
+
+	notifications := make([]Notification, 0, len(groupKeys))
+	for _, groupKey := range groupKeys {
+		notification := groups[groupKey]
+		sortEvents(notification.Firing)
+		notifications = append(notifications, *notification)
+	}
+
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].Firing[0].Alert.Labels["alertname"] < notifications[j].Firing[0].Alert.Labels["alertname"]
+	})
+
+	return notifications
+}
+
+func sortEvents(events []Event) {
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i].Alert.Labels["alertname"], events[j].Alert.Labels["alertname"]
+		if a != b {
+			return a < b
+		}
+
+		return events[i].Fingerprint < events[j].Fingerprint
+	})
+}
@@ -0,0 +1,179 @@
+package alerts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/prometheus"
+	"testing"
+	"time"
+)
+
+var testGroupingOptions = GroupingOptions{
+	GroupBy:        []string{"alertname"},
+	GroupWait:      30 * time.Second,
+	GroupInterval:  5 * time.Minute,
+	RepeatInterval: time.Hour,
+}
+
+func TestFlushWaitsForGroupWait(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	grouper := NewGrouper()
+	now := time.Now()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	events := tracker.Process("guild", "config", alerts, now)
+	grouper.Add("guild", "config", testGroupingOptions, events, now)
+
+	// Act
+	early := grouper.Flush(now.Add(10 * time.Second))
+	late := grouper.Flush(now.Add(30 * time.Second))
+
+	// Assert
+	assert.Empty(t, early)
+	assert.Len(t, late, 1)
+	assert.Len(t, late[0].Firing, 1)
+}
+
+func TestFlushGroupsAlertsByLabels(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	grouper := NewGrouper()
+	now := time.Now()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo", "instance": "a"}},
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo", "instance": "b"}},
+		prometheus.Alert{Labels: map[string]string{"alertname": "bar", "instance": "a"}},
+	}
+
+	events := tracker.Process("guild", "config", alerts, now)
+	grouper.Add("guild", "config", testGroupingOptions, events, now)
+
+	// Act
+	notifications := grouper.Flush(now.Add(time.Minute))
+
+	// Assert
+	assert.Len(t, notifications, 2)
+	for _, notification := range notifications {
+		switch notification.GroupLabels["alertname"] {
+		case "foo":
+			assert.Len(t, notification.Firing, 2)
+		case "bar":
+			assert.Len(t, notification.Firing, 1)
+		default:
+			t.Errorf("unexpected group: %v", notification.GroupLabels)
+		}
+	}
+}
+
+func TestFlushSendsResolvedAlertsAfterGroupInterval(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	grouper := NewGrouper()
+	now := time.Now()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	events := tracker.Process("guild", "config", alerts, now)
+	grouper.Add("guild", "config", testGroupingOptions, events, now)
+	grouper.Flush(now.Add(time.Minute))
+
+	resolvedAt := now.Add(2 * time.Minute)
+	events = tracker.Process("guild", "config", prometheus.Alerts{}, resolvedAt)
+	grouper.Add("guild", "config", testGroupingOptions, events, resolvedAt)
+
+	// Act
+	early := grouper.Flush(now.Add(3 * time.Minute))
+	late := grouper.Flush(now.Add(6 * time.Minute))
+
+	// Assert
+	assert.Empty(t, early)
+	assert.Len(t, late, 1)
+	assert.Empty(t, late[0].Firing)
+	assert.Len(t, late[0].Resolved, 1)
+}
+
+func TestFlushRepeatsAfterRepeatInterval(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	grouper := NewGrouper()
+	now := time.Now()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	events := tracker.Process("guild", "config", alerts, now)
+	grouper.Add("guild", "config", testGroupingOptions, events, now)
+	grouper.Flush(now.Add(time.Minute))
+
+	// Act
+	early := grouper.Flush(now.Add(30 * time.Minute))
+	late := grouper.Flush(now.Add(61 * time.Minute))
+
+	// Assert
+	assert.Empty(t, early)
+	assert.Len(t, late, 1)
+	assert.Len(t, late[0].Firing, 1)
+}
+
+func TestFlushDropsAlertsResolvedBeforeFirstNotification(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	grouper := NewGrouper()
+	now := time.Now()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	events := tracker.Process("guild", "config", alerts, now)
+	grouper.Add("guild", "config", testGroupingOptions, events, now)
+
+	resolvedAt := now.Add(10 * time.Second)
+	events = tracker.Process("guild", "config", prometheus.Alerts{}, resolvedAt)
+	grouper.Add("guild", "config", testGroupingOptions, events, resolvedAt)
+
+	// Act
+	notifications := grouper.Flush(now.Add(time.Minute))
+
+	// Assert
+	assert.Empty(t, notifications)
+}
+
+func TestGroupAlertsUsesSameGroupsAsGrouper(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	grouper := NewGrouper()
+	now := time.Now()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo", "instance": "a"}},
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo", "instance": "b"}},
+		prometheus.Alert{Labels: map[string]string{"alertname": "bar", "instance": "a"}},
+		prometheus.Alert{Labels: map[string]string{"alertname": "bar", "instance": "a"}},
+	}
+
+	events := tracker.Process("guild", "config", alerts, now)
+	grouper.Add("guild", "config", testGroupingOptions, events, now)
+	flushed := grouper.Flush(now.Add(time.Minute))
+
+	// Act
+	notifications := GroupAlerts("guild", "config", testGroupingOptions, alerts, now)
+
+	// Assert
+	assert.Len(t, notifications, 2)
+	assert.Equal(t, "bar", notifications[0].GroupLabels["alertname"])
+	assert.Len(t, notifications[0].Firing, 1)
+	assert.Equal(t, "foo", notifications[1].GroupLabels["alertname"])
+	assert.Len(t, notifications[1].Firing, 2)
+
+	for _, notification := range notifications {
+		assert.Contains(t, []string{flushed[0].GroupKey, flushed[1].GroupKey}, notification.GroupKey)
+	}
+}
@@ -0,0 +1,181 @@
+package alerts
+
+import (
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/prometheus"
+	"sync"
+	"time"
+)
+
+// DefaultHeartbeatTimeout is how long the heartbeat alert can be missing for when the scrape config doesn't specify a timeout.
+const DefaultHeartbeatTimeout = 10 * time.Minute
+
+type HeartbeatStatus string
+
+const (
+	// HeartbeatMissing means the heartbeat alert hasn't been seen for longer than the timeout, so the alerting pipeline is likely broken.
+	HeartbeatMissing HeartbeatStatus = "missing"
+
+	// HeartbeatRestored means the heartbeat alert has been seen again after going missing.
+	HeartbeatRestored HeartbeatStatus = "restored"
+)
+
+// HeartbeatEvent is a change in whether a scrape config's heartbeat alert is being received.
+type HeartbeatEvent struct {
+	Status           HeartbeatStatus
+	GuildId          string
+	ScrapeConfigName string
+	AlertName        string
+	Timeout          time.Duration
+	LastSeen         time.Time
+}
+
+// HeartbeatOptions determines which alert must always be firing for a scrape config.
+type HeartbeatOptions struct {
+	AlertName string
+	Timeout   time.Duration
+}
+
+// NewHeartbeatOptions creates a HeartbeatOptions from the given scrape config, using the default timeout if it isn't set.
+func NewHeartbeatOptions(config *db.ScrapeConfig) HeartbeatOptions {
+	opts := HeartbeatOptions{
+		AlertName: config.HeartbeatAlertName,
+		Timeout:   time.Duration(config.HeartbeatTimeoutSeconds) * time.Second,
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHeartbeatTimeout
+	}
+
+	return opts
+}
+
+// HeartbeatMonitor acts as a dead man's switch, reporting when a scrape config's heartbeat alert stops firing.
+type HeartbeatMonitor interface {
+	Observe(guildId string, configName string, opts HeartbeatOptions, alerts prometheus.Alerts, now time.Time)
+	Check(now time.Time) []HeartbeatEvent
+
+	// Missing returns when the scrape config's heartbeat alert was last seen, if it's been reported as missing by Check.
+	Missing(guildId string, configName string) (time.Time, bool)
+	Clear(guildId string, configName string)
+}
+
+type heartbeat struct {
+	guildId    string
+	configName string
+	opts       HeartbeatOptions
+	lastSeen   time.Time
+	missing    bool
+	restored   bool
+}
+
+type inMemoryHeartbeatMonitor struct {
+	mu         sync.Mutex
+	heartbeats map[key]*heartbeat
+}
+
+func NewHeartbeatMonitor() HeartbeatMonitor {
+	return &inMemoryHeartbeatMonitor{
+		heartbeats: make(map[key]*heartbeat),
+	}
+}
+
+// Observe records whether the heartbeat alert was present in the scraped alerts.
+// The timeout starts from when the scrape config is first observed, so the heartbeat isn't reported as missing straight away.
+func (m *inMemoryHeartbeatMonitor) Observe(guildId string, configName string, opts HeartbeatOptions, alerts prometheus.Alerts, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := newKey(guildId, configName)
+	if len(opts.AlertName) == 0 {
+		delete(m.heartbeats, k)
+		return
+	}
+
+	hb, ok := m.heartbeats[k]
+	if !ok || hb.opts.AlertName != opts.AlertName {
+		hb = &heartbeat{
+			guildId:    guildId,
+			configName: configName,
+			lastSeen:   now,
+		}
+
+		m.heartbeats[k] = hb
+	}
+
+	hb.opts = opts
+
+	if !hasAlert(alerts, opts.AlertName) {
+		return
+	}
+
+	hb.lastSeen = now
+	if hb.missing {
+		hb.missing = false
+		hb.restored = true
+	}
+}
+
+// Check returns an event for each heartbeat which has just gone missing or been restored.
+func (m *inMemoryHeartbeatMonitor) Check(now time.Time) []HeartbeatEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []HeartbeatEvent
+	for _, hb := range m.heartbeats {
+		var status HeartbeatStatus
+		switch {
+		case hb.restored:
+			hb.restored = false
+			status = HeartbeatRestored
+
+		case !hb.missing && now.Sub(hb.lastSeen) > hb.opts.Timeout:
+			hb.missing = true
+			status = HeartbeatMissing
+
+		default:
+			continue
+		}
+
+		events = append(events, HeartbeatEvent{
+			Status:           status,
+			GuildId:          hb.guildId,
+			ScrapeConfigName: hb.configName,
+			AlertName:        hb.opts.AlertName,
+			Timeout:          hb.opts.Timeout,
+			LastSeen:         hb.lastSeen,
+		})
+	}
+
+	return events
+}
+
+func (m *inMemoryHeartbeatMonitor) Missing(guildId string, configName string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hb, ok := m.heartbeats[newKey(guildId, configName)]
+	if !ok || !hb.missing {
+		return time.Time{}, false
+	}
+
+	return hb.lastSeen, true
+}
+
+func (m *inMemoryHeartbeatMonitor) Clear(guildId string, configName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.heartbeats, newKey(guildId, configName))
+}
+
+// hasAlert returns true if any of the alerts have the given alertname.
+func hasAlert(alerts prometheus.Alerts, alertName string) bool {
+	for _, alert := range alerts {
+		if alert.Labels["alertname"] == alertName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package alerts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/prometheus"
+	"testing"
+	"time"
+)
+
+var testHeartbeatOptions = HeartbeatOptions{AlertName: "Watchdog", Timeout: 5 * time.Minute}
+
+var testWatchdogAlerts = prometheus.Alerts{
+	{Labels: map[string]string{"alertname": "Watchdog"}},
+}
+
+func TestCheckReportsMissingHeartbeatOnce(t *testing.T) {
+
+	// Arrange
+	monitor := NewHeartbeatMonitor()
+	now := time.Now()
+	monitor.Observe("guild", "config", testHeartbeatOptions, testWatchdogAlerts, now)
+	monitor.Observe("guild", "config", testHeartbeatOptions, prometheus.Alerts{}, now.Add(time.Minute))
+
+	// Act
+	early := monitor.Check(now.Add(4 * time.Minute))
+	missing := monitor.Check(now.Add(6 * time.Minute))
+	stillMissing := monitor.Check(now.Add(time.Hour))
+
+	// Assert
+	assert.Empty(t, early)
+	assert.Empty(t, stillMissing)
+
+	assert.Len(t, missing, 1)
+	assert.Equal(t, HeartbeatMissing, missing[0].Status)
+	assert.Equal(t, "Watchdog", missing[0].AlertName)
+	assert.Equal(t, now, missing[0].LastSeen)
+}
+
+func TestCheckReportsRestoredHeartbeat(t *testing.T) {
+
+	// Arrange
+	monitor := NewHeartbeatMonitor()
+	now := time.Now()
+	monitor.Observe("guild", "config", testHeartbeatOptions, prometheus.Alerts{}, now)
+	missing := monitor.Check(now.Add(6 * time.Minute))
+	assert.Len(t, missing, 1)
+
+	lastSeen, isMissing := monitor.Missing("guild", "config")
+	assert.True(t, isMissing)
+	assert.Equal(t, now, lastSeen)
+
+	// Act
+	monitor.Observe("guild", "config", testHeartbeatOptions, testWatchdogAlerts, now.Add(7*time.Minute))
+	_, stillMissing := monitor.Missing("guild", "config")
+	restored := monitor.Check(now.Add(7 * time.Minute))
+	healthy := monitor.Check(now.Add(8 * time.Minute))
+
+	// Assert
+	assert.False(t, stillMissing)
+	assert.Len(t, restored, 1)
+	assert.Equal(t, HeartbeatRestored, restored[0].Status)
+	assert.Empty(t, healthy)
+}
+
+func TestObserveStopsMonitoringWhenHeartbeatIsRemoved(t *testing.T) {
+
+	// Arrange
+	monitor := NewHeartbeatMonitor()
+	now := time.Now()
+	monitor.Observe("guild", "config", testHeartbeatOptions, testWatchdogAlerts, now)
+
+	// Act
+	monitor.Observe("guild", "config", HeartbeatOptions{}, prometheus.Alerts{}, now.Add(time.Minute))
+	events := monitor.Check(now.Add(time.Hour))
+
+	// Assert
+	assert.Empty(t, events)
+}
@@ -0,0 +1,116 @@
+package alerts
+
+import (
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/prometheus"
+	"github.com/yukitsune/minialert/slices"
+)
+
+// GetChannels walks the routing tree and returns the IDs of all channels the alert with the given labels should be sent to.
+// Routes are evaluated depth-first, in order. Evaluation of sibling routes stops at the first matching route unless it has Continue set.
+// If no routes match, the default channel is used.
+func GetChannels(routes []db.Route, defaultChannelId string, labels map[string]string) ([]string, error) {
+	channelIds, err := getChannels(routes, defaultChannelId, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(channelIds) == 0 {
+		return []string{defaultChannelId}, nil
+	}
+
+	return channelIds, nil
+}
+
+func getChannels(routes []db.Route, parentChannelId string, labels map[string]string) ([]string, error) {
+	var channelIds []string
+	for _, route := range routes {
+		matches, err := prometheus.MatchesAll(route.Matchers, labels)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matches {
+			continue
+		}
+
+		channelId := route.ChannelId
+		if len(channelId) == 0 {
+			channelId = parentChannelId
+		}
+
+		childChannelIds, err := getChannels(route.Routes, channelId, labels)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(childChannelIds) > 0 {
+			channelIds = appendUnique(channelIds, childChannelIds...)
+		} else {
+			channelIds = appendUnique(channelIds, channelId)
+		}
+
+		if !route.Continue {
+			break
+		}
+	}
+
+	return channelIds, nil
+}
+
+// RouteNotification splits the notification up by the channels each of its alerts should be sent to.
+func RouteNotification(notification Notification, routes []db.Route, defaultChannelId string) (map[string]Notification, error) {
+	notifications := make(map[string]Notification)
+
+	getNotification := func(channelId string) Notification {
+		n, ok := notifications[channelId]
+		if !ok {
+			n = Notification{
+				GuildId:          notification.GuildId,
+				ScrapeConfigName: notification.ScrapeConfigName,
+				GroupKey:         notification.GroupKey,
+				GroupLabels:      notification.GroupLabels,
+			}
+		}
+
+		return n
+	}
+
+	for _, event := range notification.Firing {
+		channelIds, err := GetChannels(routes, defaultChannelId, event.Alert.Labels)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, channelId := range channelIds {
+			n := getNotification(channelId)
+			n.Firing = append(n.Firing, event)
+			notifications[channelId] = n
+		}
+	}
+
+	for _, event := range notification.Resolved {
+		channelIds, err := GetChannels(routes, defaultChannelId, event.Alert.Labels)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, channelId := range channelIds {
+			n := getNotification(channelId)
+			n.Resolved = append(n.Resolved, event)
+			notifications[channelId] = n
+		}
+	}
+
+	return notifications, nil
+}
+
+func appendUnique(s []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(s, value) {
+			s = append(s, value)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,69 @@
+package alerts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"testing"
+)
+
+var testRoutes = []db.Route{
+	{
+		Matchers:  []db.Matcher{{Name: "team", Type: db.MatchEqual, Value: "db"}},
+		ChannelId: "db-alerts",
+		Continue:  true,
+	},
+	{
+		Matchers:  []db.Matcher{{Name: "severity", Type: db.MatchEqual, Value: "critical"}},
+		ChannelId: "oncall",
+		Routes: []db.Route{
+			{
+				Matchers:  []db.Matcher{{Name: "env", Type: db.MatchEqual, Value: "prod"}},
+				ChannelId: "oncall-prod",
+			},
+		},
+	},
+	{
+		Matchers:  []db.Matcher{{Name: "severity", Type: db.MatchRegexp, Value: "warning|critical"}},
+		ChannelId: "warnings",
+	},
+}
+
+func TestGetChannelsUsesDefaultChannelWhenNoRoutesMatch(t *testing.T) {
+
+	// Act
+	channelIds, err := GetChannels(testRoutes, "default", map[string]string{"severity": "info"})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"default"}, channelIds)
+}
+
+func TestGetChannelsContinuesAfterMatchingRoute(t *testing.T) {
+
+	// Act
+	channelIds, err := GetChannels(testRoutes, "default", map[string]string{"team": "db", "severity": "critical"})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"db-alerts", "oncall"}, channelIds)
+}
+
+func TestGetChannelsStopsAtFirstMatchingRoute(t *testing.T) {
+
+	// Act
+	channelIds, err := GetChannels(testRoutes, "default", map[string]string{"severity": "critical"})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"oncall"}, channelIds)
+}
+
+func TestGetChannelsPrefersChildRoutes(t *testing.T) {
+
+	// Act
+	channelIds, err := GetChannels(testRoutes, "default", map[string]string{"severity": "critical", "env": "prod"})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"oncall-prod"}, channelIds)
+}
@@ -0,0 +1,57 @@
+package alerts
+
+import (
+	"fmt"
+	"github.com/yukitsune/minialert/prometheus"
+	"time"
+)
+
+const (
+	// ScrapeTargetDownAlertName is the name of the alert raised when a scrape config's endpoint can't be scraped.
+	ScrapeTargetDownAlertName = "ScrapeTargetDown"
+
+	// HeartbeatMissingAlertName is the name of the alert raised when a scrape config's heartbeat alert stops firing.
+	HeartbeatMissingAlertName = "HeartbeatMissing"
+)
+
+// sourceLabel marks alerts which are raised by minialert itself, rather than scraped from an endpoint or received.
+const (
+	sourceLabel     = "source"
+	syntheticSource = "minialert"
+)
+
+// NewScrapeTargetDownAlert creates the alert which fires while the scrape config's endpoint is down.
+func NewScrapeTargetDownAlert(configName string, consecutiveFailures int, lastError string) prometheus.Alert {
+	return prometheus.Alert{
+		Labels: map[string]string{
+			"alertname":     ScrapeTargetDownAlertName,
+			"severity":      CriticalSeverity,
+			"scrape_config": configName,
+			sourceLabel:     syntheticSource,
+		},
+		Annotations: map[string]string{
+			"description": fmt.Sprintf("Failed to scrape %s %d times in a row. Its alerts won't be updated until it recovers. Last error: %s", configName, consecutiveFailures, lastError),
+		},
+	}
+}
+
+// NewHeartbeatMissingAlert creates the alert which fires while the scrape config's heartbeat alert is missing.
+func NewHeartbeatMissingAlert(configName string, opts HeartbeatOptions, lastSeen time.Time) prometheus.Alert {
+	return prometheus.Alert{
+		Labels: map[string]string{
+			"alertname":       HeartbeatMissingAlertName,
+			"severity":        CriticalSeverity,
+			"scrape_config":   configName,
+			"heartbeat_alert": opts.AlertName,
+			sourceLabel:       syntheticSource,
+		},
+		Annotations: map[string]string{
+			"description": fmt.Sprintf("The heartbeat alert %s hasn't been seen since <t:%d:R>. Other alerts from %s may not be delivered.", opts.AlertName, lastSeen.Unix(), configName),
+		},
+	}
+}
+
+// IsSynthetic returns true if the alert was raised by minialert itself.
+func IsSynthetic(alert prometheus.Alert) bool {
+	return alert.Labels[sourceLabel] == syntheticSource
+}
@@ -0,0 +1,39 @@
+package alerts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/prometheus"
+	"testing"
+	"time"
+)
+
+func TestScrapeTargetDownAlertKeepsFiringAsFailuresIncrease(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	now := time.Now()
+	tracker.Process("guild", "config", prometheus.Alerts{NewScrapeTargetDownAlert("config", 3, "timeout")}, now)
+
+	// Act
+	events := tracker.Process("guild", "config", prometheus.Alerts{NewScrapeTargetDownAlert("config", 4, "connection refused")}, now.Add(time.Minute))
+	resolvedEvents := tracker.Process("guild", "config", prometheus.Alerts{}, now.Add(2*time.Minute))
+
+	// Assert
+	assert.Len(t, events, 1)
+	assert.Equal(t, StillFiringEvent, events[0].Type)
+
+	assert.Len(t, resolvedEvents, 1)
+	assert.Equal(t, ResolvedEvent, resolvedEvents[0].Type)
+}
+
+func TestIsSyntheticOnlyMatchesAlertsRaisedByMinialert(t *testing.T) {
+
+	// Arrange
+	scraped := prometheus.Alert{Labels: map[string]string{"alertname": ScrapeTargetDownAlertName}}
+
+	// Act
+	// Assert
+	assert.True(t, IsSynthetic(NewScrapeTargetDownAlert("config", 3, "timeout")))
+	assert.True(t, IsSynthetic(NewHeartbeatMissingAlert("config", testHeartbeatOptions, time.Now())))
+	assert.False(t, IsSynthetic(scraped))
+}
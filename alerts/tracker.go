@@ -0,0 +1,138 @@
+package alerts
+
+import (
+	"fmt"
+	"github.com/yukitsune/minialert/prometheus"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	FiringEvent      EventType = "firing"
+	StillFiringEvent EventType = "still_firing"
+	ResolvedEvent    EventType = "resolved"
+)
+
+func (t EventType) String() string {
+	return string(t)
+}
+
+type Event struct {
+	Type        EventType
+	Fingerprint string
+	Alert       prometheus.Alert
+	FiringSince time.Time
+	ResolvedAt  time.Time
+}
+
+// Tracker keeps track of which alerts are currently firing for each scrape config.
+// Each set of scraped alerts is compared against the previous set, so that only state transitions are reported.
+type Tracker interface {
+	Process(guildId string, configName string, alerts prometheus.Alerts, now time.Time) []Event
+	Active(guildId string, configName string) prometheus.Alerts
+	Clear(guildId string, configName string)
+}
+
+type key string
+
+func newKey(guildId string, configName string) key {
+	return key(fmt.Sprintf("%s:%s", guildId, configName))
+}
+
+type trackedAlert struct {
+	alert       prometheus.Alert
+	firingSince time.Time
+}
+
+type inMemoryTracker struct {
+	mu     sync.RWMutex
+	states map[key]map[string]trackedAlert
+}
+
+func NewTracker() Tracker {
+	return &inMemoryTracker{
+		states: make(map[key]map[string]trackedAlert),
+	}
+}
+
+func (t *inMemoryTracker) Process(guildId string, configName string, alerts prometheus.Alerts, now time.Time) []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := newKey(guildId, configName)
+	previous := t.states[k]
+	current := make(map[string]trackedAlert, len(alerts))
+
+	var events []Event
+	for _, alert := range alerts {
+		fingerprint := Fingerprint(alert.Labels)
+
+		// The same alert may be reported more than once, only consider the first one
+		if _, ok := current[fingerprint]; ok {
+			continue
+		}
+
+		if prev, ok := previous[fingerprint]; ok {
+			current[fingerprint] = trackedAlert{alert: alert, firingSince: prev.firingSince}
+			events = append(events, Event{
+				Type:        StillFiringEvent,
+				Fingerprint: fingerprint,
+				Alert:       alert,
+				FiringSince: prev.firingSince,
+			})
+
+			continue
+		}
+
+		firingSince := alert.ActiveAt
+		if firingSince.IsZero() {
+			firingSince = now
+		}
+
+		current[fingerprint] = trackedAlert{alert: alert, firingSince: firingSince}
+		events = append(events, Event{
+			Type:        FiringEvent,
+			Fingerprint: fingerprint,
+			Alert:       alert,
+			FiringSince: firingSince,
+		})
+	}
+
+	for fingerprint, prev := range previous {
+		if _, ok := current[fingerprint]; ok {
+			continue
+		}
+
+		events = append(events, Event{
+			Type:        ResolvedEvent,
+			Fingerprint: fingerprint,
+			Alert:       prev.alert,
+			FiringSince: prev.firingSince,
+			ResolvedAt:  now,
+		})
+	}
+
+	t.states[k] = current
+	return events
+}
+
+func (t *inMemoryTracker) Active(guildId string, configName string) prometheus.Alerts {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var alerts prometheus.Alerts
+	for _, tracked := range t.states[newKey(guildId, configName)] {
+		alerts = append(alerts, tracked.alert)
+	}
+
+	return alerts
+}
+
+func (t *inMemoryTracker) Clear(guildId string, configName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.states, newKey(guildId, configName))
+}
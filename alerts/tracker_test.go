@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/prometheus"
+	"testing"
+	"time"
+)
+
+func TestProcessReportsNewAlertsAsFiring(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	// Act
+	events := tracker.Process("guild", "config", alerts, time.Now())
+
+	// Assert
+	assert.Len(t, events, 1)
+	assert.Equal(t, FiringEvent, events[0].Type)
+}
+
+func TestProcessReportsExistingAlertsAsStillFiring(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	tracker.Process("guild", "config", alerts, time.Now())
+
+	// Act
+	events := tracker.Process("guild", "config", alerts, time.Now())
+
+	// Assert
+	assert.Len(t, events, 1)
+	assert.Equal(t, StillFiringEvent, events[0].Type)
+}
+
+func TestProcessReportsMissingAlertsAsResolved(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	firingAt := time.Now()
+	resolvedAt := firingAt.Add(time.Minute)
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+		prometheus.Alert{Labels: map[string]string{"alertname": "bar"}},
+	}
+
+	tracker.Process("guild", "config", alerts, firingAt)
+
+	// Act
+	events := tracker.Process("guild", "config", alerts[1:], resolvedAt)
+
+	// Assert
+	assert.Len(t, events, 2)
+
+	var resolved []Event
+	for _, event := range events {
+		if event.Type == ResolvedEvent {
+			resolved = append(resolved, event)
+		}
+	}
+
+	assert.Len(t, resolved, 1)
+	assert.Equal(t, "foo", resolved[0].Alert.Labels["alertname"])
+	assert.Equal(t, firingAt, resolved[0].FiringSince)
+	assert.Equal(t, resolvedAt, resolved[0].ResolvedAt)
+}
+
+func TestProcessKeepsScrapeConfigsSeparate(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	tracker.Process("guild", "config", alerts, time.Now())
+
+	// Act
+	events := tracker.Process("guild", "other-config", alerts, time.Now())
+
+	// Assert
+	assert.Len(t, events, 1)
+	assert.Equal(t, FiringEvent, events[0].Type)
+}
+
+func TestClearForgetsFiringAlerts(t *testing.T) {
+
+	// Arrange
+	tracker := NewTracker()
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Labels: map[string]string{"alertname": "foo"}},
+	}
+
+	tracker.Process("guild", "config", alerts, time.Now())
+
+	// Act
+	tracker.Clear("guild", "config")
+
+	// Assert
+	assert.Empty(t, tracker.Active("guild", "config"))
+}
+
+func TestFingerprintIgnoresLabelOrder(t *testing.T) {
+
+	// Arrange
+	a := map[string]string{"alertname": "foo", "severity": "critical"}
+	b := map[string]string{"severity": "critical", "alertname": "foo"}
+
+	// Act
+	fingerprintA := Fingerprint(a)
+	fingerprintB := Fingerprint(b)
+
+	// Assert
+	assert.Equal(t, fingerprintA, fingerprintB)
+	assert.NotEqual(t, fingerprintA, Fingerprint(map[string]string{"alertname": "bar"}))
+}
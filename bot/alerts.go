@@ -2,112 +2,551 @@ package bot
 
 import (
 	"context"
+	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
 	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/notify"
 	"github.com/yukitsune/minialert/prometheus"
+	"github.com/yukitsune/minialert/receiver"
 	"github.com/yukitsune/minialert/scraper"
 	"github.com/yukitsune/minialert/slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
-func getFieldsFromLabels(alert prometheus.Alert) []*discordgo.MessageEmbedField {
-	var fields []*discordgo.MessageEmbedField
-	for k, v := range alert.Labels {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   k,
-			Value:  v,
-			Inline: false,
+// notificationFlushInterval is how often grouped alerts are checked to see if they're ready to be sent.
+const notificationFlushInterval = time.Second
+
+// silenceCleanupInterval is how often expired silences are deleted.
+const silenceCleanupInterval = 10 * time.Minute
+
+// receiverExpiryInterval is how often received alerts are checked to see if they've ended.
+const receiverExpiryInterval = 10 * time.Second
+
+// maxEmbedFields is the maximum number of fields Discord allows in a single embed.
+const maxEmbedFields = 25
+
+func watchAlerts(ctx context.Context, sessions *sessions, deps Dependencies, logger logrus.FieldLogger) {
+	ticker := time.NewTicker(notificationFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case results := <-deps.ScrapeManager.Chan():
+			if handledResults, ok := updateScrapeHealth(deps.HealthTracker, deps.Tracker, results, logger); ok {
+				handleScrapeResult(ctx, deps.Repo, deps.HealthTracker, deps.Tracker, deps.Grouper, deps.HeartbeatMonitor, handledResults, logger)
+			}
+
+			recordScrapeState(ctx, deps.Repo, deps.ScrapeManager, deps.HealthTracker, deps.Tracker, results, logger)
+
+		case results := <-deps.Receiver.Chan():
+			handleScrapeResult(ctx, deps.Repo, deps.HealthTracker, deps.Tracker, deps.Grouper, deps.HeartbeatMonitor, results, logger)
+			recordScrapeState(ctx, deps.Repo, deps.ScrapeManager, deps.HealthTracker, deps.Tracker, results, logger)
+
+		case <-ticker.C:
+			now := time.Now()
+			notifications := deps.Grouper.Flush(now)
+			sendNotifications(ctx, deps.Repo, notifications, deps.Escalator, newChannelNotifierFactory(sessions, deps.Repo, logger), deps.NotifierFactory, logger)
+
+			escalations := deps.Escalator.Due(now)
+			sendEscalations(ctx, sessions, deps.Repo, deps.Escalator, escalations, logger)
+
+			heartbeatEvents := deps.HeartbeatMonitor.Check(now)
+			raiseMissingHeartbeats(ctx, deps.Repo, deps.HealthTracker, deps.Tracker, deps.Grouper, deps.HeartbeatMonitor, heartbeatEvents, logger)
+
+		case <-ctx.Done():
+			logger.Debug("Stopping watchAlerts")
+			return
+		}
+	}
+}
+
+func handleScrapeResult(ctx context.Context, repo db.Repo, healthTracker scraper.HealthTracker, tracker alerts.Tracker, grouper alerts.Grouper, heartbeatMonitor alerts.HeartbeatMonitor, results scraper.ScrapeResult, logger logrus.FieldLogger) {
+	ctxLogger := logger.
+		WithField("guild_id", results.GuildId).
+		WithField("scrape_config_name", results.ScrapeConfigName)
+
+	scrapeConfig, err := getScrapeConfig(ctx, repo, results.GuildId, results.ScrapeConfigName)
+	if err != nil {
+		ctxLogger.Warnf("Failed to get scrape config: %s", err.Error())
+		return
+	}
+
+	now := time.Now()
+
+	// The heartbeat alert is always firing, so it's only used to check that the alerting pipeline works rather than being notified about
+	heartbeatOpts := alerts.NewHeartbeatOptions(scrapeConfig)
+	heartbeatMonitor.Observe(results.GuildId, results.ScrapeConfigName, heartbeatOpts, results.Alerts, now)
+	if len(heartbeatOpts.AlertName) > 0 {
+		results.Alerts = slices.Filter(results.Alerts, func(alert prometheus.Alert) bool {
+			return alert.Labels["alertname"] != heartbeatOpts.AlertName
 		})
 	}
 
-	return fields
+	// Alerts raised by minialert itself are handled like any other alert, so that they can be silenced, routed, acknowledged and escalated
+	results.Alerts = append(results.Alerts, getSyntheticAlerts(scrapeConfig, heartbeatOpts, healthTracker, heartbeatMonitor, results.GuildId)...)
+
+	// Silenced alerts are still tracked so that they aren't reported as resolved when they're silenced
+	events := tracker.Process(results.GuildId, results.ScrapeConfigName, results.Alerts, now)
+
+	// History is recorded before filtering so that silenced and inhibited alerts are still included
+	err = handlers.RecordAlertEvents(ctx, repo, results.GuildId, results.ScrapeConfigName, events, now)
+	if err != nil {
+		ctxLogger.Errorf("Failed to record alert history: %s", err.Error())
+	}
+
+	silences, err := handlers.GetSilences(ctx, repo, results.GuildId, results.ScrapeConfigName)
+	if err != nil {
+		ctxLogger.Errorf("Failed to get silences: %s", err.Error())
+		return
+	}
+
+	events, err = filterEvents(events, scrapeConfig.InhibitionRules, silences, now)
+	if err != nil {
+		ctxLogger.Errorf("Failed to filter alerts: %s", err.Error())
+		return
+	}
+
+	grouper.Add(results.GuildId, results.ScrapeConfigName, alerts.NewGroupingOptions(scrapeConfig), events, now)
 }
 
-func watchAlerts(done chan bool, s *discordgo.Session, repo db.Repo, scrapeManager scraper.ScrapeManager, logger logrus.FieldLogger) {
-	for {
-		select {
-		case results := <-scrapeManager.Chan():
+// getSyntheticAlerts returns the alerts minialert raises itself for the scrape config, E.g: while its endpoint is down.
+func getSyntheticAlerts(scrapeConfig *db.ScrapeConfig, heartbeatOpts alerts.HeartbeatOptions, healthTracker scraper.HealthTracker, heartbeatMonitor alerts.HeartbeatMonitor, guildId string) prometheus.Alerts {
+	var syntheticAlerts prometheus.Alerts
 
-			ctx := context.TODO()
+	health, ok := healthTracker.Get(guildId, scrapeConfig.Name)
+	if ok && health.Down {
+		syntheticAlerts = append(syntheticAlerts, alerts.NewScrapeTargetDownAlert(scrapeConfig.Name, health.ConsecutiveFailures, health.LastError))
+	}
 
-			guildConfig, err := repo.GetGuildConfig(ctx, results.GuildId)
-			if err != nil {
-				logger.Errorf("Failed to get guild config: %s", err.Error())
-				return
-			}
+	lastSeen, missing := heartbeatMonitor.Missing(guildId, scrapeConfig.Name)
+	if missing {
+		syntheticAlerts = append(syntheticAlerts, alerts.NewHeartbeatMissingAlert(scrapeConfig.Name, heartbeatOpts, lastSeen))
+	}
 
-			scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
-				return cfg.Name == results.ScrapeConfigName
-			})
+	return syntheticAlerts
+}
 
-			if !ok {
-				logger.Warn("Guild config for %s doesn't contain a scrape config with the name %s", results.GuildId, results.ScrapeConfigName)
-				return
-			}
+// getActiveAlerts returns the scrape config's firing alerts, other than the ones raised by minialert itself, so that they can be handled again when there are no new results.
+func getActiveAlerts(tracker alerts.Tracker, guildId string, configName string) prometheus.Alerts {
+	return slices.Filter(tracker.Active(guildId, configName), func(alert prometheus.Alert) bool {
+		return !alerts.IsSynthetic(alert)
+	})
+}
+
+// recordScrapeState records the scrape config's state once its results have been handled, so that other replicas can report it.
+func recordScrapeState(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, healthTracker scraper.HealthTracker, tracker alerts.Tracker, results scraper.ScrapeResult, logger logrus.FieldLogger) {
+	err := handlers.RecordScrapeState(ctx, repo, scrapeManager, healthTracker, tracker, results.GuildId, results.ScrapeConfigName, time.Now())
+	if err != nil {
+		logger.
+			WithField("guild_id", results.GuildId).
+			WithField("scrape_config_name", results.ScrapeConfigName).
+			Errorf("Failed to record scrape state: %s", err.Error())
+	}
+}
 
-			filteredAlerts, err := prometheus.FilterAlerts(results.Alerts, scrapeConfig.InhibitedAlerts)
+// filterEvents removes the events for any firing alerts which shouldn't be notified about.
+func filterEvents(events []alerts.Event, inhibitionRules []db.InhibitionRule, silences []db.Silence, now time.Time) ([]alerts.Event, error) {
+	var firingAlerts prometheus.Alerts
+	for _, event := range events {
+		if event.Type != alerts.ResolvedEvent {
+			firingAlerts = append(firingAlerts, event.Alert)
+		}
+	}
+
+	// Inhibitions are evaluated first, so that silenced alerts can still inhibit other alerts
+	filteredAlerts, err := prometheus.InhibitAlerts(firingAlerts, inhibitionRules)
+	if err != nil {
+		return nil, err
+	}
+
+	filteredAlerts, err = prometheus.FilterAlerts(filteredAlerts, silences, now)
+	if err != nil {
+		return nil, err
+	}
+
+	unfiltered := make(map[string]bool)
+	for _, alert := range filteredAlerts {
+		unfiltered[alerts.Fingerprint(alert.Labels)] = true
+	}
+
+	return slices.Filter(events, func(event alerts.Event) bool {
+		return event.Type == alerts.ResolvedEvent || unfiltered[event.Fingerprint]
+	}), nil
+}
+
+func cleanupSilences(ctx context.Context, repo db.Repo, logger logrus.FieldLogger) {
+	ticker := time.NewTicker(silenceCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := repo.DeleteExpiredSilences(ctx, time.Now())
 			if err != nil {
-				logger.Errorf("Failed to filter alerts: %s", err.Error())
-				continue
+				logger.Errorf("Failed to delete expired silences: %s", err.Error())
 			}
 
-			sendAlertsToChannel(s, scrapeConfig.Name, scrapeConfig.AlertChannelId, filteredAlerts, logger)
+		case <-ctx.Done():
+			logger.Debug("Stopping cleanupSilences")
+			return
+		}
+	}
+}
 
-		case <-done:
-			logger.Debug("Stopping watchAlerts")
+func expireReceivedAlerts(ctx context.Context, receiver receiver.Receiver, logger logrus.FieldLogger) {
+	ticker := time.NewTicker(receiverExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			receiver.Expire(ctx, time.Now())
+
+		case <-ctx.Done():
+			logger.Debug("Stopping expireReceivedAlerts")
 			return
 		}
 	}
 }
 
-func sendAlertsToChannel(s *discordgo.Session, configName string, channelId string, alerts prometheus.Alerts, logger logrus.FieldLogger) {
-	for _, alert := range alerts {
+func sendNotifications(ctx context.Context, repo db.Repo, notifications []alerts.Notification, escalator alerts.Escalator, channelNotifierFactory notify.ChannelNotifierFactory, notifierFactory notify.Factory, logger logrus.FieldLogger) {
+	for _, notification := range notifications {
+		ctxLogger := logger.
+			WithField("guild_id", notification.GuildId).
+			WithField("scrape_config_name", notification.ScrapeConfigName)
+
+		scrapeConfig, err := getScrapeConfig(ctx, repo, notification.GuildId, notification.ScrapeConfigName)
+		if err != nil {
+			ctxLogger.Warnf("Failed to get scrape config: %s", err.Error())
+			continue
+		}
+
+		ctxLogger.Debugf("Sending notification for group %s: %d firing, %d resolved", notification.GroupKey, len(notification.Firing), len(notification.Resolved))
+		err = notify.Dispatch(ctx, scrapeConfig, notification, channelNotifierFactory, notifierFactory)
+		if err != nil {
+			ctxLogger.Errorf("Failed to send notification: %s", err.Error())
+		}
+
+		escalator.Observe(notification, scrapeConfig.EscalationSteps, time.Now())
+	}
+}
+
+// isSilenced returns true if all the notification's firing alerts have been silenced.
+func isSilenced(ctx context.Context, repo db.Repo, notification alerts.Notification, now time.Time) (bool, error) {
+	silences, err := handlers.GetSilences(ctx, repo, notification.GuildId, notification.ScrapeConfigName)
+	if err != nil {
+		return false, err
+	}
+
+	var firingAlerts prometheus.Alerts
+	for _, event := range notification.Firing {
+		firingAlerts = append(firingAlerts, event.Alert)
+	}
 
-		alertName := alert.Labels["alertname"]
+	unsilenced, err := prometheus.FilterAlerts(firingAlerts, silences, now)
+	if err != nil {
+		return false, err
+	}
+
+	return len(unsilenced) == 0, nil
+}
 
-		title := alertName
-		url := alert.Annotations["runbook_url"]
-		description := alert.Annotations["description"]
-		color, err := getColorFromSeverity(alert.Labels["severity"])
+func sendEscalations(ctx context.Context, sessions *sessions, repo db.Repo, escalator alerts.Escalator, escalations []alerts.Escalation, logger logrus.FieldLogger) {
+	for _, escalation := range escalations {
+		notification := escalation.Notification
+
+		ctxLogger := logger.
+			WithField("guild_id", notification.GuildId).
+			WithField("scrape_config_name", notification.ScrapeConfigName)
+
+		// The group may have been acknowledged using another replica, which only this replica's escalator wouldn't know about
+		acknowledged, err := handlers.IsAcknowledged(ctx, repo, notification.GuildId, notification.ScrapeConfigName, notification.GroupKey, escalation.StartedAt)
 		if err != nil {
-			logger.Errorf("Failed to generate color for alert: %s", err.Error())
+			ctxLogger.Errorf("Failed to check acknowledgements: %s", err.Error())
+			continue
 		}
 
-		fields := getFieldsFromLabels(alert)
+		if acknowledged {
+			escalator.Acknowledge(notification.GuildId, notification.ScrapeConfigName, notification.GroupKey)
+			continue
+		}
 
-		embed := &discordgo.MessageEmbed{
-			Type:        discordgo.EmbedTypeRich,
-			Title:       title,
-			URL:         url,
-			Description: description,
-			Timestamp:   alert.ActiveAt.Format("2006-01-02T15:04:05-0700"),
-			Color:       int(color),
-			Fields:      fields,
+		// The group stops being escalated once the grouper reports its silenced alerts, but that waits for the group interval
+		silenced, err := isSilenced(ctx, repo, notification, time.Now())
+		if err != nil {
+			ctxLogger.Errorf("Failed to check silences: %s", err.Error())
+			continue
 		}
 
-		inhibitButtonComponent := discordgo.Button{
-			Label:    "Inhibit",
-			Style:    discordgo.DangerButton,
-			CustomID: NewMessageInteractionId(InhibitAlertCommandName, configName, alertName).String(),
+		if silenced {
+			ctxLogger.Debugf("Not escalating group %s as its alerts have been silenced", notification.GroupKey)
+			continue
+		}
+
+		scrapeConfig, err := getScrapeConfig(ctx, repo, notification.GuildId, notification.ScrapeConfigName)
+		if err != nil {
+			ctxLogger.Warnf("Failed to get scrape config: %s", err.Error())
+			continue
+		}
+
+		// Escalate in the same channels the notification was sent to
+		routedNotifications, err := alerts.RouteNotification(notification, scrapeConfig.Routes, scrapeConfig.AlertChannelId)
+		if err != nil {
+			ctxLogger.Errorf("Failed to route escalation: %s", err.Error())
+			continue
 		}
 
 		message := &discordgo.MessageSend{
-			Embed: embed,
+			Content: fmt.Sprintf("🚨 %s %s has not been acknowledged (escalation %d)", formatMentions(escalation.Step), notify.Title(notification), escalation.StepNumber),
+			AllowedMentions: &discordgo.MessageAllowedMentions{
+				Roles: slices.Filter([]string{escalation.Step.RoleId}, isNotEmpty),
+				Users: slices.Filter([]string{escalation.Step.UserId}, isNotEmpty),
+			},
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
-						inhibitButtonComponent,
+						getAcknowledgeButton(notification.ScrapeConfigName, notification.GroupKey),
 					},
 				},
 			},
 		}
 
-		_, err = s.ChannelMessageSendComplex(channelId, message)
-		if err != nil {
-			logger.Errorf("Failed to send message to channel %s: %s\n", channelId, err.Error())
+		for channelId := range routedNotifications {
+			if len(channelId) == 0 {
+				continue
+			}
+
+			ctxLogger.Debugf("Escalating group %s to channel %s", notification.GroupKey, channelId)
+			_, err = sessions.forGuild(notification.GuildId).ChannelMessageSendComplex(channelId, message)
+			if err != nil {
+				ctxLogger.Errorf("Failed to send escalation to channel %s: %s", channelId, err.Error())
+			}
+		}
+	}
+}
+
+func formatMentions(step db.EscalationStep) string {
+	var mentions []string
+	if len(step.RoleId) > 0 {
+		mentions = append(mentions, fmt.Sprintf("<@&%s>", step.RoleId))
+	}
+
+	if len(step.UserId) > 0 {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", step.UserId))
+	}
+
+	return strings.Join(mentions, " ")
+}
+
+func isNotEmpty(s string) bool {
+	return len(s) > 0
+}
+
+func getAcknowledgeButton(configName string, fingerprint string) discordgo.Button {
+	return discordgo.Button{
+		Label:    "Acknowledge",
+		Style:    discordgo.PrimaryButton,
+		CustomID: NewMessageInteractionId(AcknowledgeCommandName, configName, fingerprint).String(),
+	}
+}
+
+func getScrapeConfig(ctx context.Context, repo db.Repo, guildId string, configName string) (*db.ScrapeConfig, error) {
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("guild config for %s doesn't contain a scrape config with the name %s", guildId, configName)
+	}
+
+	return scrapeConfig, nil
+}
+
+func getNotificationMessage(notification alerts.Notification, acknowledged bool, logger logrus.FieldLogger) *discordgo.MessageSend {
+	var embeds []*discordgo.MessageEmbed
+	if len(notification.Firing) > 0 {
+		embeds = append(embeds, getFiringEmbed(notification, logger))
+	}
+
+	if len(notification.Resolved) > 0 {
+		embeds = append(embeds, getResolvedEmbed(notification, logger))
+	}
+
+	return &discordgo.MessageSend{
+		Embeds:     embeds,
+		Components: getNotificationComponents(notification.ScrapeConfigName, notification, acknowledged),
+	}
+}
+
+func getFiringEmbed(notification alerts.Notification, logger logrus.FieldLogger) *discordgo.MessageEmbed {
+	color, err := getColorFromSeverity(getHighestSeverity(notification.Firing))
+	if err != nil {
+		logger.Errorf("Failed to generate color for alert: %s", err.Error())
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Type:      discordgo.EmbedTypeRich,
+		Title:     fmt.Sprintf("[FIRING:%d] %s", len(notification.Firing), notify.FormatLabels(notification.GroupLabels)),
+		Timestamp: getFiringSince(notification.Firing).Format("2006-01-02T15:04:05-0700"),
+		Color:     int(color),
+		Fields:    getFieldsFromEvents(notification.Firing, notification.GroupLabels, false),
+	}
+
+	if len(notification.Firing) == 1 {
+		embed.URL = notification.Firing[0].Alert.Annotations["runbook_url"]
+	}
+
+	return embed
+}
+
+// getFiringSince returns when the earliest of the events started firing.
+func getFiringSince(events []alerts.Event) time.Time {
+	var firingSince time.Time
+	for _, event := range events {
+		if firingSince.IsZero() || event.FiringSince.Before(firingSince) {
+			firingSince = event.FiringSince
+		}
+	}
+
+	return firingSince
+}
+
+func getResolvedEmbed(notification alerts.Notification, logger logrus.FieldLogger) *discordgo.MessageEmbed {
+	color, err := getResolvedColor()
+	if err != nil {
+		logger.Errorf("Failed to generate color for resolved alert: %s", err.Error())
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:      discordgo.EmbedTypeRich,
+		Title:     fmt.Sprintf("[RESOLVED:%d] %s", len(notification.Resolved), notify.FormatLabels(notification.GroupLabels)),
+		Timestamp: time.Now().Format("2006-01-02T15:04:05-0700"),
+		Color:     int(color),
+		Fields:    getFieldsFromEvents(notification.Resolved, notification.GroupLabels, true),
+	}
+}
+
+func getFieldsFromEvents(events []alerts.Event, groupLabels map[string]string, resolved bool) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+	for i, event := range events {
+		if i == maxEmbedFields-1 && len(events) > maxEmbedFields {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:  "...",
+				Value: fmt.Sprintf("and %d more", len(events)-i),
+			})
+			break
+		}
+
+		var value strings.Builder
+		if description := event.Alert.Annotations["description"]; len(description) > 0 {
+			value.WriteString(description)
+			value.WriteString("\n")
+		}
+
+		// Labels shared by the whole group are already in the title
+		labels := make(map[string]string)
+		for k, v := range event.Alert.Labels {
+			if _, ok := groupLabels[k]; !ok && k != "alertname" {
+				labels[k] = v
+			}
+		}
+
+		if len(labels) > 0 {
+			value.WriteString(fmt.Sprintf("`%s`\n", notify.FormatLabels(labels)))
+		}
+
+		if len(event.Alert.RuleGroup) > 0 {
+			value.WriteString(fmt.Sprintf("Rule group: `%s`\n", event.Alert.RuleGroup))
+		}
+
+		if resolved {
+			value.WriteString(fmt.Sprintf("Resolved after %s", event.ResolvedAt.Sub(event.FiringSince).Round(time.Second)))
+		} else {
+			value.WriteString(fmt.Sprintf("Firing since <t:%d:R>", event.FiringSince.Unix()))
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   event.Alert.Labels["alertname"],
+			Value:  truncate(value.String(), 1024),
+			Inline: false,
+		})
+	}
+
+	return fields
+}
+
+func getNotificationComponents(configName string, notification alerts.Notification, acknowledged bool) []discordgo.MessageComponent {
+	var buttons []discordgo.MessageComponent
+	var alertNames []string
+	for _, event := range notification.Firing {
+		alertName := event.Alert.Labels["alertname"]
+		if slices.Contains(alertNames, alertName) {
+			continue
+		}
+
+		alertNames = append(alertNames, alertName)
+		buttons = append(buttons, discordgo.Button{
+			Label:    fmt.Sprintf("Inhibit %s", alertName),
+			Style:    discordgo.DangerButton,
+			CustomID: NewMessageInteractionId(InhibitAlertCommandName, configName, alertName).String(),
+		})
+
+		// Discord only allows 5 buttons per row
+		if len(buttons) == 5 {
+			break
 		}
 	}
+
+	if len(buttons) == 0 {
+		return nil
+	}
+
+	acknowledgeButton := getAcknowledgeButton(configName, notification.GroupKey)
+	acknowledgeButton.Disabled = acknowledged
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				acknowledgeButton,
+			},
+		},
+		discordgo.ActionsRow{
+			Components: buttons,
+		},
+	}
+}
+
+// truncate shortens the string to at most max characters, so that multi-byte characters are never cut in half.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+
+	return string(runes[:max-3]) + "..."
+}
+
+func getHighestSeverity(events []alerts.Event) string {
+	severity := ""
+	for _, event := range events {
+		switch event.Alert.Labels["severity"] {
+		case "critical":
+			return "critical"
+		case "warning":
+			severity = "warning"
+		}
+	}
+
+	return severity
 }
 
 func getColorFromSeverity(severity string) (int64, error) {
@@ -120,3 +559,7 @@ func getColorFromSeverity(severity string) (int64, error) {
 		return strconv.ParseInt("ffffff", 16, 64)
 	}
 }
+
+func getResolvedColor() (int64, error) {
+	return strconv.ParseInt("00ff00", 16, 64)
+}
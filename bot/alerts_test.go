@@ -0,0 +1,30 @@
+package bot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateKeepsShortStrings(t *testing.T) {
+
+	// Act
+	truncated := truncate("héllo", 5)
+
+	// Assert
+	assert.Equal(t, "héllo", truncated)
+}
+
+func TestTruncateDoesNotSplitMultiByteCharacters(t *testing.T) {
+
+	// Arrange
+	s := "ディスクがいっぱいです"
+
+	// Act
+	truncated := truncate(s, 8)
+
+	// Assert
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, "ディスクが...", truncated)
+	assert.Equal(t, 8, utf8.RuneCountInString(truncated))
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/prometheus"
+	"strings"
+)
+
+// maxAutocompleteChoices is the maximum number of choices Discord allows in an autocomplete response.
+const maxAutocompleteChoices = 25
+
+// AutocompleteHandler returns the choices to suggest for an option while it's being typed.
+type AutocompleteHandler func(ctx context.Context, i *discordgo.InteractionCreate, opts map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error)
+type AutocompleteHandlers map[InteractionOption]AutocompleteHandler
+
+func getAutocompleteHandlers(repo db.Repo, tracker alerts.Tracker) AutocompleteHandlers {
+	return map[InteractionOption]AutocompleteHandler{
+		ScrapeConfigNameOption: scrapeConfigNameAutocompleteHandler(repo),
+		AlertNameOption:        alertNameAutocompleteHandler(repo, tracker),
+		SilenceIdOption:        silenceIdAutocompleteHandler(repo),
+	}
+}
+
+func handleAutocomplete(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, autocompleteHandlers AutocompleteHandlers, logger logrus.FieldLogger) {
+
+	options := i.ApplicationCommandData().Options
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	for _, opt := range options {
+		if opt.Focused {
+			focused = opt
+			break
+		}
+	}
+
+	if focused == nil {
+		logger.Warnln("Received autocomplete interaction without a focused option")
+		return
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	if h, ok := autocompleteHandlers[InteractionOption(focused.Name)]; ok {
+		var err error
+		choices, err = h(ctx, i, getOptionMap(options))
+		if err != nil {
+			// Still respond so that Discord doesn't show the interaction as failed
+			logger.Errorf("Failed to get autocomplete choices for %s: %s", focused.Name, err.Error())
+		}
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: filterChoices(choices, focused.StringValue()),
+		},
+	})
+
+	if err != nil {
+		logger.Errorf("Failed to respond: %s", err.Error())
+	}
+}
+
+// filterChoices returns the choices whose name contains the partially typed value.
+func filterChoices(choices []*discordgo.ApplicationCommandOptionChoice, value string) []*discordgo.ApplicationCommandOptionChoice {
+	value = strings.ToLower(value)
+
+	filtered := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, choice := range choices {
+		if !strings.Contains(strings.ToLower(choice.Name), value) {
+			continue
+		}
+
+		filtered = append(filtered, choice)
+		if len(filtered) == maxAutocompleteChoices {
+			break
+		}
+	}
+
+	return filtered
+}
+
+func newChoice(name string, value string) *discordgo.ApplicationCommandOptionChoice {
+	return &discordgo.ApplicationCommandOptionChoice{
+		Name:  truncate(name, 100),
+		Value: value,
+	}
+}
+
+func scrapeConfigNameAutocompleteHandler(repo db.Repo) AutocompleteHandler {
+	return func(ctx context.Context, i *discordgo.InteractionCreate, _ map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+		scrapeConfigs, err := handlers.GetScrapeConfigs(ctx, repo, i.GuildID)
+		if err != nil {
+			return nil, err
+		}
+
+		var choices []*discordgo.ApplicationCommandOptionChoice
+		for _, scrapeConfig := range scrapeConfigs {
+			choices = append(choices, newChoice(scrapeConfig.Name, scrapeConfig.Name))
+		}
+
+		return choices, nil
+	}
+}
+
+// alertNameAutocompleteHandler suggests the known alert names for the chosen scrape config.
+// When un-inhibiting, only inhibited alert names are suggested.
+func alertNameAutocompleteHandler(repo db.Repo, tracker alerts.Tracker) AutocompleteHandler {
+	return func(ctx context.Context, i *discordgo.InteractionCreate, opts map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			return nil, nil
+		}
+
+		configName := configNameOpt.StringValue()
+
+		var alertNames []string
+		var err error
+		if InteractionName(i.ApplicationCommandData().Name) == UninhibitAlertCommandName {
+			alertNames, err = handlers.GetInhibitions(ctx, configName, i.GuildID, repo)
+		} else {
+			alertNames, err = handlers.GetKnownAlertNames(ctx, repo, tracker, i.GuildID, configName)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var choices []*discordgo.ApplicationCommandOptionChoice
+		for _, alertName := range alertNames {
+			choices = append(choices, newChoice(alertName, alertName))
+		}
+
+		return choices, nil
+	}
+}
+
+func silenceIdAutocompleteHandler(repo db.Repo) AutocompleteHandler {
+	return func(ctx context.Context, i *discordgo.InteractionCreate, _ map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+		silences, err := handlers.GetActiveSilences(ctx, repo, i.GuildID)
+		if err != nil {
+			return nil, err
+		}
+
+		var choices []*discordgo.ApplicationCommandOptionChoice
+		for _, silence := range silences {
+			name := fmt.Sprintf("%s: %s", silence.ScrapeConfigName, prometheus.FormatMatchers(silence.Matchers))
+			choices = append(choices, newChoice(name, silence.Id))
+		}
+
+		return choices, nil
+	}
+}
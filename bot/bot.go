@@ -5,86 +5,178 @@ import (
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
 	"github.com/yukitsune/minialert/config"
 	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/leader"
+	"github.com/yukitsune/minialert/notify"
 	"github.com/yukitsune/minialert/prometheus"
+	"github.com/yukitsune/minialert/receiver"
 	"github.com/yukitsune/minialert/scraper"
+	"github.com/yukitsune/minialert/shard"
 	"strings"
+	"time"
 )
 
+// stopScrapersTimeout is how long to wait for leadership to be given up, and in-flight scrapes to finish, when closing.
+const stopScrapersTimeout = 10 * time.Second
+
+// Dependencies are the services the bot is built on, which are created up-front so that they can be shared with the receiver.
+type Dependencies struct {
+	Repo             db.Repo
+	ClientFactory    prometheus.ClientFactory
+	ScrapeManager    scraper.ScrapeManager
+	Elector          leader.Elector
+	Membership       shard.Membership
+	HealthTracker    scraper.HealthTracker
+	Receiver         receiver.Receiver
+	Tracker          alerts.Tracker
+	Grouper          alerts.Grouper
+	Escalator        alerts.Escalator
+	HeartbeatMonitor alerts.HeartbeatMonitor
+	NotifierFactory  notify.Factory
+}
+
 type Bot struct {
 	cfg                          config.Bot
-	session                      *discordgo.Session
-	repo                         db.Repo
-	scrapeManager                scraper.ScrapeManager
-	doneChan                     chan bool
+	sessions                     *sessions
+	deps                         Dependencies
+	scrapeManager                *leaderScrapeManager
+	electorDone                  chan struct{}
+	membershipDone               chan struct{}
+	rebalance                    chan struct{}
+	cancel                       context.CancelFunc
 	commands                     []*discordgo.ApplicationCommand
+	commandPermissions           CommandPermissions
 	interactionHandlers          InteractionHandlers
 	componentInteractionHandlers MessageInteractionHandlers
+	modalSubmitHandlers          ModalSubmitHandlers
+	autocompleteHandlers         AutocompleteHandlers
 	logger                       logrus.FieldLogger
 }
 
-func New(cfg config.Bot, repo db.Repo, clientFactory prometheus.ClientFactory, scrapeManager scraper.ScrapeManager, logger logrus.FieldLogger) *Bot {
+func New(cfg config.Bot, receiverCfg config.Receiver, prometheusCfg config.Prometheus, deps Dependencies, logger logrus.FieldLogger) *Bot {
+	// Scrapers are only run by the leader, or by the replica whose shard they're in, though every replica handles commands
+	leaderScrapeManager := newLeaderScrapeManager(deps.ScrapeManager, deps.Membership)
+	deps.ScrapeManager = leaderScrapeManager
+
 	commands := getCommands()
-	interactionHandlers := getInteractionHandlers(repo, clientFactory, scrapeManager)
-	componentInteractionHandlers := getMessageInteractionHandlers(repo)
+	commandPermissions := getCommandPermissions()
+	interactionHandlers := getInteractionHandlers(receiverCfg, prometheusCfg, deps.Repo, deps.ClientFactory, leaderScrapeManager, deps.HealthTracker, deps.Receiver, deps.Tracker, deps.Grouper, deps.Escalator, deps.HeartbeatMonitor, deps.NotifierFactory)
+	componentInteractionHandlers := getMessageInteractionHandlers(deps.Repo, deps.Escalator)
+	modalSubmitHandlers := getModalSubmitHandlers(deps.Repo, leaderScrapeManager)
+	autocompleteHandlers := getAutocompleteHandlers(deps.Repo, deps.Tracker)
 
 	return &Bot{
 		cfg:                          cfg,
-		repo:                         repo,
-		scrapeManager:                scrapeManager,
+		deps:                         deps,
+		scrapeManager:                leaderScrapeManager,
+		electorDone:                  make(chan struct{}),
+		membershipDone:               make(chan struct{}),
+		rebalance:                    make(chan struct{}, 1),
 		commands:                     commands,
+		commandPermissions:           commandPermissions,
 		interactionHandlers:          interactionHandlers,
 		componentInteractionHandlers: componentInteractionHandlers,
+		modalSubmitHandlers:          modalSubmitHandlers,
+		autocompleteHandlers:         autocompleteHandlers,
 		logger:                       logger,
-		doneChan:                     make(chan bool, 1),
 	}
 }
 
+// Start connects to Discord and starts scraping. Everything the bot runs in the background is stopped once the context is done, or the bot is closed.
 func (b *Bot) Start(ctx context.Context) error {
 
-	// Create a new Discord session using the provided bot token.
-	b.logger.Infoln("📡 Starting session...")
-	s, err := discordgo.New("Bot " + b.cfg.Token())
+	ctx, b.cancel = context.WithCancel(ctx)
+
+	// Create a Discord session for each gateway shard, each of which receives the events for its own guilds
+	b.logger.Infoln("📡 Starting sessions...")
+	sessions, err := openSessions(ctx, b.cfg, func(s *discordgo.Session) {
+		s.AddHandler(onReadyHandler(b.cfg, b.logger))
+		s.AddHandler(onGuildCreated(ctx, b.commands, b.deps.Repo, b.logger))
+		s.AddHandler(onInteractionCreateHandler(ctx, b.deps.Repo, b.commandPermissions, b.interactionHandlers, b.componentInteractionHandlers, b.modalSubmitHandlers, b.autocompleteHandlers, b.logger))
+		s.AddHandler(onGuildDeleted(ctx, b.deps.Repo, b.logger))
+	}, b.logger)
 	if err != nil {
-		return fmt.Errorf("failed to create Discord session: %s", err.Error())
+		return err
 	}
 
-	// Configure event handlers
-	s.AddHandler(onReadyHandler(b.cfg, b.logger))
-	s.AddHandler(onGuildCreated(b.commands, b.repo, b.logger))
-	s.AddHandler(onInteractionCreateHandler(b.interactionHandlers, b.componentInteractionHandlers, b.logger))
-	s.AddHandler(onGuildDeleted(b.repo, b.logger))
+	b.sessions = sessions
 
-	// Open the session (connect)
-	b.logger.Infoln("📡 Opening session...")
-	err = s.Open()
+	err = handlers.MigrateInhibitedAlerts(ctx, b.deps.Repo)
 	if err != nil {
-		return fmt.Errorf("cannot open session: %s", err.Error())
+		return fmt.Errorf("failed to migrate inhibited alerts: %s", err.Error())
 	}
 
-	// Start scraping for each config
-	guildConfigs, err := b.repo.GetGuildConfigs(ctx)
+	err = handlers.MigrateScrapeIntervals(ctx, b.deps.Repo)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to migrate scrape intervals: %s", err.Error())
 	}
 
-	for _, guildConfig := range guildConfigs {
-		for _, scrapeConfig := range guildConfig.ScrapeConfigs {
-			b.scrapeManager.Start(guildConfig.GuildId, &scrapeConfig)
-		}
-	}
+	// The leader scrapes each config and sends notifications
+	go func() {
+		defer close(b.electorDone)
+		b.deps.Elector.Run(ctx, func(ctx context.Context) {
+			b.lead(ctx, sessions)
+		})
+	}()
+
+	// This replica only leaves once its scrapers have stopped, so that its shard is never scraped by two replicas at once
+	membershipCtx, leave := context.WithCancel(context.Background())
+	go func() {
+		<-b.electorDone
+		leave()
+	}()
 
-	go watchAlerts(b.doneChan, s, b.repo, b.scrapeManager, b.logger)
-	b.session = s
+	go func() {
+		defer close(b.membershipDone)
+		b.deps.Membership.Run(membershipCtx, b.onRebalance)
+	}()
 
 	return nil
 }
 
 func (b *Bot) Close() error {
-	b.doneChan <- true
-	b.logger.Infoln("👋 Closing session...")
-	return b.session.Close()
+	b.cancel()
+
+	b.logger.Infoln("⏳ Stopping scrapers...")
+	ctx, cancel := context.WithTimeout(context.Background(), stopScrapersTimeout)
+	defer cancel()
+
+	// Leadership is given up first, so that another replica can take over as soon as possible
+	select {
+	case <-b.electorDone:
+	case <-ctx.Done():
+		b.logger.Warnln("Timed out waiting to give up leadership")
+	}
+
+	err := b.scrapeManager.StopAll(ctx)
+	if err != nil {
+		b.logger.Errorf("Failed to stop scrapers: %s", err.Error())
+	}
+
+	select {
+	case <-b.membershipDone:
+	case <-ctx.Done():
+		b.logger.Warnln("Timed out waiting to leave the replicas")
+	}
+
+	// The sessions won't have been opened if starting failed
+	if b.sessions == nil {
+		return nil
+	}
+
+	b.logger.Infoln("👋 Closing sessions...")
+	return b.sessions.close()
+}
+
+// onRebalance reconciles the scrapers as soon as possible, rather than waiting for the next scraperReconcileInterval.
+func (b *Bot) onRebalance() {
+	select {
+	case b.rebalance <- struct{}{}:
+	default:
+	}
 }
 
 func getInviteLink(cfg config.Bot) string {
@@ -5,14 +5,20 @@ import (
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/config"
 	"github.com/yukitsune/minialert/db"
 	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/notify"
 	"github.com/yukitsune/minialert/prometheus"
+	"github.com/yukitsune/minialert/receiver"
 	"github.com/yukitsune/minialert/scraper"
+	"net/url"
 	"strings"
+	"time"
 )
 
-type InteractionHandler func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger)
+type InteractionHandler func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger)
 type InteractionHandlers map[InteractionName]InteractionHandler
 
 const MessageInteractionIdSeparator = ":"
@@ -54,41 +60,1079 @@ func NewMessageInteractionId(name InteractionName, values ...string) MessageInte
 }
 
 type MessageInteractionHandlers map[InteractionName]InteractionHandler
+type ModalSubmitHandlers map[InteractionName]InteractionHandler
 
-func getInteractionHandlers(repo db.Repo, clientFactory prometheus.ClientFactory, scrapeManager scraper.ScrapeManager) InteractionHandlers {
+func getInteractionHandlers(receiverCfg config.Receiver, prometheusCfg config.Prometheus, repo db.Repo, clientFactory prometheus.ClientFactory, scrapeManager scraper.ScrapeManager, healthTracker scraper.HealthTracker, receiver receiver.Receiver, tracker alerts.Tracker, grouper alerts.Grouper, escalator alerts.Escalator, heartbeatMonitor alerts.HeartbeatMonitor, notifierFactory notify.Factory) InteractionHandlers {
 	return map[InteractionName]InteractionHandler{
-		GetAlertsCommandName: getAlertsHandler(repo, clientFactory),
+		GetAlertsCommandName:    getAlertsHandler(repo, clientFactory),
+		AlertHistoryCommandName: alertHistoryHandler(repo),
 
 		ShowInhibitedAlertsCommandName: showInhibitedAlertsHandler(repo),
 		InhibitAlertCommandName:        inhibitAlertHandler(repo),
 		UninhibitAlertCommandName:      uninhibitAlertHandler(repo),
 
+		SilenceCommandName:       silenceHandler(repo),
+		ListSilencesCommandName:  listSilencesHandler(repo),
+		ExpireSilenceCommandName: expireSilenceHandler(repo),
+
+		AddInhibitionRuleCommandName:    addInhibitionRuleHandler(repo),
+		ListInhibitionRulesCommandName:  listInhibitionRulesHandler(repo),
+		RemoveInhibitionRuleCommandName: removeInhibitionRuleHandler(repo),
+
+		AddRouteCommandName:    addRouteHandler(repo),
+		ListRoutesCommandName:  listRoutesHandler(repo),
+		RemoveRouteCommandName: removeRouteHandler(repo),
+
+		EnableReceiverCommandName:  enableReceiverHandler(receiverCfg, repo, scrapeManager),
+		DisableReceiverCommandName: disableReceiverHandler(repo, scrapeManager, receiver),
+
+		AddNotifierCommandName:    addNotifierHandler(repo, notifierFactory),
+		ListNotifiersCommandName:  listNotifiersHandler(repo),
+		RemoveNotifierCommandName: removeNotifierHandler(repo),
+
+		AddEscalationStepCommandName:    addEscalationStepHandler(repo),
+		ListEscalationStepsCommandName:  listEscalationStepsHandler(repo),
+		RemoveEscalationStepCommandName: removeEscalationStepHandler(repo),
+
+		GrantPermissionCommandName:  grantPermissionHandler(repo),
+		RevokePermissionCommandName: revokePermissionHandler(repo),
+		ListPermissionsCommandName:  listPermissionsHandler(repo),
+
+		ScrapeStatusCommandName:         scrapeStatusHandler(repo, scrapeManager, healthTracker),
+		SetMinScrapeIntervalCommandName: setMinScrapeIntervalHandler(repo, prometheusCfg, scrapeManager),
+		SetCredentialsCommandName:       setCredentialsHandler(repo),
+		SetTLSCommandName:               setTLSHandler(repo),
+
 		ListScrapeConfigsCommandName:  listScrapeConfigsCommandHandler(repo),
-		CreateScrapeConfigCommandName: createScrapeConfigCommandHandler(repo, scrapeManager),
-		UpdateScrapeConfigCommandName: updateScrapeConfigCommandHandler(repo, scrapeManager),
-		RemoveScrapeConfigCommandName: removeScrapeConfigCommandHandler(repo, scrapeManager),
+		CreateScrapeConfigCommandName: createScrapeConfigCommandHandler(repo, prometheusCfg, scrapeManager),
+		UpdateScrapeConfigCommandName: updateScrapeConfigCommandHandler(repo, prometheusCfg, scrapeManager),
+		RemoveScrapeConfigCommandName: removeScrapeConfigCommandHandler(repo, scrapeManager, healthTracker, receiver, tracker, grouper, escalator, heartbeatMonitor),
+	}
+}
+
+func getMessageInteractionHandlers(repo db.Repo, escalator alerts.Escalator) MessageInteractionHandlers {
+	return map[InteractionName]InteractionHandler{
+		InhibitAlertCommandName: inhibitAlertFromMessageHandler(repo),
+		AcknowledgeCommandName:  acknowledgeFromMessageHandler(repo, escalator),
+	}
+}
+
+func getModalSubmitHandlers(repo db.Repo, scrapeManager scraper.ScrapeManager) ModalSubmitHandlers {
+	return map[InteractionName]InteractionHandler{
+		SetCredentialsCommandName: setCredentialsFromModalHandler(repo, scrapeManager),
+		SetTLSCommandName:         setTLSFromModalHandler(repo, scrapeManager),
+	}
+}
+
+func getOptionMap(options []*discordgo.ApplicationCommandInteractionDataOption) map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption {
+	optionMap := make(map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[InteractionOption(opt.Name)] = opt
+	}
+
+	return optionMap
+}
+
+func setGroupingOptions(scrapeConfig *db.ScrapeConfig, opts map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption) {
+	if groupByOpt, ok := opts[GroupByOption]; ok {
+		scrapeConfig.GroupBy = parseLabelNames(groupByOpt.StringValue())
+	}
+
+	if groupWaitOpt, ok := opts[GroupWaitOption]; ok {
+		scrapeConfig.GroupWaitSeconds = groupWaitOpt.IntValue()
+	}
+
+	if groupIntervalOpt, ok := opts[GroupIntervalOption]; ok {
+		scrapeConfig.GroupIntervalSeconds = groupIntervalOpt.IntValue()
+	}
+
+	if repeatIntervalOpt, ok := opts[RepeatIntervalOption]; ok {
+		scrapeConfig.RepeatIntervalSeconds = repeatIntervalOpt.IntValue()
+	}
+}
+
+// setConnectionOptions sets the source type, tenant and TLS options on the scrape config.
+func setConnectionOptions(scrapeConfig *db.ScrapeConfig, opts map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption) error {
+	if sourceTypeOpt, ok := opts[SourceTypeOption]; ok {
+		sourceType := db.SourceType(sourceTypeOpt.StringValue())
+		if !prometheus.IsValidSourceType(sourceType) {
+			return fmt.Errorf("unknown source type %s", sourceType)
+		}
+
+		scrapeConfig.SourceType = sourceType
+	}
+
+	if timeoutOpt, ok := opts[ScrapeTimeoutOption]; ok {
+		timeout, err := time.ParseDuration(timeoutOpt.StringValue())
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid timeout, use a duration such as 15s")
+		}
+
+		scrapeConfig.ScrapeTimeout = ""
+		if timeout > 0 {
+			scrapeConfig.ScrapeTimeout = timeout.String()
+		}
+	}
+
+	if tenantOpt, ok := opts[TenantOption]; ok {
+		scrapeConfig.TenantId = strings.TrimSpace(tenantOpt.StringValue())
+	}
+
+	if insecureSkipVerifyOpt, ok := opts[InsecureSkipVerifyOption]; ok {
+		insecureSkipVerify := insecureSkipVerifyOpt.BoolValue()
+		scrapeConfig.TLS.InsecureSkipVerify = &insecureSkipVerify
+	}
+
+	return nil
+}
+
+// heartbeatDisabled is used as the heartbeat alert name to stop monitoring the heartbeat.
+const heartbeatDisabled = "none"
+
+func setHeartbeatOptions(scrapeConfig *db.ScrapeConfig, opts map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption) {
+	if heartbeatAlertOpt, ok := opts[HeartbeatAlertOption]; ok {
+		alertName := strings.TrimSpace(heartbeatAlertOpt.StringValue())
+		if strings.EqualFold(alertName, heartbeatDisabled) {
+			alertName = ""
+		}
+
+		scrapeConfig.HeartbeatAlertName = alertName
+	}
+
+	if heartbeatTimeoutOpt, ok := opts[HeartbeatTimeoutOption]; ok {
+		scrapeConfig.HeartbeatTimeoutSeconds = heartbeatTimeoutOpt.IntValue()
+	}
+}
+
+func parseLabelNames(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func getUserId(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+
+	if i.User != nil {
+		return i.User.ID
+	}
+
+	return ""
+}
+
+func getAlertsHandler(repo db.Repo, clientFactory prometheus.ClientFactory) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+
+		firingAlerts, err := handlers.GetAlerts(ctx, repo, clientFactory, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get alerts: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get alerts.")
+			return
+		}
+
+		scrapeConfig, err := getScrapeConfig(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get scrape config: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get alerts.")
+			return
+		}
+
+		notifications := alerts.GroupAlerts(i.GuildID, configName, alerts.NewGroupingOptions(scrapeConfig), firingAlerts, time.Now())
+		if len(notifications) == 0 {
+			respond(s, i, logger, fmt.Sprintf("No alerts are firing for %s.", configName))
+			return
+		}
+
+		// Each group gets its own message, the same as notifications, so that each group can be acknowledged
+		for n, notification := range notifications {
+			acknowledged, err := handlers.IsAcknowledged(ctx, repo, i.GuildID, configName, notification.GroupKey, getFiringSince(notification.Firing))
+			if err != nil {
+				logger.Errorf("Failed to check acknowledgements: %s", err.Error())
+			}
+
+			message := getNotificationMessage(notification, acknowledged, logger)
+
+			if n == 0 {
+				err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Embeds:     message.Embeds,
+						Components: message.Components,
+					},
+				})
+			} else {
+				_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+					Embeds:     message.Embeds,
+					Components: message.Components,
+				})
+			}
+
+			if err != nil {
+				logger.Errorf("Failed to send alerts for group %s: %s", notification.GroupKey, err.Error())
+				return
+			}
+		}
+	}
+}
+
+func showInhibitedAlertsHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+		inhibitions, err := handlers.GetInhibitions(ctx, configName, i.GuildID, repo)
+		if err != nil {
+			logger.Errorf("Failed to get inhibitions: %s", err.Error())
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to get inhibitions: %s", err))
+			return
+		}
+
+		if len(inhibitions) == 0 {
+			respond(s, i, logger, fmt.Sprintf("No inhibitions set for %s.", configName))
+			return
+		}
+
+		content := strings.Join(inhibitions, ",")
+		respond(s, i, logger, content)
+	}
+}
+
+func inhibitAlertHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+
+		alertNameOpt, ok := opts[AlertNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Could not find alert name option.")
+			return
+		}
+
+		alertName := alertNameOpt.StringValue()
+
+		err := handlers.InhibitAlert(ctx, configName, i.GuildID, alertName, getUserId(i), repo)
+		if err != nil {
+			logger.Errorf("Failed to inhibit alert: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to add inhibition.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, "Inhibition added.")
+	}
+}
+
+func uninhibitAlertHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+
+		alertNameOpt, ok := opts[AlertNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Could not find alert name option.")
+			return
+		}
+
+		alertName := alertNameOpt.StringValue()
+
+		err := handlers.UninhibitAlert(ctx, configName, i.GuildID, alertName, repo)
+		if err != nil {
+			logger.Errorf("Failed to set guild config: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to remove inhibition.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, "Inhibition removed.")
+	}
+}
+
+func inhibitAlertFromMessageHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		customId := MessageInteractionId(i.Interaction.MessageComponentData().CustomID)
+		values, ok := customId.Values()
+		if !ok || len(values) != 2 {
+			respondWithWarning(s, i, logger, fmt.Sprintf("Received unknown custom_id: %s", customId))
+			return
+		}
+
+		configName := values[0]
+		alertName := values[1]
+
+		err := handlers.InhibitAlert(ctx, configName, i.GuildID, alertName, getUserId(i), repo)
+		if err != nil {
+			logger.Errorf("Failed to set guild config: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to add inhibition.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, "Inhibition added.")
+	}
+}
+
+func acknowledgeFromMessageHandler(repo db.Repo, escalator alerts.Escalator) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		customId := MessageInteractionId(i.Interaction.MessageComponentData().CustomID)
+		values, ok := customId.Values()
+		if !ok || len(values) != 2 {
+			respondWithWarning(s, i, logger, fmt.Sprintf("Received unknown custom_id: %s", customId))
+			return
+		}
+
+		configName := values[0]
+		fingerprint := values[1]
+
+		acknowledgement, err := handlers.AcknowledgeAlert(ctx, repo, i.GuildID, configName, fingerprint, getUserId(i), time.Now())
+		if err != nil {
+			logger.Errorf("Failed to acknowledge alert: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to acknowledge alert.")
+			return
+		}
+
+		escalator.Acknowledge(i.GuildID, configName, fingerprint)
+
+		content := fmt.Sprintf("👀 Acknowledged by <@%s> <t:%d:R>", acknowledgement.AcknowledgedBy, acknowledgement.AcknowledgedAt.Unix())
+		if len(i.Message.Content) > 0 {
+			content = i.Message.Content + "\n" + content
+		}
+
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:         content,
+				Embeds:          i.Message.Embeds,
+				Components:      disableButton(i.Message.Components, customId),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+
+		if err != nil {
+			logger.Errorf("Failed to respond: %s", err.Error())
+		}
+	}
+}
+
+// disableButton disables the button with the given custom ID so that it can't be clicked again.
+func disableButton(components []discordgo.MessageComponent, customId MessageInteractionId) []discordgo.MessageComponent {
+	for _, component := range components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+
+		for _, rowComponent := range row.Components {
+			if button, ok := rowComponent.(*discordgo.Button); ok && button.CustomID == customId.String() {
+				button.Disabled = true
+			}
+		}
 	}
+
+	return components
 }
 
-func getMessageInteractionHandlers(repo db.Repo) MessageInteractionHandlers {
-	return map[InteractionName]InteractionHandler{
-		InhibitAlertCommandName: inhibitAlertFromMessageHandler(repo),
+func addEscalationStepHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		delayOpt, ok := opts[DelayOption]
+		if !ok {
+			respondWithError(s, i, logger, "Delay is required.")
+			return
+		}
+
+		step := db.EscalationStep{
+			DelayMinutes: delayOpt.IntValue(),
+		}
+
+		if roleOpt, ok := opts[RoleOption]; ok {
+			step.RoleId = roleOpt.RoleValue(nil, "").ID
+		}
+
+		if userOpt, ok := opts[UserOption]; ok {
+			step.UserId = userOpt.UserValue(nil).ID
+		}
+
+		addedStep, err := handlers.AddEscalationStep(ctx, repo, i.GuildID, configNameOpt.StringValue(), step)
+		if err != nil {
+			logger.Errorf("Failed to add escalation step: %s", err.Error())
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to add escalation step: %s", err.Error()))
+			return
+		}
+
+		respondWithSuccess(s, i, logger, fmt.Sprintf("Escalation step `%s` added.", addedStep.Id))
+	}
+}
+
+func listEscalationStepsHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+		steps, err := handlers.GetEscalationSteps(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get escalation steps: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get escalation steps.")
+			return
+		}
+
+		if len(steps) == 0 {
+			respond(s, i, logger, fmt.Sprintf("No escalation steps set for %s.", configName))
+			return
+		}
+
+		var lines []string
+		for _, step := range steps {
+			lines = append(lines, fmt.Sprintf("`%s` after %d minutes, mention %s", step.Id, step.DelayMinutes, formatMentions(step)))
+		}
+
+		// Listing the steps shouldn't ping anyone
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         truncate(strings.Join(lines, "\n"), 2000),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+
+		if err != nil {
+			logger.Errorf("Failed to respond: %s", err.Error())
+		}
+	}
+}
+
+func removeEscalationStepHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		stepIdOpt, ok := opts[EscalationStepIdOption]
+		if !ok {
+			respondWithError(s, i, logger, "Escalation step ID is required.")
+			return
+		}
+
+		err := handlers.RemoveEscalationStep(ctx, repo, i.GuildID, configNameOpt.StringValue(), stepIdOpt.StringValue())
+		if err != nil {
+			logger.Errorf("Failed to remove escalation step: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to remove escalation step.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, "Escalation step removed.")
+	}
+}
+
+func silenceHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		matchersOpt, ok := opts[MatchersOption]
+		if !ok {
+			respondWithError(s, i, logger, "Matchers are required.")
+			return
+		}
+
+		matchers, err := prometheus.ParseMatchers(matchersOpt.StringValue())
+		if err != nil {
+			respondWithError(s, i, logger, fmt.Sprintf("Invalid matchers: %s", err.Error()))
+			return
+		}
+
+		durationOpt, ok := opts[DurationOption]
+		if !ok {
+			respondWithError(s, i, logger, "Duration is required.")
+			return
+		}
+
+		duration, err := time.ParseDuration(durationOpt.StringValue())
+		if err != nil || duration <= 0 {
+			respondWithError(s, i, logger, "Duration must be a positive duration, E.g: 2h30m.")
+			return
+		}
+
+		var comment string
+		if commentOpt, ok := opts[CommentOption]; ok {
+			comment = commentOpt.StringValue()
+		}
+
+		silence, err := handlers.CreateSilence(ctx, repo, i.GuildID, configNameOpt.StringValue(), matchers, getUserId(i), comment, duration)
+		if err != nil {
+			logger.Errorf("Failed to create silence: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to create silence.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, fmt.Sprintf("Silence `%s` created, expires <t:%d:R>.", silence.Id, silence.EndsAt.Unix()))
+	}
+}
+
+func listSilencesHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+		silences, err := handlers.GetSilences(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get silences: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get silences.")
+			return
+		}
+
+		if len(silences) == 0 {
+			respond(s, i, logger, fmt.Sprintf("No silences set for %s.", configName))
+			return
+		}
+
+		var lines []string
+		for _, silence := range silences {
+			lines = append(lines, formatSilence(silence))
+		}
+
+		respond(s, i, logger, truncate(strings.Join(lines, "\n"), 2000))
+	}
+}
+
+// defaultAlertHistoryRange is how far back /alert-history looks when no start time is given.
+const defaultAlertHistoryRange = 24 * time.Hour
+
+func alertHistoryHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		var configName string
+		if configNameOpt, ok := opts[ScrapeConfigNameOption]; ok {
+			configName = configNameOpt.StringValue()
+		}
+
+		var matchers []db.Matcher
+		if matchersOpt, ok := opts[MatchersOption]; ok {
+			var err error
+			matchers, err = prometheus.ParseMatchers(matchersOpt.StringValue())
+			if err != nil {
+				respondWithError(s, i, logger, fmt.Sprintf("Invalid matchers: %s", err.Error()))
+				return
+			}
+		}
+
+		if alertNameOpt, ok := opts[AlertNameOption]; ok {
+			matchers = append(matchers, db.Matcher{
+				Name:  "alertname",
+				Type:  db.MatchEqual,
+				Value: alertNameOpt.StringValue(),
+			})
+		}
+
+		now := time.Now()
+		since := now.Add(-defaultAlertHistoryRange)
+		if sinceOpt, ok := opts[SinceOption]; ok {
+			duration, err := time.ParseDuration(sinceOpt.StringValue())
+			if err != nil || duration <= 0 {
+				respondWithError(s, i, logger, "Since must be a positive duration, E.g: 24h.")
+				return
+			}
+
+			since = now.Add(-duration)
+		}
+
+		var until time.Time
+		if untilOpt, ok := opts[UntilOption]; ok {
+			duration, err := time.ParseDuration(untilOpt.StringValue())
+			if err != nil || duration < 0 {
+				respondWithError(s, i, logger, "Until must be a positive duration, E.g: 1h.")
+				return
+			}
+
+			until = now.Add(-duration)
+		}
+
+		page := 1
+		if pageOpt, ok := opts[PageOption]; ok {
+			page = int(pageOpt.IntValue())
+		}
+
+		events, pages, err := handlers.GetAlertHistory(ctx, repo, i.GuildID, configName, matchers, since, until, page)
+		if err != nil {
+			logger.Errorf("Failed to get alert history: %s", err.Error())
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to get alert history: %s", err.Error()))
+			return
+		}
+
+		if len(events) == 0 {
+			respond(s, i, logger, "No alerts found.")
+			return
+		}
+
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{
+					getAlertHistoryEmbed(events, page, pages),
+				},
+			},
+		})
+
+		if err != nil {
+			logger.Errorf("Failed to respond: %s", err.Error())
+		}
+	}
+}
+
+func getAlertHistoryEmbed(events []db.AlertEvent, page int, pages int) *discordgo.MessageEmbed {
+	var fields []*discordgo.MessageEmbedField
+	for _, event := range events {
+		var value strings.Builder
+		value.WriteString(fmt.Sprintf("`%s`\n", notify.FormatLabels(event.Labels)))
+
+		if event.Status == db.AlertResolved {
+			value.WriteString(fmt.Sprintf("Resolved <t:%d:R> after %s", event.Timestamp.Unix(), time.Duration(event.DurationSeconds)*time.Second))
+		} else {
+			value.WriteString(fmt.Sprintf("Started firing <t:%d:R>", event.Timestamp.Unix()))
+		}
+
+		if len(event.ScrapeConfigName) > 0 {
+			value.WriteString(fmt.Sprintf(" (%s)", event.ScrapeConfigName))
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%s %s", strings.ToUpper(event.Status.String()), event.Labels["alertname"]),
+			Value:  truncate(value.String(), 1024),
+			Inline: false,
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:   discordgo.EmbedTypeRich,
+		Title:  "Alert history",
+		Fields: fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d of %d", page, pages),
+		},
+	}
+}
+
+func grantPermissionHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		roleOpt, ok := opts[RoleOption]
+		if !ok {
+			respondWithError(s, i, logger, "Role is required.")
+			return
+		}
+
+		levelOpt, ok := opts[PermissionLevelOption]
+		if !ok {
+			respondWithError(s, i, logger, "Level is required.")
+			return
+		}
+
+		roleId := roleOpt.RoleValue(nil, "").ID
+		err := handlers.GrantPermission(ctx, repo, i.GuildID, roleId, db.PermissionLevel(levelOpt.StringValue()))
+		if err != nil {
+			logger.Errorf("Failed to grant permission: %s", err.Error())
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to grant permission: %s", err.Error()))
+			return
+		}
+
+		respondWithSuccessWithoutMentions(s, i, logger, fmt.Sprintf("Granted %s permission to <@&%s>.", levelOpt.StringValue(), roleId))
+	}
+}
+
+func revokePermissionHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		roleOpt, ok := opts[RoleOption]
+		if !ok {
+			respondWithError(s, i, logger, "Role is required.")
+			return
+		}
+
+		roleId := roleOpt.RoleValue(nil, "").ID
+		err := handlers.RevokePermission(ctx, repo, i.GuildID, roleId)
+		if err != nil {
+			logger.Errorf("Failed to revoke permission: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to revoke permission.")
+			return
+		}
+
+		respondWithSuccessWithoutMentions(s, i, logger, fmt.Sprintf("Revoked permissions from <@&%s>.", roleId))
+	}
+}
+
+func listPermissionsHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		permissions, err := handlers.GetPermissions(ctx, repo, i.GuildID)
+		if err != nil {
+			logger.Errorf("Failed to get permissions: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get permissions.")
+			return
+		}
+
+		if len(permissions) == 0 {
+			respond(s, i, logger, "No permissions have been granted. Everyone can use every command, other than granting and revoking permissions.")
+			return
+		}
+
+		var lines []string
+		for _, permission := range permissions {
+			lines = append(lines, fmt.Sprintf("<@&%s> %s", permission.RoleId, permission.Level))
+		}
+
+		respondWithoutMentions(s, i, logger, truncate(strings.Join(lines, "\n"), 2000))
+	}
+}
+
+func formatSilence(silence db.Silence) string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("`%s` `%s`", silence.Id, prometheus.FormatMatchers(silence.Matchers)))
+
+	if silence.EndsAt.IsZero() {
+		str.WriteString(" never expires")
+	} else {
+		str.WriteString(fmt.Sprintf(" expires <t:%d:R>", silence.EndsAt.Unix()))
+	}
+
+	if len(silence.CreatedBy) > 0 {
+		str.WriteString(fmt.Sprintf(", created by <@%s>", silence.CreatedBy))
+	}
+
+	if len(silence.Comment) > 0 {
+		str.WriteString(fmt.Sprintf(": %s", silence.Comment))
+	}
+
+	return str.String()
+}
+
+func expireSilenceHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		silenceIdOpt, ok := opts[SilenceIdOption]
+		if !ok {
+			respondWithError(s, i, logger, "Silence ID is required.")
+			return
+		}
+
+		err := handlers.ExpireSilence(ctx, repo, i.GuildID, silenceIdOpt.StringValue())
+		if err != nil {
+			logger.Errorf("Failed to expire silence: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to expire silence.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, "Silence expired.")
+	}
+}
+
+func addInhibitionRuleHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		sourceMatchersOpt, ok := opts[SourceMatchersOption]
+		if !ok {
+			respondWithError(s, i, logger, "Source matchers are required.")
+			return
+		}
+
+		sourceMatchers, err := prometheus.ParseMatchers(sourceMatchersOpt.StringValue())
+		if err != nil {
+			respondWithError(s, i, logger, fmt.Sprintf("Invalid source matchers: %s", err.Error()))
+			return
+		}
+
+		targetMatchersOpt, ok := opts[TargetMatchersOption]
+		if !ok {
+			respondWithError(s, i, logger, "Target matchers are required.")
+			return
+		}
+
+		targetMatchers, err := prometheus.ParseMatchers(targetMatchersOpt.StringValue())
+		if err != nil {
+			respondWithError(s, i, logger, fmt.Sprintf("Invalid target matchers: %s", err.Error()))
+			return
+		}
+
+		rule := db.InhibitionRule{
+			SourceMatchers: sourceMatchers,
+			TargetMatchers: targetMatchers,
+		}
+
+		if equalOpt, ok := opts[EqualOption]; ok {
+			rule.Equal = parseLabelNames(equalOpt.StringValue())
+		}
+
+		addedRule, err := handlers.AddInhibitionRule(ctx, repo, i.GuildID, configNameOpt.StringValue(), rule)
+		if err != nil {
+			logger.Errorf("Failed to add inhibition rule: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to add inhibition rule.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, fmt.Sprintf("Inhibition rule `%s` added.", addedRule.Id))
+	}
+}
+
+func listInhibitionRulesHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+		rules, err := handlers.GetInhibitionRules(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get inhibition rules: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get inhibition rules.")
+			return
+		}
+
+		if len(rules) == 0 {
+			respond(s, i, logger, fmt.Sprintf("No inhibition rules set for %s.", configName))
+			return
+		}
+
+		var lines []string
+		for _, rule := range rules {
+			line := fmt.Sprintf("`%s` `%s` inhibits `%s`", rule.Id, prometheus.FormatMatchers(rule.SourceMatchers), prometheus.FormatMatchers(rule.TargetMatchers))
+			if len(rule.Equal) > 0 {
+				line += fmt.Sprintf(" when `%s` are equal", strings.Join(rule.Equal, ", "))
+			}
+
+			lines = append(lines, line)
+		}
+
+		respond(s, i, logger, truncate(strings.Join(lines, "\n"), 2000))
+	}
+}
+
+func removeInhibitionRuleHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		ruleIdOpt, ok := opts[RuleIdOption]
+		if !ok {
+			respondWithError(s, i, logger, "Rule ID is required.")
+			return
+		}
+
+		err := handlers.RemoveInhibitionRule(ctx, repo, i.GuildID, configNameOpt.StringValue(), ruleIdOpt.StringValue())
+		if err != nil {
+			logger.Errorf("Failed to remove inhibition rule: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to remove inhibition rule.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, "Inhibition rule removed.")
+	}
+}
+
+func addRouteHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		matchersOpt, ok := opts[MatchersOption]
+		if !ok {
+			respondWithError(s, i, logger, "Matchers are required.")
+			return
+		}
+
+		matchers, err := prometheus.ParseMatchers(matchersOpt.StringValue())
+		if err != nil {
+			respondWithError(s, i, logger, fmt.Sprintf("Invalid matchers: %s", err.Error()))
+			return
+		}
+
+		route := db.Route{
+			Matchers: matchers,
+		}
+
+		if channelOpt, ok := opts[ChannelOption]; ok {
+			channel := channelOpt.ChannelValue(s)
+			if channel.Type != discordgo.ChannelTypeGuildText {
+				respondWithError(s, i, logger, "Alerts channel must be a text channel.")
+				return
+			}
+
+			route.ChannelId = channel.ID
+		}
+
+		if continueOpt, ok := opts[ContinueOption]; ok {
+			route.Continue = continueOpt.BoolValue()
+		}
+
+		var parentRouteId string
+		if parentRouteIdOpt, ok := opts[ParentRouteIdOption]; ok {
+			parentRouteId = parentRouteIdOpt.StringValue()
+		}
+
+		addedRoute, err := handlers.AddRoute(ctx, repo, i.GuildID, configNameOpt.StringValue(), parentRouteId, route)
+		if err != nil {
+			logger.Errorf("Failed to add route: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to add route.")
+			return
+		}
+
+		respondWithSuccess(s, i, logger, fmt.Sprintf("Route `%s` added.", addedRoute.Id))
+	}
+}
+
+func listRoutesHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+		routes, err := handlers.GetRoutes(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get routes: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get routes.")
+			return
+		}
+
+		if len(routes) == 0 {
+			respond(s, i, logger, fmt.Sprintf("No routes set for %s.", configName))
+			return
+		}
+
+		lines := formatRoutes(routes, 0)
+		respond(s, i, logger, truncate(strings.Join(lines, "\n"), 2000))
 	}
 }
 
-func getOptionMap(options []*discordgo.ApplicationCommandInteractionDataOption) map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption {
-	optionMap := make(map[InteractionOption]*discordgo.ApplicationCommandInteractionDataOption, len(options))
-	for _, opt := range options {
-		optionMap[InteractionOption(opt.Name)] = opt
+func formatRoutes(routes []db.Route, depth int) []string {
+	var lines []string
+	for _, route := range routes {
+		line := fmt.Sprintf("%s`%s` `%s`", strings.Repeat("    ", depth), route.Id, prometheus.FormatMatchers(route.Matchers))
+		if len(route.ChannelId) > 0 {
+			line += fmt.Sprintf(" → <#%s>", route.ChannelId)
+		}
+
+		if route.Continue {
+			line += " (continue)"
+		}
+
+		lines = append(lines, line)
+		lines = append(lines, formatRoutes(route.Routes, depth+1)...)
 	}
 
-	return optionMap
+	return lines
 }
 
-func getAlertsHandler(repo db.Repo, clientFactory prometheus.ClientFactory) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
-
-		ctx := context.TODO()
+func removeRouteHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		opts := getOptionMap(i.ApplicationCommandData().Options)
 
@@ -98,22 +1142,25 @@ func getAlertsHandler(repo db.Repo, clientFactory prometheus.ClientFactory) Inte
 			return
 		}
 
-		configName := configNameOpt.StringValue()
+		routeIdOpt, ok := opts[RouteIdOption]
+		if !ok {
+			respondWithError(s, i, logger, "Route ID is required.")
+			return
+		}
 
-		alerts, err := handlers.GetAlerts(ctx, repo, clientFactory, i.GuildID, configName)
+		err := handlers.RemoveRoute(ctx, repo, i.GuildID, configNameOpt.StringValue(), routeIdOpt.StringValue())
 		if err != nil {
-			logger.Errorf("Failed to get alerts: %s", err.Error())
-			respondWithError(s, i, logger, "Failed to get alerts.")
+			logger.Errorf("Failed to remove route: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to remove route.")
+			return
 		}
 
-		sendAlertsToChannel(s, configName, i.ChannelID, alerts, logger)
+		respondWithSuccess(s, i, logger, "Route removed.")
 	}
 }
 
-func showInhibitedAlertsHandler(repo db.Repo) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
-
-		ctx := context.TODO()
+func addNotifierHandler(repo db.Repo, notifierFactory notify.Factory) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		opts := getOptionMap(i.ApplicationCommandData().Options)
 
@@ -123,28 +1170,37 @@ func showInhibitedAlertsHandler(repo db.Repo) InteractionHandler {
 			return
 		}
 
-		configName := configNameOpt.StringValue()
-		inhibitions, err := handlers.GetInhibitions(ctx, configName, i.GuildID, repo)
-		if err != nil {
-			logger.Errorf("Failed to get inhibitions: %s", err.Error())
-			respondWithError(s, i, logger, fmt.Sprintf("Failed to get inhibitions: %s", err))
+		typeOpt, ok := opts[NotifierTypeOption]
+		if !ok {
+			respondWithError(s, i, logger, "Type is required.")
 			return
 		}
 
-		if len(inhibitions) == 0 {
-			respond(s, i, logger, fmt.Sprintf("No inhibitions set for %s.", configName))
+		notifier := db.NotifierConfig{
+			Type: db.NotifierType(typeOpt.StringValue()),
+		}
+
+		if urlOpt, ok := opts[UrlOption]; ok {
+			notifier.Url = urlOpt.StringValue()
+		}
+
+		if toOpt, ok := opts[ToOption]; ok {
+			notifier.To = parseLabelNames(toOpt.StringValue())
+		}
+
+		addedNotifier, err := handlers.AddNotifier(ctx, repo, notifierFactory, i.GuildID, configNameOpt.StringValue(), notifier)
+		if err != nil {
+			logger.Errorf("Failed to add notifier: %s", err.Error())
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to add notifier: %s", err.Error()))
 			return
 		}
 
-		content := strings.Join(inhibitions, ",")
-		respond(s, i, logger, content)
+		respondWithSuccess(s, i, logger, fmt.Sprintf("Notifier `%s` added.", addedNotifier.Id))
 	}
 }
 
-func inhibitAlertHandler(repo db.Repo) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
-
-		ctx := context.TODO()
+func listNotifiersHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		opts := getOptionMap(i.ApplicationCommandData().Options)
 
@@ -155,30 +1211,40 @@ func inhibitAlertHandler(repo db.Repo) InteractionHandler {
 		}
 
 		configName := configNameOpt.StringValue()
+		notifiers, err := handlers.GetNotifiers(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get notifiers: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to get notifiers.")
+			return
+		}
 
-		alertNameOpt, ok := opts[AlertNameOption]
-		if !ok {
-			respondWithError(s, i, logger, "Could not find alert name option.")
+		if len(notifiers) == 0 {
+			respond(s, i, logger, fmt.Sprintf("No notifiers set for %s.", configName))
 			return
 		}
 
-		alertName := alertNameOpt.StringValue()
+		var lines []string
+		for _, notifier := range notifiers {
+			line := fmt.Sprintf("`%s` %s", notifier.Id, notifier.Type)
+			switch notifier.Type {
+			case db.WebhookNotifier, db.SlackNotifier:
+				// URLs often contain secrets, so only show the host
+				if u, err := url.Parse(notifier.Url); err == nil {
+					line += fmt.Sprintf(" `%s`", u.Host)
+				}
+			case db.EmailNotifier:
+				line += fmt.Sprintf(" `%s`", strings.Join(notifier.To, ", "))
+			}
 
-		err := handlers.InhibitAlert(ctx, configName, i.GuildID, alertName, repo)
-		if err != nil {
-			logger.Errorf("Failed to inhibit alert: %s", err.Error())
-			respondWithError(s, i, logger, "Failed to add inhibition.")
-			return
+			lines = append(lines, line)
 		}
 
-		respondWithSuccess(s, i, logger, "Inhibition added.")
+		respond(s, i, logger, truncate(strings.Join(lines, "\n"), 2000))
 	}
 }
 
-func uninhibitAlertHandler(repo db.Repo) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
-
-		ctx := context.TODO()
+func removeNotifierHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		opts := getOptionMap(i.ApplicationCommandData().Options)
 
@@ -188,57 +1254,96 @@ func uninhibitAlertHandler(repo db.Repo) InteractionHandler {
 			return
 		}
 
-		configName := configNameOpt.StringValue()
-
-		alertNameOpt, ok := opts[AlertNameOption]
+		notifierIdOpt, ok := opts[NotifierIdOption]
 		if !ok {
-			respondWithError(s, i, logger, "Could not find alert name option.")
+			respondWithError(s, i, logger, "Notifier ID is required.")
 			return
 		}
 
-		alertName := alertNameOpt.StringValue()
-
-		err := handlers.UninhibitAlert(ctx, configName, i.GuildID, alertName, repo)
+		err := handlers.RemoveNotifier(ctx, repo, i.GuildID, configNameOpt.StringValue(), notifierIdOpt.StringValue())
 		if err != nil {
-			logger.Errorf("Failed to set guild config: %s", err.Error())
-			respondWithError(s, i, logger, "Failed to remove inhibition.")
+			logger.Errorf("Failed to remove notifier: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to remove notifier.")
 			return
 		}
 
-		respondWithSuccess(s, i, logger, "Inhibition removed.")
+		respondWithSuccess(s, i, logger, "Notifier removed.")
 	}
 }
 
-func inhibitAlertFromMessageHandler(repo db.Repo) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
-
-		ctx := context.TODO()
+func enableReceiverHandler(receiverCfg config.Receiver, repo db.Repo, scrapeManager scraper.ScrapeManager) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
-		customId := MessageInteractionId(i.Interaction.MessageComponentData().CustomID)
-		values, ok := customId.Values()
-		if !ok || len(values) != 2 {
-			respondWithWarning(s, i, logger, fmt.Sprintf("Received unknown custom_id: %s", customId))
+		if !receiverCfg.Enabled() {
+			respondWithError(s, i, logger, "The receiver is not enabled.")
 			return
 		}
 
-		configName := values[0]
-		alertName := values[1]
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
 
-		err := handlers.InhibitAlert(ctx, configName, i.GuildID, alertName, repo)
+		token, err := handlers.EnableReceiver(ctx, repo, scrapeManager, i.GuildID, configNameOpt.StringValue())
 		if err != nil {
-			logger.Errorf("Failed to set guild config: %s", err.Error())
-			respondWithError(s, i, logger, "Failed to add inhibition.")
+			logger.Errorf("Failed to enable receiver: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to enable receiver.")
 			return
 		}
 
-		respondWithSuccess(s, i, logger, "Inhibition added.")
+		target := "<minialert-host>" + receiverCfg.Address()
+		scheme := "http"
+		if externalUrl, err := url.Parse(receiverCfg.ExternalUrl()); err == nil && len(externalUrl.Host) > 0 {
+			target = externalUrl.Host
+			scheme = externalUrl.Scheme
+		}
+
+		message := fmt.Sprintf("✅ Receiver enabled. This token will not be shown again.\n"+
+			"Add the following to your Prometheus config:\n"+
+			"```yaml\n"+
+			"alerting:\n"+
+			"  alertmanagers:\n"+
+			"    - scheme: %s\n"+
+			"      authorization:\n"+
+			"        credentials: %s\n"+
+			"      static_configs:\n"+
+			"        - targets: [\"%s\"]\n"+
+			"```", scheme, token, target)
+
+		respondEphemeral(s, i, logger, message)
 	}
 }
 
-func createScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.ScrapeManager) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+func disableReceiverHandler(repo db.Repo, scrapeManager scraper.ScrapeManager, receiver receiver.Receiver) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+		err := handlers.DisableReceiver(ctx, repo, scrapeManager, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to disable receiver: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to disable receiver.")
+			return
+		}
+
+		receiver.Clear(i.GuildID, configName)
 
-		ctx := context.TODO()
+		respondWithSuccess(s, i, logger, "Receiver disabled.")
+	}
+}
+
+func createScrapeConfigCommandHandler(repo db.Repo, prometheusCfg config.Prometheus, scrapeManager scraper.ScrapeManager) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		opts := getOptionMap(i.ApplicationCommandData().Options)
 
@@ -254,7 +1359,7 @@ func createScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 			return
 		}
 
-		intervalMinsOpt, ok := opts[IntervalOption]
+		intervalOpt, ok := opts[IntervalOption]
 		if !ok {
 			respondWithError(s, i, logger, "Start interval is required.")
 			return
@@ -272,20 +1377,34 @@ func createScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 			return
 		}
 
+		minInterval, err := handlers.GetMinScrapeInterval(ctx, repo, prometheusCfg.MinScrapeInterval(), i.GuildID)
+		if err != nil {
+			logger.Errorf("Failed to get minimum scrape interval: %s", err.Error())
+			respondWithError(s, i, logger, "Failed to create scrape config.")
+			return
+		}
+
+		interval, err := handlers.ParseScrapeInterval(intervalOpt.StringValue(), minInterval)
+		if err != nil {
+			respondWithError(s, i, logger, fmt.Sprintf("Invalid interval: %s.", err.Error()))
+			return
+		}
+
 		scrapeConfig := &db.ScrapeConfig{
-			Name:                  configNameOpt.StringValue(),
-			Endpoint:              endpointOpt.StringValue(),
-			ScrapeIntervalMinutes: intervalMinsOpt.IntValue(),
-			AlertChannelId:        channel.ID,
-			InhibitedAlerts:       []string{},
+			Name:           configNameOpt.StringValue(),
+			Endpoint:       endpointOpt.StringValue(),
+			SourceType:     db.PrometheusSource,
+			ScrapeInterval: interval.String(),
+			AlertChannelId: channel.ID,
 		}
 
-		// Todo: Don't source credentials from a discord interaction... Maybe provide a link to an external form? (New project idea?)
-		usernameOpt, _ := opts[UsernameOption]
-		passwordOpt, _ := opts[PasswordOption]
-		if usernameOpt != nil && passwordOpt != nil {
-			scrapeConfig.Username = usernameOpt.StringValue()
-			scrapeConfig.Password = passwordOpt.StringValue()
+		setGroupingOptions(scrapeConfig, opts)
+		setHeartbeatOptions(scrapeConfig, opts)
+
+		err = setConnectionOptions(scrapeConfig, opts)
+		if err != nil {
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to create scrape config: %s", err.Error()))
+			return
 		}
 
 		guildConfig, err := repo.GetGuildConfig(ctx, i.GuildID)
@@ -304,7 +1423,7 @@ func createScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 
 		guildConfig.ScrapeConfigs = append(guildConfig.ScrapeConfigs, *scrapeConfig)
 
-		err = repo.SetGuildConfig(context.Background(), guildConfig)
+		err = repo.SetGuildConfig(ctx, guildConfig)
 		if err != nil {
 			logger.Errorf("Failed to update guild config: %s", err.Error())
 			respondWithError(s, i, logger, "Failed to create scrape config.")
@@ -313,14 +1432,12 @@ func createScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 
 		scrapeManager.Start(guildConfig.GuildId, scrapeConfig)
 
-		respondWithSuccess(s, i, logger, "Start config created.")
+		respondWithSuccess(s, i, logger, fmt.Sprintf("Start config created. If the endpoint requires credentials or certificates, use `/%s` or `/%s` to set them.", SetCredentialsCommandName, SetTLSCommandName))
 	}
 }
 
-func updateScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.ScrapeManager) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
-
-		ctx := context.TODO()
+func updateScrapeConfigCommandHandler(repo db.Repo, prometheusCfg config.Prometheus, scrapeManager scraper.ScrapeManager) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		opts := getOptionMap(i.ApplicationCommandData().Options)
 
@@ -356,19 +1473,23 @@ func updateScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 			scrapeConfig.Endpoint = endpointOpt.StringValue()
 		}
 
-		usernameOpt, ok := opts[UsernameOption]
+		intervalOpt, ok := opts[IntervalOption]
 		if ok {
-			scrapeConfig.Username = usernameOpt.StringValue()
-		}
+			minInterval, err := handlers.GetMinScrapeInterval(ctx, repo, prometheusCfg.MinScrapeInterval(), i.GuildID)
+			if err != nil {
+				logger.Errorf("Failed to get minimum scrape interval: %s", err.Error())
+				respondWithError(s, i, logger, "Failed to update scrape config.")
+				return
+			}
 
-		passwordOpt, ok := opts[PasswordOption]
-		if ok {
-			scrapeConfig.Password = passwordOpt.StringValue()
-		}
+			interval, err := handlers.ParseScrapeInterval(intervalOpt.StringValue(), minInterval)
+			if err != nil {
+				respondWithError(s, i, logger, fmt.Sprintf("Invalid interval: %s.", err.Error()))
+				return
+			}
 
-		intervalMinsOpt, ok := opts[IntervalOption]
-		if ok {
-			scrapeConfig.ScrapeIntervalMinutes = intervalMinsOpt.IntValue()
+			scrapeConfig.ScrapeInterval = interval.String()
+			scrapeConfig.ScrapeIntervalMinutes = 0
 		}
 
 		channelOpt, ok := opts[ChannelOption]
@@ -382,7 +1503,16 @@ func updateScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 			scrapeConfig.AlertChannelId = channel.ID
 		}
 
-		err = repo.SetGuildConfig(context.Background(), guildConfig)
+		setGroupingOptions(scrapeConfig, opts)
+		setHeartbeatOptions(scrapeConfig, opts)
+
+		err = setConnectionOptions(scrapeConfig, opts)
+		if err != nil {
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to update scrape config: %s", err.Error()))
+			return
+		}
+
+		err = repo.SetGuildConfig(ctx, guildConfig)
 		if err != nil {
 			logger.Errorf("Failed to set guild config: %s", err.Error())
 			respondWithError(s, i, logger, "Failed to update scrape config.")
@@ -392,8 +1522,9 @@ func updateScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 		// Restart the scrape after updating the config
 		err = scrapeManager.Restart(guildConfig.GuildId, scrapeConfig)
 		if err != nil {
+			logger.Errorf("Failed to restart scraper: %s", err.Error())
 			respondWithError(s, i, logger, "Failed to restart scraper.")
-			logger.Fatalf("Failed to restart scraper: %s", err.Error())
+			return
 		}
 
 		respondWithSuccess(s, i, logger, "Start config updated.")
@@ -401,9 +1532,7 @@ func updateScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 }
 
 func listScrapeConfigsCommandHandler(repo db.Repo) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
-
-		ctx := context.TODO()
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		scrapeConfigs, err := handlers.GetScrapeConfigs(ctx, repo, i.GuildID)
 		if err != nil {
@@ -421,10 +1550,321 @@ func listScrapeConfigsCommandHandler(repo db.Repo) InteractionHandler {
 	}
 }
 
-func removeScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.ScrapeManager) InteractionHandler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+func scrapeStatusHandler(repo db.Repo, scrapeManager scraper.ScrapeManager, healthTracker scraper.HealthTracker) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		var configName string
+		if configNameOpt, ok := opts[ScrapeConfigNameOption]; ok {
+			configName = configNameOpt.StringValue()
+		}
+
+		statuses, err := handlers.GetScrapeStatuses(ctx, repo, scrapeManager, healthTracker, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get scrape statuses: %s", err.Error())
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to get scrape status: %s", err.Error()))
+			return
+		}
+
+		if len(statuses) == 0 {
+			respond(s, i, logger, "There are no scrape configs.")
+			return
+		}
+
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{
+					getScrapeStatusEmbed(statuses),
+				},
+			},
+		})
+
+		if err != nil {
+			logger.Errorf("Failed to respond: %s", err.Error())
+		}
+	}
+}
+
+func setMinScrapeIntervalHandler(repo db.Repo, prometheusCfg config.Prometheus, scrapeManager scraper.ScrapeManager) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		intervalOpt, ok := opts[IntervalOption]
+		if !ok {
+			respondWithError(s, i, logger, "Interval is required.")
+			return
+		}
+
+		interval, err := time.ParseDuration(intervalOpt.StringValue())
+		if err != nil {
+			respondWithError(s, i, logger, "Invalid interval, use a duration such as 30s or 2m30s.")
+			return
+		}
+
+		raised, err := handlers.SetMinScrapeInterval(ctx, repo, scrapeManager, i.GuildID, interval)
+		if err != nil {
+			logger.Errorf("Failed to set minimum scrape interval: %s", err.Error())
+			respondWithError(s, i, logger, fmt.Sprintf("Failed to set minimum scrape interval: %s", err.Error()))
+			return
+		}
+
+		var message strings.Builder
+		if interval == 0 {
+			message.WriteString(fmt.Sprintf("Minimum scrape interval removed. The global minimum of %s still applies.", prometheusCfg.MinScrapeInterval()))
+		} else {
+			message.WriteString(fmt.Sprintf("Minimum scrape interval set to %s.", interval))
+		}
+
+		if len(raised) > 0 {
+			message.WriteString(fmt.Sprintf(" The interval of %s has been raised to match.", strings.Join(raised, ", ")))
+		}
+
+		respondWithSuccess(s, i, logger, message.String())
+	}
+}
+
+// setCredentialsHandler asks for the credentials using a modal, so that they aren't visible in the command's options.
+func setCredentialsHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithEphemeralError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+
+		scrapeConfig, err := getScrapeConfig(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get scrape config: %s", err.Error())
+			respondWithEphemeralError(s, i, logger, fmt.Sprintf("Couldn't find scrape config with name \"%s\".", configName))
+			return
+		}
+
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				CustomID: NewMessageInteractionId(SetCredentialsCommandName, configName).String(),
+				Title:    truncate(fmt.Sprintf("Credentials for %s", configName), 45),
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.TextInput{
+								CustomID:    UsernameOption.String(),
+								Label:       "Username",
+								Style:       discordgo.TextInputShort,
+								Placeholder: "Leave empty to remove the credentials",
+								Value:       scrapeConfig.Username,
+								Required:    false,
+								MaxLength:   200,
+							},
+						},
+					},
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.TextInput{
+								CustomID:    PasswordOption.String(),
+								Label:       "Password",
+								Style:       discordgo.TextInputShort,
+								Placeholder: "Leave empty to remove the credentials",
+								Required:    false,
+								MaxLength:   200,
+							},
+						},
+					},
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.TextInput{
+								CustomID:    BearerTokenOption.String(),
+								Label:       "Bearer token",
+								Style:       discordgo.TextInputParagraph,
+								Placeholder: "Used instead of the username and password",
+								Required:    false,
+								MaxLength:   4000,
+							},
+						},
+					},
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.TextInput{
+								CustomID:    HeadersOption.String(),
+								Label:       "Headers",
+								Style:       discordgo.TextInputParagraph,
+								Placeholder: "E.g: X-Scope-OrgID=tenant-1. Leave a value empty to remove the header",
+								Required:    false,
+								MaxLength:   4000,
+							},
+						},
+					},
+				},
+			},
+		})
+
+		if err != nil {
+			logger.Errorf("Failed to respond: %s", err.Error())
+		}
+	}
+}
+
+func setCredentialsFromModalHandler(repo db.Repo, scrapeManager scraper.ScrapeManager) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		data := i.ModalSubmitData()
+
+		customId := MessageInteractionId(data.CustomID)
+		values, ok := customId.Values()
+		if !ok || len(values) != 1 {
+			respondWithEphemeralError(s, i, logger, fmt.Sprintf("Received unknown custom_id: %s", customId))
+			return
+		}
+
+		configName := values[0]
+		inputs := getTextInputMap(data.Components)
+
+		err := handlers.SetCredentials(ctx, repo, scrapeManager, i.GuildID, configName, inputs[UsernameOption], inputs[PasswordOption], inputs[BearerTokenOption], inputs[HeadersOption])
+		if err != nil {
+			logger.Errorf("Failed to set credentials: %s", err.Error())
+			respondWithEphemeralError(s, i, logger, fmt.Sprintf("Failed to set credentials: %s", err.Error()))
+			return
+		}
+
+		respondWithEphemeralSuccess(s, i, logger, fmt.Sprintf("Credentials for %s updated.", configName))
+	}
+}
+
+// setTLSHandler asks for the certificates using a modal, as they're too long to enter as command options.
+func setTLSHandler(repo db.Repo) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		opts := getOptionMap(i.ApplicationCommandData().Options)
+
+		configNameOpt, ok := opts[ScrapeConfigNameOption]
+		if !ok {
+			respondWithEphemeralError(s, i, logger, "Name is required.")
+			return
+		}
+
+		configName := configNameOpt.StringValue()
+
+		scrapeConfig, err := getScrapeConfig(ctx, repo, i.GuildID, configName)
+		if err != nil {
+			logger.Errorf("Failed to get scrape config: %s", err.Error())
+			respondWithEphemeralError(s, i, logger, fmt.Sprintf("Couldn't find scrape config with name \"%s\".", configName))
+			return
+		}
+
+		clientKeyPlaceholder := "Required when using a client certificate"
+		if len(scrapeConfig.TLS.ClientKey) > 0 {
+			clientKeyPlaceholder = "Leave empty to keep the current key"
+		}
+
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				CustomID: NewMessageInteractionId(SetTLSCommandName, configName).String(),
+				Title:    truncate(fmt.Sprintf("Certificates for %s", configName), 45),
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.TextInput{
+								CustomID:    CaCertOption.String(),
+								Label:       "CA certificate (PEM)",
+								Style:       discordgo.TextInputParagraph,
+								Placeholder: "Leave empty to use the system's CA certificates",
+								Value:       scrapeConfig.TLS.CaCert,
+								Required:    false,
+								MaxLength:   4000,
+							},
+						},
+					},
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.TextInput{
+								CustomID:    ClientCertOption.String(),
+								Label:       "Client certificate (PEM)",
+								Style:       discordgo.TextInputParagraph,
+								Placeholder: "Leave empty to remove the client certificate",
+								Value:       scrapeConfig.TLS.ClientCert,
+								Required:    false,
+								MaxLength:   4000,
+							},
+						},
+					},
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.TextInput{
+								CustomID:    ClientKeyOption.String(),
+								Label:       "Client key (PEM)",
+								Style:       discordgo.TextInputParagraph,
+								Placeholder: clientKeyPlaceholder,
+								Required:    false,
+								MaxLength:   4000,
+							},
+						},
+					},
+				},
+			},
+		})
+
+		if err != nil {
+			logger.Errorf("Failed to respond: %s", err.Error())
+		}
+	}
+}
+
+func setTLSFromModalHandler(repo db.Repo, scrapeManager scraper.ScrapeManager) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
+
+		data := i.ModalSubmitData()
+
+		customId := MessageInteractionId(data.CustomID)
+		values, ok := customId.Values()
+		if !ok || len(values) != 1 {
+			respondWithEphemeralError(s, i, logger, fmt.Sprintf("Received unknown custom_id: %s", customId))
+			return
+		}
+
+		configName := values[0]
+		inputs := getTextInputMap(data.Components)
+
+		err := handlers.SetTLS(ctx, repo, scrapeManager, i.GuildID, configName, inputs[CaCertOption], inputs[ClientCertOption], inputs[ClientKeyOption])
+		if err != nil {
+			logger.Errorf("Failed to set certificates: %s", err.Error())
+			respondWithEphemeralError(s, i, logger, fmt.Sprintf("Failed to set certificates: %s", err.Error()))
+			return
+		}
+
+		respondWithEphemeralSuccess(s, i, logger, fmt.Sprintf("Certificates for %s updated.", configName))
+	}
+}
+
+// getTextInputMap returns the value of each text input in a submitted modal.
+func getTextInputMap(components []discordgo.MessageComponent) map[InteractionOption]string {
+	inputs := make(map[InteractionOption]string)
+	for _, component := range components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+
+		for _, rowComponent := range row.Components {
+			if input, ok := rowComponent.(*discordgo.TextInput); ok {
+				inputs[InteractionOption(input.CustomID)] = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+
+	return inputs
+}
 
-		ctx := context.TODO()
+func removeScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.ScrapeManager, healthTracker scraper.HealthTracker, receiver receiver.Receiver, tracker alerts.Tracker, grouper alerts.Grouper, escalator alerts.Escalator, heartbeatMonitor alerts.HeartbeatMonitor) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger) {
 
 		opts := getOptionMap(i.ApplicationCommandData().Options)
 
@@ -443,6 +1883,13 @@ func removeScrapeConfigCommandHandler(repo db.Repo, scrapeManager scraper.Scrape
 			return
 		}
 
+		healthTracker.Clear(i.GuildID, configName)
+		receiver.Clear(i.GuildID, configName)
+		tracker.Clear(i.GuildID, configName)
+		grouper.Clear(i.GuildID, configName)
+		escalator.Clear(i.GuildID, configName)
+		heartbeatMonitor.Clear(i.GuildID, configName)
+
 		respondWithSuccess(s, i, logger, "Scrape config removed.")
 	}
 }
@@ -1,16 +1,54 @@
 package bot
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/yukitsune/minialert/db"
+)
 
 type InteractionName string
 
 const (
-	GetAlertsCommandName InteractionName = "get-alerts"
+	GetAlertsCommandName    InteractionName = "get-alerts"
+	AlertHistoryCommandName InteractionName = "alert-history"
 
 	ShowInhibitedAlertsCommandName InteractionName = "show-inhibited-alerts"
 	InhibitAlertCommandName        InteractionName = "inhibit-alert"
 	UninhibitAlertCommandName      InteractionName = "uninhibit-alert"
 
+	SilenceCommandName       InteractionName = "silence"
+	ListSilencesCommandName  InteractionName = "silences"
+	ExpireSilenceCommandName InteractionName = "expire-silence"
+
+	AddInhibitionRuleCommandName    InteractionName = "add-inhibition-rule"
+	ListInhibitionRulesCommandName  InteractionName = "inhibition-rules"
+	RemoveInhibitionRuleCommandName InteractionName = "remove-inhibition-rule"
+
+	AddRouteCommandName    InteractionName = "add-route"
+	ListRoutesCommandName  InteractionName = "routes"
+	RemoveRouteCommandName InteractionName = "remove-route"
+
+	EnableReceiverCommandName  InteractionName = "enable-receiver"
+	DisableReceiverCommandName InteractionName = "disable-receiver"
+
+	AddNotifierCommandName    InteractionName = "add-notifier"
+	ListNotifiersCommandName  InteractionName = "notifiers"
+	RemoveNotifierCommandName InteractionName = "remove-notifier"
+
+	AcknowledgeCommandName InteractionName = "acknowledge"
+
+	AddEscalationStepCommandName    InteractionName = "add-escalation-step"
+	ListEscalationStepsCommandName  InteractionName = "escalation-steps"
+	RemoveEscalationStepCommandName InteractionName = "remove-escalation-step"
+
+	GrantPermissionCommandName  InteractionName = "grant-permission"
+	RevokePermissionCommandName InteractionName = "revoke-permission"
+	ListPermissionsCommandName  InteractionName = "permissions"
+
+	ScrapeStatusCommandName         InteractionName = "scrape-status"
+	SetMinScrapeIntervalCommandName InteractionName = "set-min-scrape-interval"
+	SetCredentialsCommandName       InteractionName = "set-credentials"
+	SetTLSCommandName               InteractionName = "set-tls"
+
 	CreateScrapeConfigCommandName InteractionName = "create-scrape-config"
 	ListScrapeConfigsCommandName  InteractionName = "list-scrape-configs"
 	UpdateScrapeConfigCommandName InteractionName = "update-scrape-config"
@@ -24,19 +62,101 @@ func (c InteractionName) String() string {
 type InteractionOption string
 
 const (
-	ChannelOption          InteractionOption = "channel"
-	AlertNameOption        InteractionOption = "alertname"
-	ScrapeConfigNameOption InteractionOption = "scrape-config-name"
-	EndpointOption         InteractionOption = "endpoint"
-	UsernameOption         InteractionOption = "username"
-	PasswordOption         InteractionOption = "password"
-	IntervalOption         InteractionOption = "interval"
+	ChannelOption            InteractionOption = "channel"
+	AlertNameOption          InteractionOption = "alertname"
+	ScrapeConfigNameOption   InteractionOption = "scrape-config-name"
+	EndpointOption           InteractionOption = "endpoint"
+	UsernameOption           InteractionOption = "username"
+	PasswordOption           InteractionOption = "password"
+	IntervalOption           InteractionOption = "interval"
+	GroupByOption            InteractionOption = "group-by"
+	GroupWaitOption          InteractionOption = "group-wait"
+	GroupIntervalOption      InteractionOption = "group-interval"
+	RepeatIntervalOption     InteractionOption = "repeat-interval"
+	MatchersOption           InteractionOption = "matchers"
+	DurationOption           InteractionOption = "duration"
+	CommentOption            InteractionOption = "comment"
+	SilenceIdOption          InteractionOption = "silence-id"
+	SourceMatchersOption     InteractionOption = "source-matchers"
+	TargetMatchersOption     InteractionOption = "target-matchers"
+	EqualOption              InteractionOption = "equal"
+	RuleIdOption             InteractionOption = "rule-id"
+	ContinueOption           InteractionOption = "continue"
+	ParentRouteIdOption      InteractionOption = "parent-route-id"
+	RouteIdOption            InteractionOption = "route-id"
+	NotifierTypeOption       InteractionOption = "type"
+	UrlOption                InteractionOption = "url"
+	ToOption                 InteractionOption = "to"
+	NotifierIdOption         InteractionOption = "notifier-id"
+	DelayOption              InteractionOption = "delay"
+	RoleOption               InteractionOption = "role"
+	UserOption               InteractionOption = "user"
+	EscalationStepIdOption   InteractionOption = "escalation-step-id"
+	SinceOption              InteractionOption = "since"
+	UntilOption              InteractionOption = "until"
+	PageOption               InteractionOption = "page"
+	PermissionLevelOption    InteractionOption = "level"
+	BearerTokenOption        InteractionOption = "bearer-token"
+	HeadersOption            InteractionOption = "headers"
+	InsecureSkipVerifyOption InteractionOption = "insecure-skip-verify"
+	CaCertOption             InteractionOption = "ca-cert"
+	ClientCertOption         InteractionOption = "client-cert"
+	ClientKeyOption          InteractionOption = "client-key"
+	SourceTypeOption         InteractionOption = "source"
+	TenantOption             InteractionOption = "tenant"
+	HeartbeatAlertOption     InteractionOption = "heartbeat-alert"
+	HeartbeatTimeoutOption   InteractionOption = "heartbeat-timeout"
+	ScrapeTimeoutOption      InteractionOption = "timeout"
 )
 
 func (c InteractionOption) String() string {
 	return string(c)
 }
 
+// getCommandPermissions returns the permission level required to use each command.
+// Commands which aren't listed can only be used by admins.
+func getCommandPermissions() CommandPermissions {
+	return map[InteractionName]db.PermissionLevel{
+		GetAlertsCommandName:           db.ReadPermission,
+		AlertHistoryCommandName:        db.ReadPermission,
+		ShowInhibitedAlertsCommandName: db.ReadPermission,
+		ListSilencesCommandName:        db.ReadPermission,
+		ListInhibitionRulesCommandName: db.ReadPermission,
+		ListRoutesCommandName:          db.ReadPermission,
+		ListNotifiersCommandName:       db.ReadPermission,
+		ListEscalationStepsCommandName: db.ReadPermission,
+		ListScrapeConfigsCommandName:   db.ReadPermission,
+		ScrapeStatusCommandName:        db.ReadPermission,
+		ListPermissionsCommandName:     db.ReadPermission,
+
+		InhibitAlertCommandName:   db.SilencePermission,
+		UninhibitAlertCommandName: db.SilencePermission,
+		SilenceCommandName:        db.SilencePermission,
+		ExpireSilenceCommandName:  db.SilencePermission,
+		AcknowledgeCommandName:    db.SilencePermission,
+
+		AddInhibitionRuleCommandName:    db.ManagePermission,
+		RemoveInhibitionRuleCommandName: db.ManagePermission,
+		AddRouteCommandName:             db.ManagePermission,
+		RemoveRouteCommandName:          db.ManagePermission,
+		EnableReceiverCommandName:       db.ManagePermission,
+		DisableReceiverCommandName:      db.ManagePermission,
+		AddNotifierCommandName:          db.ManagePermission,
+		RemoveNotifierCommandName:       db.ManagePermission,
+		AddEscalationStepCommandName:    db.ManagePermission,
+		RemoveEscalationStepCommandName: db.ManagePermission,
+		CreateScrapeConfigCommandName:   db.ManagePermission,
+		UpdateScrapeConfigCommandName:   db.ManagePermission,
+		RemoveScrapeConfigCommandName:   db.ManagePermission,
+		SetCredentialsCommandName:       db.ManagePermission,
+		SetTLSCommandName:               db.ManagePermission,
+
+		GrantPermissionCommandName:      db.AdminPermission,
+		RevokePermissionCommandName:     db.AdminPermission,
+		SetMinScrapeIntervalCommandName: db.AdminPermission,
+	}
+}
+
 func configCommand(create bool) *discordgo.ApplicationCommand {
 
 	var name = UpdateScrapeConfigCommandName.String()
@@ -54,21 +174,22 @@ func configCommand(create bool) *discordgo.ApplicationCommand {
 		Description: description,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Name:        ScrapeConfigNameOption.String(),
-				Description: "The name of the scrape config",
-				Type:        discordgo.ApplicationCommandOptionString,
-				Required:    true,
+				Name:         ScrapeConfigNameOption.String(),
+				Description:  "The name of the scrape config",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: !create,
 			},
 			{
 				Name:        EndpointOption.String(),
-				Description: "The endpoint to scrape",
+				Description: "The URL to scrape. The default path for the source is used if it doesn't have one",
 				Type:        discordgo.ApplicationCommandOptionString,
 				Required:    create,
 			},
 			{
 				Name:        IntervalOption.String(),
-				Description: "The interval (in minutes) at which to scrape the endpoint",
-				Type:        discordgo.ApplicationCommandOptionInteger,
+				Description: "How often to scrape the endpoint, E.g: 30s or 2m30s",
+				Type:        discordgo.ApplicationCommandOptionString,
 				Required:    create,
 			},
 			{
@@ -78,17 +199,71 @@ func configCommand(create bool) *discordgo.ApplicationCommand {
 				Required:    create,
 			},
 			{
-				Name:        UsernameOption.String(),
-				Description: "The username required to access the endpoint",
+				Name:        ScrapeTimeoutOption.String(),
+				Description: "How long to wait for the endpoint to respond, E.g: 15s. Use 0s for the default",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    false,
+			},
+			{
+				Name:        GroupByOption.String(),
+				Description: "Comma-separated list of labels to group alerts by (defaults to alertname)",
 				Type:        discordgo.ApplicationCommandOptionString,
 				Required:    false,
 			},
 			{
-				Name:        PasswordOption.String(),
-				Description: "The password required to access the endpoint",
+				Name:        GroupWaitOption.String(),
+				Description: "How long (in seconds) to wait before sending the first notification for a group",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Required:    false,
+			},
+			{
+				Name:        GroupIntervalOption.String(),
+				Description: "How long (in seconds) to wait before notifying about changes to a group",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Required:    false,
+			},
+			{
+				Name:        RepeatIntervalOption.String(),
+				Description: "How long (in seconds) to wait before re-sending a notification for a group",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Required:    false,
+			},
+			{
+				Name:        HeartbeatAlertOption.String(),
+				Description: "An alert which should always be firing, E.g: Watchdog. Use \"none\" to disable",
 				Type:        discordgo.ApplicationCommandOptionString,
 				Required:    false,
 			},
+			{
+				Name:        HeartbeatTimeoutOption.String(),
+				Description: "How long (in seconds) the heartbeat alert can be missing for (defaults to 600)",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Required:    false,
+			},
+			{
+				Name:        SourceTypeOption.String(),
+				Description: "The type of API the endpoint serves (defaults to prometheus)",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Prometheus alerts", Value: db.PrometheusSource.String()},
+					{Name: "Prometheus rules", Value: db.PrometheusRulesSource.String()},
+					{Name: "Alertmanager", Value: db.AlertmanagerSource.String()},
+					{Name: "Mimir or Cortex ruler", Value: db.MimirSource.String()},
+				},
+			},
+			{
+				Name:        TenantOption.String(),
+				Description: "The tenant ID sent in the X-Scope-OrgID header, for Mimir and Cortex",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    false,
+			},
+			{
+				Name:        InsecureSkipVerifyOption.String(),
+				Description: "Whether to skip verifying the endpoint's TLS certificate",
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Required:    false,
+			},
 		},
 	}
 }
@@ -100,10 +275,55 @@ func getCommands() []*discordgo.ApplicationCommand {
 			Description: "List all currently firing alerts",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Name:        ScrapeConfigNameOption.String(),
-					Description: "The name of the scrape config",
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        AlertHistoryCommandName.String(),
+			Description: "List alerts which have started firing or resolved",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     false,
+					Autocomplete: true,
+				},
+				{
+					Name:         AlertNameOption.String(),
+					Description:  "Alertname",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     false,
+					Autocomplete: true,
+				},
+				{
+					Name:        MatchersOption.String(),
+					Description: "Comma-separated label matchers, only = is supported, E.g: severity=\"critical\"",
 					Type:        discordgo.ApplicationCommandOptionString,
-					Required:    true,
+					Required:    false,
+				},
+				{
+					Name:        SinceOption.String(),
+					Description: "How far back to look, E.g: 24h. Defaults to 24h",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+				{
+					Name:        UntilOption.String(),
+					Description: "How long ago the time range ends, E.g: 1h. Defaults to now",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+				{
+					Name:        PageOption.String(),
+					Description: "The page of results to show. Defaults to 1",
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Required:    false,
 				},
 			},
 		},
@@ -112,10 +332,11 @@ func getCommands() []*discordgo.ApplicationCommand {
 			Description: "List all inhibited alerts",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Name:        ScrapeConfigNameOption.String(),
-					Description: "The name of the scrape config",
-					Type:        discordgo.ApplicationCommandOptionString,
-					Required:    true,
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -124,52 +345,494 @@ func getCommands() []*discordgo.ApplicationCommand {
 			Description: "Inhibit an alert with the given name",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Name:        ScrapeConfigNameOption.String(),
-					Description: "The name of the scrape config",
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:         AlertNameOption.String(),
+					Description:  "Alertname",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        UninhibitAlertCommandName.String(),
+			Description: "Un-inhibit an alert with the given name",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:         AlertNameOption.String(),
+					Description:  "Alertname",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        SilenceCommandName.String(),
+			Description: "Silence all alerts matching the given label matchers",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        MatchersOption.String(),
+					Description: "Comma-separated label matchers, E.g: alertname=\"Foo\", severity=~\"warning|critical\"",
 					Type:        discordgo.ApplicationCommandOptionString,
 					Required:    true,
 				},
 				{
-					Name:        AlertNameOption.String(),
-					Description: "Alertname",
+					Name:        DurationOption.String(),
+					Description: "How long the silence should last, E.g: 2h30m",
 					Type:        discordgo.ApplicationCommandOptionString,
 					Required:    true,
 				},
+				{
+					Name:        CommentOption.String(),
+					Description: "Why the alerts are being silenced",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
 			},
 		},
 		{
-			Name:        UninhibitAlertCommandName.String(),
-			Description: "Un-inhibit an alert with the given name",
+			Name:        ListSilencesCommandName.String(),
+			Description: "List all active silences",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        ExpireSilenceCommandName.String(),
+			Description: "Expire a silence",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         SilenceIdOption.String(),
+					Description:  "The ID of the silence",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        AddInhibitionRuleCommandName.String(),
+			Description: "Mute alerts matching the target matchers while an alert matching the source matchers is firing",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        SourceMatchersOption.String(),
+					Description: "Label matchers for the alerts which cause the inhibition, E.g: alertname=\"NodeDown\"",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        TargetMatchersOption.String(),
+					Description: "Label matchers for the alerts to inhibit, E.g: severity=\"warning\"",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        EqualOption.String(),
+					Description: "Comma-separated list of labels which must be equal on both alerts, E.g: instance",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        ListInhibitionRulesCommandName.String(),
+			Description: "List all inhibition rules",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        RemoveInhibitionRuleCommandName.String(),
+			Description: "Remove an inhibition rule",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        RuleIdOption.String(),
+					Description: "The ID of the inhibition rule",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        AddRouteCommandName.String(),
+			Description: "Send alerts matching the given matchers to a different channel",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Name:        ScrapeConfigNameOption.String(),
-					Description: "The name of the scrape config",
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        MatchersOption.String(),
+					Description: "Label matchers for the alerts to route, E.g: team=\"database\"",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        ChannelOption.String(),
+					Description: "The channel to send matching alerts to, defaults to the parent route's channel",
+					Type:        discordgo.ApplicationCommandOptionChannel,
+					Required:    false,
+				},
+				{
+					Name:        ContinueOption.String(),
+					Description: "Whether to keep evaluating sibling routes after this route matches",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Required:    false,
+				},
+				{
+					Name:        ParentRouteIdOption.String(),
+					Description: "The ID of the route to nest this route under",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        ListRoutesCommandName.String(),
+			Description: "List all routes",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        RemoveRouteCommandName.String(),
+			Description: "Remove a route and all of its child routes",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        RouteIdOption.String(),
+					Description: "The ID of the route",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        EnableReceiverCommandName.String(),
+			Description: "Generate a token which Prometheus can use to push alerts, replacing any existing token",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        DisableReceiverCommandName.String(),
+			Description: "Stop accepting pushed alerts and resume scraping",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        AddNotifierCommandName.String(),
+			Description: "Send notifications somewhere other than Discord",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        NotifierTypeOption.String(),
+					Description: "The type of notifier",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Webhook", Value: db.WebhookNotifier.String()},
+						{Name: "Slack", Value: db.SlackNotifier.String()},
+						{Name: "Email", Value: db.EmailNotifier.String()},
+						{Name: "File", Value: db.FileNotifier.String()},
+					},
+				},
+				{
+					Name:        UrlOption.String(),
+					Description: "The URL to send notifications to, required for webhook and slack notifiers",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+				{
+					Name:        ToOption.String(),
+					Description: "Comma-separated list of email addresses, required for email notifiers",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        ListNotifiersCommandName.String(),
+			Description: "List all notifiers",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        RemoveNotifierCommandName.String(),
+			Description: "Remove a notifier",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        NotifierIdOption.String(),
+					Description: "The ID of the notifier",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        AddEscalationStepCommandName.String(),
+			Description: "Mention a role or user when critical alerts haven't been acknowledged",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        DelayOption.String(),
+					Description: "The number of minutes after the first notification to escalate",
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Required:    true,
+				},
+				{
+					Name:        RoleOption.String(),
+					Description: "The role to mention",
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Required:    false,
+				},
+				{
+					Name:        UserOption.String(),
+					Description: "The user to mention",
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        ListEscalationStepsCommandName.String(),
+			Description: "List all escalation steps",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        RemoveEscalationStepCommandName.String(),
+			Description: "Remove an escalation step",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        EscalationStepIdOption.String(),
+					Description: "The ID of the escalation step",
 					Type:        discordgo.ApplicationCommandOptionString,
 					Required:    true,
 				},
+			},
+		},
+		{
+			Name:        GrantPermissionCommandName.String(),
+			Description: "Grant a role permission to use minialert's commands",
+			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Name:        AlertNameOption.String(),
-					Description: "Alertname",
+					Name:        RoleOption.String(),
+					Description: "The role to grant permission to",
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Required:    true,
+				},
+				{
+					Name:        PermissionLevelOption.String(),
+					Description: "The commands the role can use, each level includes the ones before it",
 					Type:        discordgo.ApplicationCommandOptionString,
 					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Read", Value: db.ReadPermission.String()},
+						{Name: "Silence", Value: db.SilencePermission.String()},
+						{Name: "Manage", Value: db.ManagePermission.String()},
+					},
 				},
 			},
 		},
+		{
+			Name:        RevokePermissionCommandName.String(),
+			Description: "Revoke a role's permission to use minialert's commands",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        RoleOption.String(),
+					Description: "The role to revoke permission from",
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        ListPermissionsCommandName.String(),
+			Description: "List the permissions granted to each role",
+		},
 		{
 			Name:        ListScrapeConfigsCommandName.String(),
 			Description: "Lists all scrape configs",
 		},
+		{
+			Name:        ScrapeStatusCommandName.String(),
+			Description: "Show whether each scrape config's endpoint is being scraped successfully",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     false,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        SetMinScrapeIntervalCommandName.String(),
+			Description: "Set the shortest scrape interval allowed in this server",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        IntervalOption.String(),
+					Description: "The minimum interval, E.g: 30s or 2m30s. Use 0 to remove the minimum",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
 		configCommand(false),
 		configCommand(true),
+		{
+			Name:        SetCredentialsCommandName.String(),
+			Description: "Set the credentials and headers required to access a scrape config's endpoint",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        SetTLSCommandName.String(),
+			Description: "Set the CA certificate and client certificate used to connect to a scrape config's endpoint",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
 		{
 			Name:        RemoveScrapeConfigCommandName.String(),
 			Description: "Removes a scrape config",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Name:        ScrapeConfigNameOption.String(),
-					Description: "The name of the scrape config",
-					Type:        discordgo.ApplicationCommandOptionString,
-					Required:    true,
+					Name:         ScrapeConfigNameOption.String(),
+					Description:  "The name of the scrape config",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -10,31 +10,39 @@ import (
 
 func onReadyHandler(cfg config.Bot, logger logrus.FieldLogger) func(s *discordgo.Session, r *discordgo.Ready) {
 	return func(s *discordgo.Session, r *discordgo.Ready) {
-		logger.Infof("✅  Logged in as: %s#%s", s.State.User.Username, s.State.User.Discriminator)
+		ctxLogger := logger
+		if s.ShardCount > 1 {
+			ctxLogger = ctxLogger.WithField("shard_id", s.ShardID)
+		}
+
+		ctxLogger.Infof("✅  Logged in as: %s#%s", s.State.User.Username, s.State.User.Discriminator)
 
 		inviteLink := getInviteLink(cfg)
-		logger.Infof("🔗 Invite link: %s", inviteLink)
+		ctxLogger.Infof("🔗 Invite link: %s", inviteLink)
 	}
 }
 
-func onInteractionCreateHandler(interactionHandlers InteractionHandlers, messageInteractionHandlers MessageInteractionHandlers, logger logrus.FieldLogger) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func onInteractionCreateHandler(ctx context.Context, repo db.Repo, commandPermissions CommandPermissions, interactionHandlers InteractionHandlers, messageInteractionHandlers MessageInteractionHandlers, modalSubmitHandlers ModalSubmitHandlers, autocompleteHandlers AutocompleteHandlers, logger logrus.FieldLogger) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 		entry := logger.WithField("guild_id", i.GuildID).
 			WithField("interaction_id", i.Interaction.ID).
 			WithField("interaction_type", i.Interaction.Type.String())
 
-		if i.Type == discordgo.InteractionApplicationCommand {
+		if i.Type == discordgo.InteractionApplicationCommand || i.Type == discordgo.InteractionApplicationCommandAutocomplete {
 			entry = entry.WithField("interaction_name", i.ApplicationCommandData().Name)
 		} else if i.Type == discordgo.InteractionMessageComponent {
 			entry = entry.WithField("interaction_custom_id", i.Interaction.MessageComponentData().CustomID)
+		} else if i.Type == discordgo.InteractionModalSubmit {
+			entry = entry.WithField("interaction_custom_id", i.Interaction.ModalSubmitData().CustomID)
 		}
 
 		entry.Debugln("interaction created")
 
 		if i.Type == discordgo.InteractionApplicationCommand {
-			if h, ok := interactionHandlers[InteractionName(i.ApplicationCommandData().Name)]; ok {
-				h(s, i, entry)
+			commandName := InteractionName(i.ApplicationCommandData().Name)
+			if h, ok := interactionHandlers[commandName]; ok && authorize(ctx, s, i, repo, commandPermissions, commandName, entry) {
+				h(ctx, s, i, entry)
 			}
 		} else if i.Type == discordgo.InteractionMessageComponent {
 			customId := MessageInteractionId(i.Interaction.MessageComponentData().CustomID)
@@ -43,8 +51,26 @@ func onInteractionCreateHandler(interactionHandlers InteractionHandlers, message
 				entry.Errorf("unable to determine command name or value from custom_id: %s", customId)
 			}
 
-			if h, ok := messageInteractionHandlers[commandName]; ok {
-				h(s, i, entry)
+			if h, ok := messageInteractionHandlers[commandName]; ok && authorize(ctx, s, i, repo, commandPermissions, commandName, entry) {
+				h(ctx, s, i, entry)
+			}
+		} else if i.Type == discordgo.InteractionModalSubmit {
+			customId := MessageInteractionId(i.Interaction.ModalSubmitData().CustomID)
+			commandName, ok := customId.Name()
+			if !ok {
+				entry.Errorf("unable to determine command name from custom_id: %s", customId)
+			}
+
+			if h, ok := modalSubmitHandlers[commandName]; ok && authorize(ctx, s, i, repo, commandPermissions, commandName, entry) {
+				h(ctx, s, i, entry)
+			}
+		} else if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			commandName := InteractionName(i.ApplicationCommandData().Name)
+			if authorize(ctx, s, i, repo, commandPermissions, commandName, entry) {
+				handleAutocomplete(ctx, s, i, autocompleteHandlers, entry)
+			} else {
+				// Nothing is suggested to members who can't use the command
+				handleAutocomplete(ctx, s, i, AutocompleteHandlers{}, entry)
 			}
 		} else {
 			entry.Warnf("unexpected interaction type: %s", i.Type.String())
@@ -52,11 +78,9 @@ func onInteractionCreateHandler(interactionHandlers InteractionHandlers, message
 	}
 }
 
-func onGuildCreated(commands []*discordgo.ApplicationCommand, repo db.Repo, logger logrus.FieldLogger) func(s *discordgo.Session, i *discordgo.GuildCreate) {
+func onGuildCreated(ctx context.Context, commands []*discordgo.ApplicationCommand, repo db.Repo, logger logrus.FieldLogger) func(s *discordgo.Session, i *discordgo.GuildCreate) {
 	return func(s *discordgo.Session, i *discordgo.GuildCreate) {
 
-		ctx := context.TODO()
-
 		ctxLogger := logger.WithField("guild_id", i.Guild.ID)
 		ctxLogger.Infoln("Guild created")
 
@@ -96,14 +120,12 @@ func onGuildCreated(commands []*discordgo.ApplicationCommand, repo db.Repo, logg
 	}
 }
 
-func onGuildDeleted(repo db.Repo, logger logrus.FieldLogger) func(s *discordgo.Session, i *discordgo.GuildDelete) {
+func onGuildDeleted(ctx context.Context, repo db.Repo, logger logrus.FieldLogger) func(s *discordgo.Session, i *discordgo.GuildDelete) {
 	return func(s *discordgo.Session, i *discordgo.GuildDelete) {
 
 		ctxLogger := logger.WithField("guild_id", i.Guild.ID)
 		ctxLogger.Infoln("Guild deleted")
 
-		ctx := context.TODO()
-
 		commands, err := repo.GetRegisteredCommands(ctx, i.Guild.ID)
 		if err != nil {
 			ctxLogger.Errorf("Failed to get commands: %s", err.Error())
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/scraper"
+	"strings"
+	"time"
+)
+
+// updateScrapeHealth records the outcome of the scrape, and returns the results which should be handled for it.
+// Returns false if the scrape failed but the endpoint isn't considered down yet, in which case the scrape should be ignored so that firing alerts aren't reported as resolved.
+// Once the endpoint is down, the alerts from the last successful scrape are kept firing alongside the ScrapeTargetDown alert.
+func updateScrapeHealth(healthTracker scraper.HealthTracker, tracker alerts.Tracker, results scraper.ScrapeResult, logger logrus.FieldLogger) (scraper.ScrapeResult, bool) {
+	ctxLogger := logger.
+		WithField("guild_id", results.GuildId).
+		WithField("scrape_config_name", results.ScrapeConfigName)
+
+	now := time.Now()
+
+	if results.Err == nil {
+		previous, recovered := healthTracker.RecordSuccess(results.GuildId, results.ScrapeConfigName, now)
+		if recovered {
+			ctxLogger.Infof("Scrape target recovered after %d failures", previous.ConsecutiveFailures)
+		}
+
+		return results, true
+	}
+
+	health, wentDown := healthTracker.RecordFailure(results.GuildId, results.ScrapeConfigName, results.Err, now)
+	if wentDown {
+		ctxLogger.Warnf("Scrape target down after %d failures", health.ConsecutiveFailures)
+	}
+
+	if !health.Down {
+		return results, false
+	}
+
+	results.Alerts = getActiveAlerts(tracker, results.GuildId, results.ScrapeConfigName)
+	return results, true
+}
+
+func getScrapeStatusEmbed(statuses []handlers.ScrapeStatus) *discordgo.MessageEmbed {
+	var fields []*discordgo.MessageEmbedField
+	for i, status := range statuses {
+		if i == maxEmbedFields-1 && len(statuses) > maxEmbedFields {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:  "...",
+				Value: fmt.Sprintf("and %d more", len(statuses)-i),
+			})
+			break
+		}
+
+		var value strings.Builder
+		switch {
+		case status.ReceiverEnabled:
+			value.WriteString("📥 Alerts are pushed to the receiver")
+
+		case !status.Scraped:
+			value.WriteString("⏳ Not scraped yet")
+
+		case status.Health.Down:
+			value.WriteString(fmt.Sprintf("❌ Down, %d consecutive failures", status.Health.ConsecutiveFailures))
+
+		case status.Health.ConsecutiveFailures > 0:
+			value.WriteString(fmt.Sprintf("⚠️ %d consecutive failures", status.Health.ConsecutiveFailures))
+
+		default:
+			value.WriteString("✅ Healthy")
+		}
+
+		if status.Scraped && !status.ReceiverEnabled {
+			value.WriteString(fmt.Sprintf("\nLast successful scrape: %s", formatLastSuccess(status.Health)))
+
+			if len(status.Health.LastError) > 0 {
+				value.WriteString(fmt.Sprintf("\nLast error <t:%d:R>: `%s`", status.Health.LastErrorAt.Unix(), status.Health.LastError))
+			}
+		}
+
+		if status.Scheduled && !status.ReceiverEnabled {
+			value.WriteString(fmt.Sprintf("\nNext scrape: <t:%d:R>", status.Schedule.NextScrape.Unix()))
+
+			if status.Schedule.BackingOff() {
+				value.WriteString(fmt.Sprintf("\n🐢 Backing off, scraping every %s instead of every %s", status.Schedule.Delay, status.Schedule.Interval))
+			}
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   status.ScrapeConfigName,
+			Value:  truncate(value.String(), 1024),
+			Inline: false,
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:   discordgo.EmbedTypeRich,
+		Title:  "Scrape status",
+		Fields: fields,
+	}
+}
+
+func formatLastSuccess(health scraper.Health) string {
+	if health.LastSuccess.IsZero() {
+		return "Never"
+	}
+
+	return fmt.Sprintf("<t:%d:R>", health.LastSuccess.Unix())
+}
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/scraper"
+	"time"
+)
+
+// raiseMissingHeartbeats raises a HeartbeatMissing alert for each scrape config whose heartbeat alert has gone missing.
+// The alerting pipeline is likely broken, so there may not be any new results to raise the alert with. Instead, the scrape config's active alerts are handled again.
+// The HeartbeatMissing alert resolves once the heartbeat alert is received again.
+func raiseMissingHeartbeats(ctx context.Context, repo db.Repo, healthTracker scraper.HealthTracker, tracker alerts.Tracker, grouper alerts.Grouper, heartbeatMonitor alerts.HeartbeatMonitor, events []alerts.HeartbeatEvent, logger logrus.FieldLogger) {
+	for _, event := range events {
+		ctxLogger := logger.
+			WithField("guild_id", event.GuildId).
+			WithField("scrape_config_name", event.ScrapeConfigName)
+
+		if event.Status != alerts.HeartbeatMissing {
+			ctxLogger.Infof("Heartbeat alert %s restored", event.AlertName)
+			continue
+		}
+
+		ctxLogger.Warnf("Heartbeat alert %s missing since %s", event.AlertName, event.LastSeen.Format(time.RFC3339))
+
+		results := scraper.ScrapeResult{
+			GuildId:          event.GuildId,
+			ScrapeConfigName: event.ScrapeConfigName,
+			Alerts:           getActiveAlerts(tracker, event.GuildId, event.ScrapeConfigName),
+		}
+
+		handleScrapeResult(ctx, repo, healthTracker, tracker, grouper, heartbeatMonitor, results, logger)
+	}
+}
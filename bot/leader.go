@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/scraper"
+	"github.com/yukitsune/minialert/shard"
+	"sync"
+	"time"
+)
+
+// scraperReconcileInterval is how often the leader checks that its scrapers match the stored scrape configs.
+// This picks up changes made using other replicas.
+const scraperReconcileInterval = 30 * time.Second
+
+// leaderScrapeManager only runs scrapers while this replica is the leader, and only for the scrape configs in its shard,
+// so that commands used on other replicas don't cause duplicate scrapes.
+type leaderScrapeManager struct {
+	scraper.ScrapeManager
+	membership shard.Membership
+
+	mu      sync.RWMutex
+	leading bool
+}
+
+func newLeaderScrapeManager(scrapeManager scraper.ScrapeManager, membership shard.Membership) *leaderScrapeManager {
+	return &leaderScrapeManager{
+		ScrapeManager: scrapeManager,
+		membership:    membership,
+	}
+}
+
+func (m *leaderScrapeManager) Start(guildId string, config *db.ScrapeConfig) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.leading && m.membership.Owns(guildId, config.Name) {
+		m.ScrapeManager.Start(guildId, config)
+	}
+}
+
+func (m *leaderScrapeManager) Restart(guildId string, config *db.ScrapeConfig) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.leading {
+		return nil
+	}
+
+	// The scrape config may have moved to another replica since it was started, in which case the next rebalance stops it
+	if !m.membership.Owns(guildId, config.Name) {
+		return nil
+	}
+
+	return m.ScrapeManager.Restart(guildId, config)
+}
+
+func (m *leaderScrapeManager) Stop(guildId string, configName string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Scrape configs in other replicas' shards are stopped when those replicas reconcile their scrapers
+	if !m.leading || !m.membership.Owns(guildId, configName) {
+		return nil
+	}
+
+	return m.ScrapeManager.Stop(guildId, configName)
+}
+
+func (m *leaderScrapeManager) Reconcile(scrapeConfigs map[string][]db.ScrapeConfig) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.leading {
+		m.ScrapeManager.Reconcile(m.owned(scrapeConfigs))
+	}
+}
+
+// owned returns the scrape configs in this replica's shard.
+func (m *leaderScrapeManager) owned(scrapeConfigs map[string][]db.ScrapeConfig) map[string][]db.ScrapeConfig {
+	owned := make(map[string][]db.ScrapeConfig)
+	for guildId, configs := range scrapeConfigs {
+		for _, config := range configs {
+			if m.membership.Owns(guildId, config.Name) {
+				owned[guildId] = append(owned[guildId], config)
+			}
+		}
+	}
+
+	return owned
+}
+
+// setLeading stops every scraper when leadership is lost.
+func (m *leaderScrapeManager) setLeading(leading bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.leading = leading
+	if !leading {
+		m.ScrapeManager.Reconcile(nil)
+	}
+}
+
+// lead runs the scrapers and sends notifications until leadership is lost.
+func (b *Bot) lead(ctx context.Context, sessions *sessions) {
+	b.scrapeManager.setLeading(true)
+	defer b.scrapeManager.setLeading(false)
+
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	run(func() {
+		watchAlerts(ctx, sessions, b.deps, b.logger)
+	})
+	run(func() { expireReceivedAlerts(ctx, b.deps.Receiver, b.logger) })
+	run(func() { cleanupSilences(ctx, b.deps.Repo, b.logger) })
+	run(func() {
+		reconcileScrapers(ctx, b.deps.Repo, b.scrapeManager, b.deps.Membership, b.rebalance, b.forget, b.logger)
+	})
+
+	wg.Wait()
+}
+
+// scrapeConfigKey identifies a scrape config.
+type scrapeConfigKey struct {
+	guildId    string
+	configName string
+}
+
+// forget clears everything known about a scrape config's alerts, E.g: once it has moved to another replica's shard.
+func (b *Bot) forget(guildId string, configName string) {
+	b.deps.HealthTracker.Clear(guildId, configName)
+	b.deps.Receiver.Clear(guildId, configName)
+	b.deps.Tracker.Clear(guildId, configName)
+	b.deps.Grouper.Clear(guildId, configName)
+	b.deps.Escalator.Clear(guildId, configName)
+	b.deps.HeartbeatMonitor.Clear(guildId, configName)
+}
+
+// reconcileScrapers periodically, and each time the shards are rebalanced, starts and stops scrapers to match the stored scrape configs.
+// Scrape configs in other replicas' shards are forgotten, so that the heartbeat monitor doesn't report them as broken.
+// Scrape configs which have been removed are also forgotten, since they may have been removed using another replica.
+func reconcileScrapers(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, membership shard.Membership, rebalance <-chan struct{}, forget func(guildId string, configName string), logger logrus.FieldLogger) {
+	ticker := time.NewTicker(scraperReconcileInterval)
+	defer ticker.Stop()
+
+	// The last scrape configs which were loaded are used if they can't be loaded, so that scrape configs which have moved to another replica are still stopped
+	var scrapeConfigs map[string][]db.ScrapeConfig
+
+	// known contains each scrape config which has been loaded, so that those which are no longer stored can be forgotten
+	known := make(map[scrapeConfigKey]bool)
+
+	for {
+		guildConfigs, err := repo.GetGuildConfigs(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Errorf("Failed to get guild configs: %s", err.Error())
+			}
+		} else {
+			scrapeConfigs = make(map[string][]db.ScrapeConfig)
+			for _, guildConfig := range guildConfigs {
+				scrapeConfigs[guildConfig.GuildId] = guildConfig.ScrapeConfigs
+			}
+		}
+
+		// Scrapers are left as they are if the scrape configs have never been loaded, rather than being stopped
+		if scrapeConfigs != nil {
+			scrapeManager.Reconcile(scrapeConfigs)
+
+			stored := make(map[scrapeConfigKey]bool)
+			for guildId, configs := range scrapeConfigs {
+				for _, config := range configs {
+					k := scrapeConfigKey{guildId: guildId, configName: config.Name}
+					stored[k] = true
+					known[k] = true
+
+					if !membership.Owns(guildId, config.Name) {
+						forget(guildId, config.Name)
+					}
+				}
+			}
+
+			for k := range known {
+				if !stored[k] {
+					forget(k.guildId, k.configName)
+					delete(known, k)
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+
+		case <-rebalance:
+			logger.Debug("Shards rebalanced, reconciling scrapers")
+
+		case <-ctx.Done():
+			logger.Debug("Stopping reconcileScrapers")
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/scraper"
+	"github.com/yukitsune/minialert/shard"
+	"testing"
+	"time"
+)
+
+// fakeScrapeManager records the scrape configs it's reconciled with.
+type fakeScrapeManager struct {
+	scraper.ScrapeManager
+	reconciled chan map[string][]db.ScrapeConfig
+}
+
+func (m *fakeScrapeManager) Reconcile(scrapeConfigs map[string][]db.ScrapeConfig) {
+	m.reconciled <- scrapeConfigs
+}
+
+func TestReconcileScrapersForgetsRemovedScrapeConfigs(t *testing.T) {
+
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &fakeScrapeManager{reconciled: make(chan map[string][]db.ScrapeConfig)}
+	rebalance := make(chan struct{})
+
+	forgotten := make(chan scrapeConfigKey, 1)
+	forget := func(guildId string, configName string) {
+		forgotten <- scrapeConfigKey{guildId: guildId, configName: configName}
+	}
+
+	err := repo.SetGuildConfig(ctx, &db.GuildConfig{
+		GuildId:       "guild",
+		ScrapeConfigs: []db.ScrapeConfig{{Name: "foo"}, {Name: "bar"}},
+	})
+	assert.NoError(t, err)
+
+	go reconcileScrapers(ctx, repo, scrapeManager, shard.NewStaticMembership(), rebalance, forget, logger)
+	<-scrapeManager.reconciled
+
+	// The scrape config is removed using another replica
+	err = repo.SetGuildConfig(ctx, &db.GuildConfig{
+		GuildId:       "guild",
+		ScrapeConfigs: []db.ScrapeConfig{{Name: "foo"}},
+	})
+	assert.NoError(t, err)
+
+	// Act
+	rebalance <- struct{}{}
+	<-scrapeManager.reconciled
+
+	// Assert
+	select {
+	case k := <-forgotten:
+		assert.Equal(t, scrapeConfigKey{guildId: "guild", configName: "bar"}, k)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Removed scrape config wasn't forgotten")
+	}
+
+	assert.Empty(t, forgotten)
+}
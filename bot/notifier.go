@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/notify"
+	"time"
+)
+
+// messageAPI is the part of the Discord session used to send and edit alert messages.
+type messageAPI interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit) (*discordgo.Message, error)
+}
+
+// discordNotifier keeps one message per group of alerts in its channel, and edits it as the group changes.
+type discordNotifier struct {
+	messagesForGuild func(guildId string) messageAPI
+	repo             db.Repo
+	channelId        string
+	logger           logrus.FieldLogger
+}
+
+func newChannelNotifierFactory(sessions *sessions, repo db.Repo, logger logrus.FieldLogger) notify.ChannelNotifierFactory {
+	messagesForGuild := func(guildId string) messageAPI {
+		return sessions.forGuild(guildId)
+	}
+
+	return func(channelId string) notify.Notifier {
+		return &discordNotifier{
+			messagesForGuild: messagesForGuild,
+			repo:             repo,
+			channelId:        channelId,
+			logger:           logger.WithField("channel_id", channelId),
+		}
+	}
+}
+
+func (n *discordNotifier) Notify(ctx context.Context, notification alerts.Notification) error {
+	alertMessage, err := n.repo.GetAlertMessage(ctx, notification.GuildId, notification.ScrapeConfigName, notification.GroupKey, n.channelId)
+	if err != nil {
+		return fmt.Errorf("failed to get alert message: %s", err.Error())
+	}
+
+	if alertMessage != nil {
+		edited, err := n.edit(ctx, alertMessage, notification)
+		if err != nil {
+			return err
+		}
+
+		if edited {
+			return n.forgetIfResolved(ctx, notification)
+		}
+
+		// The message has been deleted, so a new one needs to be sent in its place
+		n.logger.Debugf("Alert message %s no longer exists", alertMessage.MessageId)
+	}
+
+	message, err := n.messagesForGuild(notification.GuildId).ChannelMessageSendComplex(n.channelId, getNotificationMessage(notification, false, n.logger))
+	if err != nil {
+		return err
+	}
+
+	// There's nothing left to update once the group has resolved
+	if len(notification.Firing) == 0 {
+		return nil
+	}
+
+	err = n.repo.SetAlertMessage(ctx, &db.AlertMessage{
+		GuildId:          notification.GuildId,
+		ScrapeConfigName: notification.ScrapeConfigName,
+		GroupKey:         notification.GroupKey,
+		ChannelId:        n.channelId,
+		MessageId:        message.ID,
+		CreatedAt:        time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set alert message: %s", err.Error())
+	}
+
+	return nil
+}
+
+// edit replaces the embeds and buttons of the existing alert message.
+// Returns false if the message no longer exists.
+func (n *discordNotifier) edit(ctx context.Context, alertMessage *db.AlertMessage, notification alerts.Notification) (bool, error) {
+	acknowledged, err := n.isAcknowledged(ctx, alertMessage, notification)
+	if err != nil {
+		return false, err
+	}
+
+	message := getNotificationMessage(notification, acknowledged, n.logger)
+
+	messageEdit := discordgo.NewMessageEdit(alertMessage.ChannelId, alertMessage.MessageId)
+	messageEdit.Embeds = message.Embeds
+
+	// Components need to be explicitly emptied to remove the buttons from the message
+	messageEdit.Components = message.Components
+	if messageEdit.Components == nil {
+		messageEdit.Components = []discordgo.MessageComponent{}
+	}
+
+	_, err = n.messagesForGuild(notification.GuildId).ChannelMessageEditComplex(messageEdit)
+	if err != nil {
+		var restErr *discordgo.RESTError
+		if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMessage {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// isAcknowledged returns true if the group has been acknowledged since the alert message was sent.
+func (n *discordNotifier) isAcknowledged(ctx context.Context, alertMessage *db.AlertMessage, notification alerts.Notification) (bool, error) {
+	return handlers.IsAcknowledged(ctx, n.repo, notification.GuildId, notification.ScrapeConfigName, notification.GroupKey, alertMessage.CreatedAt)
+}
+
+// forgetIfResolved deletes the alert message once the group has fully resolved, so that the next time it fires a new message is sent.
+func (n *discordNotifier) forgetIfResolved(ctx context.Context, notification alerts.Notification) error {
+	if len(notification.Firing) > 0 {
+		return nil
+	}
+
+	err := n.repo.DeleteAlertMessage(ctx, notification.GuildId, notification.ScrapeConfigName, notification.GroupKey, n.channelId)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert message: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/prometheus"
+	"testing"
+	"time"
+)
+
+// fakeMessageAPI records the messages sent and edited, and treats messages in deleted as no longer existing.
+type fakeMessageAPI struct {
+	sent    []*discordgo.MessageSend
+	edited  []*discordgo.MessageEdit
+	deleted map[string]bool
+}
+
+func (f *fakeMessageAPI) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	f.sent = append(f.sent, data)
+
+	return &discordgo.Message{
+		ID:        fmt.Sprintf("message-%d", len(f.sent)),
+		ChannelID: channelID,
+	}, nil
+}
+
+func (f *fakeMessageAPI) ChannelMessageEditComplex(m *discordgo.MessageEdit) (*discordgo.Message, error) {
+	if f.deleted[m.ID] {
+		return nil, &discordgo.RESTError{
+			Message: &discordgo.APIErrorMessage{Code: discordgo.ErrCodeUnknownMessage},
+		}
+	}
+
+	f.edited = append(f.edited, m)
+
+	return &discordgo.Message{
+		ID:        m.ID,
+		ChannelID: m.Channel,
+	}, nil
+}
+
+func setupNotifier() (*discordNotifier, *fakeMessageAPI, db.Repo) {
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	messages := &fakeMessageAPI{deleted: make(map[string]bool)}
+
+	notifier := &discordNotifier{
+		messagesForGuild: func(_ string) messageAPI { return messages },
+		repo:             repo,
+		channelId:        "channel",
+		logger:           logger,
+	}
+
+	return notifier, messages, repo
+}
+
+func newTestNotification(firing bool) alerts.Notification {
+	event := alerts.Event{
+		Type:        alerts.FiringEvent,
+		Fingerprint: "fingerprint",
+		Alert: prometheus.Alert{
+			Labels:   map[string]string{"alertname": "foo"},
+			ActiveAt: time.Now(),
+		},
+	}
+
+	notification := alerts.Notification{
+		GuildId:          "guild",
+		ScrapeConfigName: "config",
+		GroupKey:         "group",
+		GroupLabels:      map[string]string{"alertname": "foo"},
+	}
+
+	if firing {
+		notification.Firing = []alerts.Event{event}
+	} else {
+		event.Type = alerts.ResolvedEvent
+		notification.Resolved = []alerts.Event{event}
+	}
+
+	return notification
+}
+
+func TestNotifyEditsExistingMessage(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	notifier, messages, _ := setupNotifier()
+
+	err := notifier.Notify(ctx, newTestNotification(true))
+	assert.NoError(t, err)
+
+	// Act
+	err = notifier.Notify(ctx, newTestNotification(true))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, messages.sent, 1)
+	assert.Len(t, messages.edited, 1)
+	assert.Equal(t, "message-1", messages.edited[0].ID)
+	assert.Equal(t, "channel", messages.edited[0].Channel)
+}
+
+func TestNotifySendsNewMessageWhenExistingMessageWasDeleted(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	notifier, messages, repo := setupNotifier()
+
+	err := notifier.Notify(ctx, newTestNotification(true))
+	assert.NoError(t, err)
+
+	messages.deleted["message-1"] = true
+
+	// Act
+	err = notifier.Notify(ctx, newTestNotification(true))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, messages.sent, 2)
+	assert.Empty(t, messages.edited)
+
+	alertMessage, err := repo.GetAlertMessage(ctx, "guild", "config", "group", "channel")
+	assert.NoError(t, err)
+	assert.Equal(t, "message-2", alertMessage.MessageId)
+}
+
+func TestNotifyForgetsMessageOnceGroupHasResolved(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	notifier, messages, repo := setupNotifier()
+
+	err := notifier.Notify(ctx, newTestNotification(true))
+	assert.NoError(t, err)
+
+	// Act
+	err = notifier.Notify(ctx, newTestNotification(false))
+	assert.NoError(t, err)
+
+	err = notifier.Notify(ctx, newTestNotification(true))
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, messages.edited, 1)
+	assert.Equal(t, "message-1", messages.edited[0].ID)
+	assert.Empty(t, messages.edited[0].Components)
+
+	// The group fired again after resolving, so a new message is sent rather than editing the resolved one
+	assert.Len(t, messages.sent, 2)
+
+	alertMessage, err := repo.GetAlertMessage(ctx, "guild", "config", "group", "channel")
+	assert.NoError(t, err)
+	assert.Equal(t, "message-2", alertMessage.MessageId)
+}
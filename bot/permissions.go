@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"context"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/handlers"
+)
+
+type CommandPermissions map[InteractionName]db.PermissionLevel
+
+// authorize checks whether the member who created the interaction is allowed to use the command.
+// If they aren't, they're told so, unless the interaction is an autocomplete which can't be responded to with a message.
+func authorize(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, repo db.Repo, commandPermissions CommandPermissions, commandName InteractionName, logger logrus.FieldLogger) bool {
+
+	// Commands can only be used in guilds
+	if i.Member == nil {
+		return false
+	}
+
+	required, ok := commandPermissions[commandName]
+	if !ok {
+		required = db.AdminPermission
+	}
+
+	isAdmin := i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0
+
+	// Everyone has the @everyone role, which shares its ID with the guild
+	roleIds := append([]string{i.GuildID}, i.Member.Roles...)
+
+	permitted, err := handlers.HasPermission(ctx, repo, i.GuildID, roleIds, isAdmin, required)
+	if err != nil {
+		logger.Errorf("Failed to check permissions: %s", err.Error())
+	}
+
+	if permitted {
+		return true
+	}
+
+	logger.Infof("Member %s doesn't have the %s permission", getUserId(i), required)
+
+	if i.Type != discordgo.InteractionApplicationCommandAutocomplete {
+		respondEphemeral(s, i, logger, "❌ You don't have permission to do that.")
+	}
+
+	return false
+}
@@ -19,10 +19,44 @@ func respond(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus
 	}
 }
 
+// respondEphemeral responds with a message which is only visible to the user who invoked the interaction.
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   uint64(discordgo.MessageFlagsEphemeral),
+		},
+	})
+
+	if err != nil {
+		logger.Errorf("Failed to respond: %s", err.Error())
+	}
+}
+
+// respondWithoutMentions responds with a message which can mention roles and users without notifying them.
+func respondWithoutMentions(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         message,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+
+	if err != nil {
+		logger.Errorf("Failed to respond: %s", err.Error())
+	}
+}
+
 func respondWithSuccess(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
 	respond(s, i, logger, fmt.Sprintf("✅ %s", message))
 }
 
+func respondWithSuccessWithoutMentions(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
+	respondWithoutMentions(s, i, logger, fmt.Sprintf("✅ %s", message))
+}
+
 func respondWithWarning(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
 	respond(s, i, logger, fmt.Sprintf("⚠️ %s", message))
 }
@@ -30,3 +64,11 @@ func respondWithWarning(s *discordgo.Session, i *discordgo.InteractionCreate, lo
 func respondWithError(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
 	respond(s, i, logger, fmt.Sprintf("❌ %s", message))
 }
+
+func respondWithEphemeralSuccess(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
+	respondEphemeral(s, i, logger, fmt.Sprintf("✅ %s", message))
+}
+
+func respondWithEphemeralError(s *discordgo.Session, i *discordgo.InteractionCreate, logger logrus.FieldLogger, message string) {
+	respondEphemeral(s, i, logger, fmt.Sprintf("❌ %s", message))
+}
@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/config"
+	"github.com/yukitsune/minialert/slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// identifyInterval is how long Discord requires shards with the same rate limit key to wait between identifying.
+const identifyInterval = 5 * time.Second
+
+// shardForGuild returns the gateway shard which receives the guild's events.
+// See https://discord.com/developers/docs/topics/gateway#sharding
+func shardForGuild(guildId string, shardCount int) int {
+	id, err := strconv.ParseUint(guildId, 10, 64)
+	if err != nil || shardCount <= 1 {
+		return 0
+	}
+
+	return int((id >> 22) % uint64(shardCount))
+}
+
+// sessions holds a Discord session for each of the gateway shards run by this process.
+type sessions struct {
+	shardCount int
+	shardIds   []int
+	byShard    map[int]*discordgo.Session
+}
+
+// gatewayBotFunc gets the number of shards recommended by Discord, along with the session start limits.
+type gatewayBotFunc func() (*discordgo.GatewayBotResponse, error)
+
+// openSessions opens a session for each of the configured gateway shards, calling configure on each session before it's opened.
+// If any session fails to open, the ones which were opened are closed.
+func openSessions(ctx context.Context, cfg config.Bot, configure func(s *discordgo.Session), logger logrus.FieldLogger) (*sessions, error) {
+	gatewayBot := func() (*discordgo.GatewayBotResponse, error) {
+		s, err := discordgo.New("Bot " + cfg.Token())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Discord session: %s", err.Error())
+		}
+
+		return s.GatewayBot()
+	}
+
+	shardCount, maxConcurrency, shardIds, err := getShards(cfg, gatewayBot, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	opened := &sessions{
+		shardCount: shardCount,
+		byShard:    make(map[int]*discordgo.Session),
+	}
+
+	lastIdentified := make(map[int]time.Time)
+	for _, shardId := range shardIds {
+		// Shards share a rate limit key with every shard whose ID has the same remainder when divided by the max concurrency
+		rateLimitKey := shardId % maxConcurrency
+		if last, ok := lastIdentified[rateLimitKey]; ok {
+			select {
+			case <-time.After(time.Until(last.Add(identifyInterval))):
+			case <-ctx.Done():
+				_ = opened.close()
+				return nil, ctx.Err()
+			}
+		}
+
+		s, err := discordgo.New("Bot " + cfg.Token())
+		if err != nil {
+			_ = opened.close()
+			return nil, fmt.Errorf("failed to create Discord session: %s", err.Error())
+		}
+
+		s.ShardID = shardId
+		s.ShardCount = shardCount
+		configure(s)
+
+		if shardCount > 1 {
+			logger.Infof("📡 Opening session for shard %d of %d...", shardId, shardCount)
+		} else {
+			logger.Infoln("📡 Opening session...")
+		}
+
+		lastIdentified[rateLimitKey] = time.Now()
+		err = s.Open()
+		if err != nil {
+			_ = opened.close()
+			return nil, fmt.Errorf("cannot open session for shard %d: %s", shardId, err.Error())
+		}
+
+		opened.shardIds = append(opened.shardIds, shardId)
+		opened.byShard[shardId] = s
+	}
+
+	return opened, nil
+}
+
+// getShards returns the total number of shards, the number of shards which can identify at once, and the shards to be run by this process.
+// If sharding isn't configured, a single session is used without asking Discord for the recommended shard count.
+func getShards(cfg config.Bot, gatewayBot gatewayBotFunc, logger logrus.FieldLogger) (int, int, []int, error) {
+	if cfg.ShardCount() <= 0 && len(cfg.ShardIds()) == 0 {
+		return 1, 1, []int{0}, nil
+	}
+
+	shardCount, maxConcurrency, err := getShardCount(cfg, gatewayBot, logger)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	var shardIds []int
+	if len(cfg.ShardIds()) == 0 {
+		for shardId := 0; shardId < shardCount; shardId++ {
+			shardIds = append(shardIds, shardId)
+		}
+
+		return shardCount, maxConcurrency, shardIds, nil
+	}
+
+	for _, shardId := range cfg.ShardIds() {
+		if shardId < 0 || shardId >= shardCount {
+			return 0, 0, nil, fmt.Errorf("shard %d is out of range for %d shards", shardId, shardCount)
+		}
+
+		if !slices.Contains(shardIds, shardId) {
+			shardIds = append(shardIds, shardId)
+		}
+	}
+
+	return shardCount, maxConcurrency, shardIds, nil
+}
+
+// getShardCount returns the configured shard count, or the one recommended by Discord, along with the number of shards which can identify at once.
+func getShardCount(cfg config.Bot, gatewayBot gatewayBotFunc, logger logrus.FieldLogger) (int, int, error) {
+	shardCount := cfg.ShardCount()
+	maxConcurrency := 1
+
+	gateway, err := gatewayBot()
+	if err != nil {
+		if shardCount <= 0 {
+			return 0, 0, fmt.Errorf("failed to get the recommended shard count: %s", err.Error())
+		}
+
+		// The shard count is known, so shards can still be opened one at a time
+		logger.Warnf("Failed to get gateway session limits: %s", err.Error())
+	} else {
+		if shardCount <= 0 {
+			shardCount = gateway.Shards
+		}
+
+		if gateway.SessionStartLimit.MaxConcurrency > 0 {
+			maxConcurrency = gateway.SessionStartLimit.MaxConcurrency
+		}
+	}
+
+	if shardCount <= 0 {
+		shardCount = 1
+	}
+
+	return shardCount, maxConcurrency, nil
+}
+
+// forGuild returns the session for the guild's shard.
+// REST requests, such as sending messages, can be made using any session, so another session is returned if the guild's shard is run by another process.
+func (s *sessions) forGuild(guildId string) *discordgo.Session {
+	if session, ok := s.byShard[shardForGuild(guildId, s.shardCount)]; ok {
+		return session
+	}
+
+	return s.byShard[s.shardIds[0]]
+}
+
+func (s *sessions) close() error {
+	var errs []string
+	for _, shardId := range s.shardIds {
+		err := s.byShard[shardId].Close()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("shard %d: %s", shardId, err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close sessions: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/config"
+	"testing"
+)
+
+func newTestBotConfig(shardCount int, shardIds string) config.Bot {
+	v := viper.New()
+	v.Set("bot.shardCount", shardCount)
+	v.Set("bot.shardIds", shardIds)
+
+	return config.NewConfigProvider(v).Bot()
+}
+
+// fakeGatewayBot returns a gatewayBotFunc which recommends the given number of shards, and counts how many times it was called.
+func fakeGatewayBot(shards int, maxConcurrency int, err error, calls *int) gatewayBotFunc {
+	return func() (*discordgo.GatewayBotResponse, error) {
+		*calls++
+		if err != nil {
+			return nil, err
+		}
+
+		return &discordgo.GatewayBotResponse{
+			Shards: shards,
+			SessionStartLimit: discordgo.SessionInformation{
+				MaxConcurrency: maxConcurrency,
+			},
+		}, nil
+	}
+}
+
+func TestShardForGuild(t *testing.T) {
+
+	tests := []struct {
+		guildId    string
+		shardCount int
+		expected   int
+	}{
+		{guildId: "29360128", shardCount: 1, expected: 0},
+		{guildId: "29360128", shardCount: 2, expected: 1},
+		{guildId: "29360128", shardCount: 3, expected: 1},
+		{guildId: "29360128", shardCount: 5, expected: 2},
+		{guildId: "197038439483310086", shardCount: 3, expected: 2},
+		{guildId: "197038439483310086", shardCount: 0, expected: 0},
+		{guildId: "not-a-snowflake", shardCount: 3, expected: 0},
+	}
+
+	for _, test := range tests {
+		// Act
+		shardId := shardForGuild(test.guildId, test.shardCount)
+
+		// Assert
+		assert.Equal(t, test.expected, shardId, fmt.Sprintf("%s with %d shards", test.guildId, test.shardCount))
+	}
+}
+
+func TestGetShardsUsesSingleSessionWhenShardingIsNotConfigured(t *testing.T) {
+
+	// Arrange
+	cfg := newTestBotConfig(0, "")
+	calls := 0
+
+	// Act
+	shardCount, maxConcurrency, shardIds, err := getShards(cfg, fakeGatewayBot(4, 1, nil, &calls), logrus.New())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, shardCount)
+	assert.Equal(t, 1, maxConcurrency)
+	assert.Equal(t, []int{0}, shardIds)
+	assert.Zero(t, calls)
+}
+
+func TestGetShardsUsesRecommendedShardCountForConfiguredShardIds(t *testing.T) {
+
+	// Arrange
+	cfg := newTestBotConfig(0, "1,3,3")
+	calls := 0
+
+	// Act
+	shardCount, maxConcurrency, shardIds, err := getShards(cfg, fakeGatewayBot(4, 2, nil, &calls), logrus.New())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 4, shardCount)
+	assert.Equal(t, 2, maxConcurrency)
+	assert.Equal(t, []int{1, 3}, shardIds)
+	assert.Equal(t, 1, calls)
+}
+
+func TestGetShardsRunsEveryShardWhenSessionLimitsAreUnavailable(t *testing.T) {
+
+	// Arrange
+	cfg := newTestBotConfig(3, "")
+	calls := 0
+
+	// Act
+	shardCount, maxConcurrency, shardIds, err := getShards(cfg, fakeGatewayBot(0, 0, errors.New("unavailable"), &calls), logrus.New())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 3, shardCount)
+	assert.Equal(t, 1, maxConcurrency)
+	assert.Equal(t, []int{0, 1, 2}, shardIds)
+}
+
+func TestGetShardsRejectsShardIdsOutOfRange(t *testing.T) {
+
+	// Arrange
+	cfg := newTestBotConfig(2, "0,2")
+	calls := 0
+
+	// Act
+	_, _, _, err := getShards(cfg, fakeGatewayBot(2, 1, nil, &calls), logrus.New())
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestForGuildFallsBackToAnotherSessionWhenShardIsRunElsewhere(t *testing.T) {
+
+	// Arrange
+	shard1 := &discordgo.Session{ShardID: 1}
+	s := &sessions{
+		shardCount: 2,
+		shardIds:   []int{1},
+		byShard:    map[int]*discordgo.Session{1: shard1},
+	}
+
+	// Act
+	session := s.forGuild("4194304")
+	fallback := s.forGuild("8388608")
+
+	// Assert
+	assert.Same(t, shard1, session)
+	assert.Same(t, shard1, fallback)
+}
@@ -7,13 +7,22 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/yukitsune/minialert"
+	"github.com/yukitsune/minialert/alerts"
 	"github.com/yukitsune/minialert/bot"
 	"github.com/yukitsune/minialert/config"
 	"github.com/yukitsune/minialert/db"
 	"github.com/yukitsune/minialert/grace"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/leader"
+	"github.com/yukitsune/minialert/notify"
 	"github.com/yukitsune/minialert/prometheus"
+	"github.com/yukitsune/minialert/receiver"
 	"github.com/yukitsune/minialert/scraper"
+	"github.com/yukitsune/minialert/secrets"
+	"github.com/yukitsune/minialert/shard"
 	"log"
+	"net/http"
+	"os"
 )
 
 var rootCmd = &cobra.Command{
@@ -36,7 +45,17 @@ var runCmd = &cobra.Command{
 	RunE:  run,
 }
 
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Re-encrypts all stored secrets with a new encryption key",
+	Long: `Re-encrypts all stored secrets with the key in the given key file, generating a new key if the file doesn't exist.
+Secrets which aren't encrypted yet are encrypted too, and secrets already encrypted with the new key are skipped, so rotating can be resumed if it fails.
+The bot should be stopped while the key is rotated, and the config updated to use the new key afterwards.`,
+	RunE: rotateKey,
+}
+
 var configFile string
+var newKeyFile string
 
 // Todo: Tidy up cobra x viper stuffs
 
@@ -45,8 +64,12 @@ func init() {
 	rootCmd.PersistentFlags().Bool("debug", false, "use verbose logging")
 	_ = viper.BindPFlag("log.debug", rootCmd.PersistentFlags().Lookup("debug"))
 
+	rotateKeyCmd.Flags().StringVar(&newKeyFile, "new-key-file", "", "the file containing the new base64 encoded key")
+	_ = rotateKeyCmd.MarkFlagRequired("new-key-file")
+
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(rotateKeyCmd)
 }
 
 func main() {
@@ -58,8 +81,6 @@ func main() {
 
 func run(_ *cobra.Command, _ []string) error {
 
-	ctx, cancel := context.WithCancel(context.Background())
-
 	// Build the logger now, so we can log stuff
 	// We'll configure it once we've loaded the config
 	logger := logrus.New()
@@ -69,13 +90,66 @@ func run(_ *cobra.Command, _ []string) error {
 
 	logger.Debugf("Config %s", cfg.Debug())
 
-	repo := configureRepo(cfg.Database(), logger)
+	repo, err := configureRepo(cfg.Database(), cfg.Encryption(), logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	clientFactory := prometheus.NewClientFactory(cfg.Prometheus())
+
+	scrapeManager := scraper.NewScrapeManager(clientFactory, cfg.Prometheus().MinScrapeInterval(), cfg.Prometheus().MaxBackoff(), logger)
+	healthTracker := scraper.NewHealthTracker(cfg.Prometheus().FailureThreshold())
+
+	tracker := alerts.NewTracker()
+	grouper := alerts.NewGrouper()
+	escalator := alerts.NewEscalator()
+	heartbeatMonitor := alerts.NewHeartbeatMonitor()
+
+	if cfg.Sharding().Enabled() && cfg.LeaderElection().Enabled() {
+		cancel()
+		return fmt.Errorf("leader election and sharding can't both be enabled, as every replica scrapes its own shard when sharding")
+	}
+
+	membership, err := shard.NewMembership(cfg.Sharding(), repo, logger)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	rcv := receiver.NewReceiver(repo, membership.Owns, logger)
 
-	clientFactory := prometheus.NewClientFromScrapeConfig
+	notifierFactory := notify.NewFactory(cfg.Notifiers())
 
-	scrapeManager := scraper.NewScrapeManager(clientFactory, logger)
+	if cfg.LeaderElection().Enabled() && cfg.Database().UseInMemoryDatabase() {
+		logger.Warnln("Leader election is enabled, but replicas can't share an in-memory database.")
+	}
+
+	if cfg.Sharding().Enabled() && cfg.Database().UseInMemoryDatabase() {
+		logger.Warnln("Sharding is enabled, but replicas can't share an in-memory database.")
+	}
+
+	elector, err := leader.NewElector(cfg.LeaderElection(), repo, logger)
+	if err != nil {
+		cancel()
+		return err
+	}
 
-	b := bot.New(cfg.Bot(), repo, clientFactory, scrapeManager, logger)
+	b := bot.New(cfg.Bot(), cfg.Receiver(), cfg.Prometheus(), bot.Dependencies{
+		Repo:             repo,
+		ClientFactory:    clientFactory,
+		ScrapeManager:    scrapeManager,
+		Elector:          elector,
+		Membership:       membership,
+		HealthTracker:    healthTracker,
+		Receiver:         rcv,
+		Tracker:          tracker,
+		Grouper:          grouper,
+		Escalator:        escalator,
+		HeartbeatMonitor: heartbeatMonitor,
+		NotifierFactory:  notifierFactory,
+	}, logger)
 
 	errorsChan := make(chan error)
 	go func() {
@@ -86,8 +160,26 @@ func run(_ *cobra.Command, _ []string) error {
 		}
 	}()
 
+	var server *http.Server
+	if cfg.Receiver().Enabled() {
+		server = receiver.NewServer(cfg.Receiver().Address(), rcv, elector.IsLeader)
+		go func() {
+			logger.Infof("📥 Receiver listening on %s", server.Addr)
+			err := server.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				errorsChan <- fmt.Errorf("receiver failed: %s", err.Error())
+			}
+		}()
+	}
+
 	grace.WaitForShutdownSignalOrError(logger, errorsChan, func() error {
 		cancel()
+		if server != nil {
+			if err := server.Shutdown(context.Background()); err != nil {
+				logger.Errorf("Failed to shut down receiver: %s", err.Error())
+			}
+		}
+
 		return b.Close()
 	})
 
@@ -99,7 +191,23 @@ func configureLogging(logger *logrus.Logger, cfg config.Log) {
 	logger.SetLevel(lvl)
 }
 
-func configureRepo(cfg config.Database, logger logrus.FieldLogger) db.Repo {
+func configureRepo(cfg config.Database, encryptionCfg config.Encryption, logger logrus.FieldLogger) (db.Repo, error) {
+	repo := configureDatabase(cfg, logger)
+
+	keyring, err := secrets.LoadKeyring(encryptionCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %s", err.Error())
+	}
+
+	if keyring == nil {
+		logger.Warnln("No encryption key configured. Scrape config secrets will be stored in plaintext.")
+		return repo, nil
+	}
+
+	return db.NewEncryptedRepo(repo, keyring), nil
+}
+
+func configureDatabase(cfg config.Database, logger logrus.FieldLogger) db.Repo {
 	if cfg.UseInMemoryDatabase() {
 		logger.Warnln("Using in-memory database. Data will not be persisted after the program has exited.")
 		return db.SetupInMemoryDatabase(logger)
@@ -107,3 +215,62 @@ func configureRepo(cfg config.Database, logger logrus.FieldLogger) db.Repo {
 
 	return db.SetupMongoDatabase(cfg)
 }
+
+func rotateKey(_ *cobra.Command, _ []string) error {
+
+	ctx := context.Background()
+	logger := logrus.New()
+
+	cfg := config.Setup(configFile, viper.GetViper(), logger)
+	configureLogging(logger, cfg.Log())
+
+	if cfg.Database().UseInMemoryDatabase() {
+		return fmt.Errorf("the in-memory database doesn't persist any secrets to rotate")
+	}
+
+	newKey, err := loadOrGenerateKeyFile(newKeyFile, logger)
+	if err != nil {
+		return err
+	}
+
+	// Secrets stored without a key are plaintext, so they can be read as-is
+	currentKeyring, err := secrets.LoadKeyring(cfg.Encryption())
+	if err != nil {
+		return fmt.Errorf("failed to load encryption key: %s", err.Error())
+	}
+
+	// Secrets which were re-encrypted by an earlier attempt can still be read, and are skipped
+	keyring := currentKeyring.Rotate(newKey)
+
+	count, err := handlers.RotateEncryptionKey(ctx, configureDatabase(cfg.Database(), logger), keyring)
+	if err != nil {
+		return fmt.Errorf("failed to rotate key after re-encrypting %d guild configs: %s. Run rotate-key again with the same key file to resume", count, err.Error())
+	}
+
+	logger.Infof("🔑 Re-encrypted %d guild configs with key %s. Update the config to use %s before starting the bot.", count, newKey.Id(), newKeyFile)
+	return nil
+}
+
+func loadOrGenerateKeyFile(path string, logger logrus.FieldLogger) (*secrets.Key, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return secrets.ReadKeyFile(path)
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read key file: %s", err.Error())
+	}
+
+	encoded, err := secrets.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %s", err.Error())
+	}
+
+	err = os.WriteFile(path, []byte(encoded), 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write key file: %s", err.Error())
+	}
+
+	logger.Infof("🔑 Generated a new key in %s", path)
+	return secrets.ParseKey(encoded)
+}
@@ -1,12 +1,23 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"strconv"
+	"strings"
+)
 
 type Bot interface {
 	Token() string
 	ClientId() string
 	Permissions() string
 	Scopes() []string
+
+	// ShardCount is the total number of gateway shards. When 0, a single shard is used, unless ShardIds are set, in which case the number recommended by Discord is used.
+	ShardCount() int
+
+	// ShardIds are the gateway shards run by this process. When empty, every shard is run.
+	ShardIds() []int
 }
 
 type viperBotConfig struct {
@@ -48,3 +59,30 @@ func (c *viperBotConfig) Scopes() []string {
 	scopes := c.v.GetStringSlice("bot.scopes")
 	return scopes
 }
+
+func (c *viperBotConfig) ShardCount() int {
+	return c.v.GetInt("bot.shardCount")
+}
+
+func (c *viperBotConfig) ShardIds() []int {
+	var shardIds []int
+
+	// Environment variables can only be given as a single string, E.g: "0,1"
+	for _, value := range c.v.GetStringSlice("bot.shardIds") {
+		for _, rawId := range strings.Split(value, ",") {
+			rawId = strings.TrimSpace(rawId)
+			if len(rawId) == 0 {
+				continue
+			}
+
+			shardId, err := strconv.Atoi(rawId)
+			if err != nil {
+				panic(fmt.Sprintf("invalid discord bot shard id \"%s\"", rawId))
+			}
+
+			shardIds = append(shardIds, shardId)
+		}
+	}
+
+	return shardIds
+}
@@ -5,15 +5,28 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"net/url"
 	"strings"
 )
 
+// sensitiveKeys are the parts of config keys whose values are redacted from the debug output.
+var sensitiveKeys = []string{"password", "token", "secret", "key", "authorization"}
+
 func Setup(configFile string, v *viper.Viper, logger logrus.FieldLogger) Config {
 
 	// Set defaults
 	v.SetDefault("prometheus.timeoutSeconds", 5)
+	v.SetDefault("prometheus.failureThreshold", 3)
+	v.SetDefault("prometheus.minScrapeInterval", "10s")
+	v.SetDefault("prometheus.maxBackoff", "10m")
 	v.SetDefault("bot.scopes", []string{"bot", "application.commands"})
 	v.SetDefault("log.level", "info")
+	v.SetDefault("receiver.address", ":9094")
+	v.SetDefault("notifiers.smtp.port", 587)
+	v.SetDefault("leaderElection.leaseDuration", "15s")
+	v.SetDefault("leaderElection.renewInterval", "5s")
+	v.SetDefault("sharding.heartbeatInterval", "5s")
+	v.SetDefault("sharding.memberTimeout", "15s")
 
 	// Environment variables
 	v.SetEnvPrefix("MINIALERT")
@@ -48,6 +61,12 @@ type Config interface {
 	Database() Database
 	Bot() Bot
 	Log() Log
+	Receiver() Receiver
+	Notifiers() Notifiers
+	Encryption() Encryption
+	Prometheus() Prometheus
+	LeaderElection() LeaderElection
+	Sharding() Sharding
 	Debug() string
 }
 
@@ -56,6 +75,12 @@ type viperConfig struct {
 	db  *viperDatabaseConfig
 	bot *viperBotConfig
 	log *viperLogConfig
+	rcv *viperReceiverConfig
+	ntf *viperNotifiersConfig
+	enc *viperEncryptionConfig
+	prm *viperPrometheusConfig
+	le  *viperLeaderElectionConfig
+	shd *viperShardingConfig
 }
 
 func NewConfigProvider(v *viper.Viper) Config {
@@ -64,6 +89,12 @@ func NewConfigProvider(v *viper.Viper) Config {
 		db:  &viperDatabaseConfig{v},
 		bot: &viperBotConfig{v},
 		log: &viperLogConfig{v},
+		rcv: &viperReceiverConfig{v},
+		ntf: &viperNotifiersConfig{v},
+		enc: &viperEncryptionConfig{v},
+		prm: &viperPrometheusConfig{v},
+		le:  &viperLeaderElectionConfig{v},
+		shd: &viperShardingConfig{v},
 	}
 }
 
@@ -79,6 +110,111 @@ func (c *viperConfig) Log() Log {
 	return c.log
 }
 
+func (c *viperConfig) Receiver() Receiver {
+	return c.rcv
+}
+
+func (c *viperConfig) Notifiers() Notifiers {
+	return c.ntf
+}
+
+func (c *viperConfig) Encryption() Encryption {
+	return c.enc
+}
+
+func (c *viperConfig) Prometheus() Prometheus {
+	return c.prm
+}
+
+func (c *viperConfig) LeaderElection() LeaderElection {
+	return c.le
+}
+
+func (c *viperConfig) Sharding() Sharding {
+	return c.shd
+}
+
+// Debug returns all settings, with any passwords or tokens redacted so that it's safe to log.
 func (c *viperConfig) Debug() string {
-	return fmt.Sprintf("%#v", c.v.AllSettings())
+	return fmt.Sprintf("%#v", redactSettings(c.v.AllSettings()))
+}
+
+func redactSettings(settings map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			redacted[k] = redactSettings(value)
+
+		case string:
+			redacted[k] = redactSetting(k, value)
+
+		case []string:
+			redacted[k] = redactList(k, value)
+
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+
+			redacted[k] = redactList(k, items)
+
+		default:
+			redacted[k] = v
+		}
+	}
+
+	return redacted
+}
+
+func redactList(key string, values []string) []string {
+	redacted := make([]string, 0, len(values))
+	for _, value := range values {
+		redacted = append(redacted, redactSetting(key, value))
+	}
+
+	return redacted
+}
+
+func redactSetting(key string, value string) string {
+	if len(value) == 0 {
+		return value
+	}
+
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return "<redacted>"
+		}
+	}
+
+	// URIs, such as the MongoDB connection string, can contain credentials
+	if key == "uri" {
+		u, err := url.Parse(value)
+		if err != nil {
+			return "<redacted>"
+		}
+
+		return u.Redacted()
+	}
+
+	return value
+}
+
+// getList returns the list of strings for the key, splitting any comma-separated values.
+func getList(v *viper.Viper, key string) []string {
+	var values []string
+
+	// Environment variables can only be given as a single string, E.g: "a,b"
+	for _, value := range v.GetStringSlice(key) {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if len(item) > 0 {
+				values = append(values, item)
+			}
+		}
+	}
+
+	return values
 }
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+)
+
+type Encryption interface {
+	Key() string
+	KeyFile() string
+
+	// PreviousKeys are keys which secrets may still be encrypted with, E.g: while the key is being rotated.
+	// They're only used to decrypt secrets.
+	PreviousKeys() []string
+	PreviousKeyFiles() []string
+}
+
+type viperEncryptionConfig struct {
+	v *viper.Viper
+}
+
+func (c *viperEncryptionConfig) Key() string {
+	return c.v.GetString("encryption.key")
+}
+
+func (c *viperEncryptionConfig) KeyFile() string {
+	return c.v.GetString("encryption.keyFile")
+}
+
+func (c *viperEncryptionConfig) PreviousKeys() []string {
+	return getList(c.v, "encryption.previousKeys")
+}
+
+func (c *viperEncryptionConfig) PreviousKeyFiles() []string {
+	return getList(c.v, "encryption.previousKeyFiles")
+}
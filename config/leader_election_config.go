@@ -0,0 +1,36 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"time"
+)
+
+// LeaderElection determines how replicas decide which of them runs the scrapers and sends notifications.
+type LeaderElection interface {
+	Enabled() bool
+
+	// Id identifies this replica. When empty, an ID is generated from the hostname.
+	Id() string
+	LeaseDuration() time.Duration
+	RenewInterval() time.Duration
+}
+
+type viperLeaderElectionConfig struct {
+	v *viper.Viper
+}
+
+func (c *viperLeaderElectionConfig) Enabled() bool {
+	return c.v.GetBool("leaderElection.enabled")
+}
+
+func (c *viperLeaderElectionConfig) Id() string {
+	return c.v.GetString("leaderElection.id")
+}
+
+func (c *viperLeaderElectionConfig) LeaseDuration() time.Duration {
+	return c.v.GetDuration("leaderElection.leaseDuration")
+}
+
+func (c *viperLeaderElectionConfig) RenewInterval() time.Duration {
+	return c.v.GetDuration("leaderElection.renewInterval")
+}
@@ -0,0 +1,40 @@
+package config
+
+import "github.com/spf13/viper"
+
+type Notifiers interface {
+	SmtpHost() string
+	SmtpPort() int
+	SmtpUsername() string
+	SmtpPassword() string
+	SmtpFrom() string
+	FilePath() string
+}
+
+type viperNotifiersConfig struct {
+	v *viper.Viper
+}
+
+func (c *viperNotifiersConfig) SmtpHost() string {
+	return c.v.GetString("notifiers.smtp.host")
+}
+
+func (c *viperNotifiersConfig) SmtpPort() int {
+	return c.v.GetInt("notifiers.smtp.port")
+}
+
+func (c *viperNotifiersConfig) SmtpUsername() string {
+	return c.v.GetString("notifiers.smtp.username")
+}
+
+func (c *viperNotifiersConfig) SmtpPassword() string {
+	return c.v.GetString("notifiers.smtp.password")
+}
+
+func (c *viperNotifiersConfig) SmtpFrom() string {
+	return c.v.GetString("notifiers.smtp.from")
+}
+
+func (c *viperNotifiersConfig) FilePath() string {
+	return c.v.GetString("notifiers.file.path")
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"time"
+)
+
+// Prometheus contains the defaults used when scraping every scrape config's endpoint.
+// Any values set on the scrape config itself take precedence.
+type Prometheus interface {
+	// TrustedHosts are the hosts which the default headers and certificates are used for.
+	// Scrape configs can be created for any endpoint, so they're never sent to other hosts.
+	TrustedHosts() []string
+	Headers() map[string]string
+	CaFile() string
+	CertFile() string
+	KeyFile() string
+	InsecureSkipVerify() bool
+	FailureThreshold() int
+	MinScrapeInterval() time.Duration
+	MaxBackoff() time.Duration
+	Timeout() time.Duration
+}
+
+type viperPrometheusConfig struct {
+	v *viper.Viper
+}
+
+func (c *viperPrometheusConfig) TrustedHosts() []string {
+	return getList(c.v, "prometheus.trustedHosts")
+}
+
+func (c *viperPrometheusConfig) Headers() map[string]string {
+	return c.v.GetStringMapString("prometheus.headers")
+}
+
+func (c *viperPrometheusConfig) CaFile() string {
+	return c.v.GetString("prometheus.tls.caFile")
+}
+
+func (c *viperPrometheusConfig) CertFile() string {
+	return c.v.GetString("prometheus.tls.certFile")
+}
+
+func (c *viperPrometheusConfig) KeyFile() string {
+	return c.v.GetString("prometheus.tls.keyFile")
+}
+
+func (c *viperPrometheusConfig) InsecureSkipVerify() bool {
+	return c.v.GetBool("prometheus.tls.insecureSkipVerify")
+}
+
+func (c *viperPrometheusConfig) FailureThreshold() int {
+	return c.v.GetInt("prometheus.failureThreshold")
+}
+
+func (c *viperPrometheusConfig) MinScrapeInterval() time.Duration {
+	return c.v.GetDuration("prometheus.minScrapeInterval")
+}
+
+func (c *viperPrometheusConfig) MaxBackoff() time.Duration {
+	return c.v.GetDuration("prometheus.maxBackoff")
+}
+
+func (c *viperPrometheusConfig) Timeout() time.Duration {
+	return time.Duration(c.v.GetInt("prometheus.timeoutSeconds")) * time.Second
+}
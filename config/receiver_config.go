@@ -0,0 +1,25 @@
+package config
+
+import "github.com/spf13/viper"
+
+type Receiver interface {
+	Enabled() bool
+	Address() string
+	ExternalUrl() string
+}
+
+type viperReceiverConfig struct {
+	v *viper.Viper
+}
+
+func (c *viperReceiverConfig) Enabled() bool {
+	return c.v.GetBool("receiver.enabled")
+}
+
+func (c *viperReceiverConfig) Address() string {
+	return c.v.GetString("receiver.address")
+}
+
+func (c *viperReceiverConfig) ExternalUrl() string {
+	return c.v.GetString("receiver.externalUrl")
+}
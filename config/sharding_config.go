@@ -0,0 +1,38 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"time"
+)
+
+// Sharding determines how scrape configs are split between replicas.
+type Sharding interface {
+	Enabled() bool
+
+	// Id identifies this replica. When empty, an ID is generated from the hostname.
+	Id() string
+	HeartbeatInterval() time.Duration
+
+	// MemberTimeout is how long a replica can go without sending a heartbeat before its scrape configs are taken over by the other replicas.
+	MemberTimeout() time.Duration
+}
+
+type viperShardingConfig struct {
+	v *viper.Viper
+}
+
+func (c *viperShardingConfig) Enabled() bool {
+	return c.v.GetBool("sharding.enabled")
+}
+
+func (c *viperShardingConfig) Id() string {
+	return c.v.GetString("sharding.id")
+}
+
+func (c *viperShardingConfig) HeartbeatInterval() time.Duration {
+	return c.v.GetDuration("sharding.heartbeatInterval")
+}
+
+func (c *viperShardingConfig) MemberTimeout() time.Duration {
+	return c.v.GetDuration("sharding.memberTimeout")
+}
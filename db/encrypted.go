@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Cipher encrypts secrets before they're stored, and decrypts them once they've been retrieved.
+type Cipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+
+	// IsCurrent returns true if the value was encrypted with the key Encrypt uses.
+	IsCurrent(value string) bool
+}
+
+// encryptedRepo encrypts the secrets in each guild config before passing it on to the underlying Repo.
+type encryptedRepo struct {
+	Repo
+	cipher Cipher
+}
+
+// NewEncryptedRepo wraps the Repo so that scrape config passwords, bearer tokens, client keys, header values and notifier URLs are encrypted at rest.
+func NewEncryptedRepo(repo Repo, cipher Cipher) Repo {
+	return &encryptedRepo{
+		Repo:   repo,
+		cipher: cipher,
+	}
+}
+
+func (r *encryptedRepo) GetGuildConfigs(ctx context.Context) ([]GuildConfig, error) {
+	guildConfigs, err := r.Repo.GetGuildConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]GuildConfig, 0, len(guildConfigs))
+	for _, guildConfig := range guildConfigs {
+		decryptedConfig, err := transformSecrets(guildConfig, r.cipher.Decrypt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt guild config %s: %s", guildConfig.GuildId, err.Error())
+		}
+
+		decrypted = append(decrypted, decryptedConfig)
+	}
+
+	return decrypted, nil
+}
+
+func (r *encryptedRepo) GetGuildConfig(ctx context.Context, guildId string) (*GuildConfig, error) {
+	guildConfig, err := r.Repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := transformSecrets(*guildConfig, r.cipher.Decrypt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt guild config %s: %s", guildId, err.Error())
+	}
+
+	return &decrypted, nil
+}
+
+func (r *encryptedRepo) GetGuildConfigByReceiverTokenHash(ctx context.Context, tokenHash string) (*GuildConfig, error) {
+	guildConfig, err := r.Repo.GetGuildConfigByReceiverTokenHash(ctx, tokenHash)
+	if err != nil || guildConfig == nil {
+		return guildConfig, err
+	}
+
+	decrypted, err := transformSecrets(*guildConfig, r.cipher.Decrypt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt guild config %s: %s", guildConfig.GuildId, err.Error())
+	}
+
+	return &decrypted, nil
+}
+
+func (r *encryptedRepo) SetGuildConfig(ctx context.Context, config *GuildConfig) error {
+	encrypted, err := transformSecrets(*config, r.cipher.Encrypt)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt guild config %s: %s", config.GuildId, err.Error())
+	}
+
+	return r.Repo.SetGuildConfig(ctx, &encrypted)
+}
+
+// IsEncryptedWithCurrentKey returns true if each of the stored guild config's secrets were encrypted with the key the cipher encrypts with.
+func IsEncryptedWithCurrentKey(guildConfig GuildConfig, cipher Cipher) bool {
+	current := true
+	_, _ = transformSecrets(guildConfig, func(value string) (string, error) {
+		current = current && cipher.IsCurrent(value)
+		return value, nil
+	})
+
+	return current
+}
+
+// transformSecrets returns a copy of the guild config with the transform applied to each of its secrets.
+// The original guild config is left unchanged, as callers may still be using it.
+func transformSecrets(guildConfig GuildConfig, transform func(string) (string, error)) (GuildConfig, error) {
+	transformValue := func(value string) (string, error) {
+		if len(value) == 0 {
+			return value, nil
+		}
+
+		return transform(value)
+	}
+
+	scrapeConfigs := make([]ScrapeConfig, 0, len(guildConfig.ScrapeConfigs))
+	for _, scrapeConfig := range guildConfig.ScrapeConfigs {
+		password, err := transformValue(scrapeConfig.Password)
+		if err != nil {
+			return GuildConfig{}, fmt.Errorf("password for %s: %s", scrapeConfig.Name, err.Error())
+		}
+
+		scrapeConfig.Password = password
+
+		bearerToken, err := transformValue(scrapeConfig.BearerToken)
+		if err != nil {
+			return GuildConfig{}, fmt.Errorf("bearer token for %s: %s", scrapeConfig.Name, err.Error())
+		}
+
+		scrapeConfig.BearerToken = bearerToken
+
+		clientKey, err := transformValue(scrapeConfig.TLS.ClientKey)
+		if err != nil {
+			return GuildConfig{}, fmt.Errorf("client key for %s: %s", scrapeConfig.Name, err.Error())
+		}
+
+		scrapeConfig.TLS.ClientKey = clientKey
+
+		// Header names are kept as-is, since only their values may contain credentials
+		if scrapeConfig.Headers != nil {
+			headers := make(map[string]string, len(scrapeConfig.Headers))
+			for name, value := range scrapeConfig.Headers {
+				headers[name], err = transformValue(value)
+				if err != nil {
+					return GuildConfig{}, fmt.Errorf("header %s for %s: %s", name, scrapeConfig.Name, err.Error())
+				}
+			}
+
+			scrapeConfig.Headers = headers
+		}
+
+		var notifiers []NotifierConfig
+		for _, notifier := range scrapeConfig.Notifiers {
+			url, err := transformValue(notifier.Url)
+			if err != nil {
+				return GuildConfig{}, fmt.Errorf("url for notifier %s: %s", notifier.Id, err.Error())
+			}
+
+			notifier.Url = url
+			notifiers = append(notifiers, notifier)
+		}
+
+		scrapeConfig.Notifiers = notifiers
+		scrapeConfigs = append(scrapeConfigs, scrapeConfig)
+	}
+
+	guildConfig.ScrapeConfigs = scrapeConfigs
+	return guildConfig, nil
+}
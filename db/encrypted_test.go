@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/secrets"
+	"testing"
+)
+
+func newTestKey(t *testing.T) *secrets.Key {
+	encoded, err := secrets.GenerateKey()
+	assert.NoError(t, err)
+
+	key, err := secrets.ParseKey(encoded)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func prefixSecret(value string) (string, error) {
+	return "enc:" + value, nil
+}
+
+func TestEncryptedRepoEncryptsSecretsAtRest(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := SetupInMemoryDatabase(logger)
+	encryptedRepo := NewEncryptedRepo(repo, newTestKey(t))
+
+	guildId := "foo"
+	guildConfig := &GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []ScrapeConfig{
+			{
+				Name:      "bar",
+				Username:  "baz",
+				Password:  "hunter2",
+				Notifiers: []NotifierConfig{{Id: "qux", Type: SlackNotifier, Url: "https://hooks.slack.com/services/secret"}},
+			},
+			{
+				Name:        "quux",
+				BearerToken: "hunter3",
+				Headers:     map[string]string{"X-Api-Key": "hunter4"},
+			},
+		},
+	}
+
+	// Act
+	err := encryptedRepo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "hunter2", guildConfig.ScrapeConfigs[0].Password)
+
+	storedConfig, err := repo.GetGuildConfig(ctx, guildId)
+	assert.NoError(t, err)
+	assert.True(t, secrets.IsEncrypted(storedConfig.ScrapeConfigs[0].Password))
+	assert.True(t, secrets.IsEncrypted(storedConfig.ScrapeConfigs[0].Notifiers[0].Url))
+	assert.True(t, secrets.IsEncrypted(storedConfig.ScrapeConfigs[1].BearerToken))
+	assert.True(t, secrets.IsEncrypted(storedConfig.ScrapeConfigs[1].Headers["X-Api-Key"]))
+	assert.Equal(t, "baz", storedConfig.ScrapeConfigs[0].Username)
+
+	decryptedConfig, err := encryptedRepo.GetGuildConfig(ctx, guildId)
+	assert.NoError(t, err)
+	assert.Equal(t, guildConfig, decryptedConfig)
+}
+
+func TestTransformSecretsTransformsEverySecret(t *testing.T) {
+
+	// Arrange
+	guildConfig := GuildConfig{
+		GuildId: "foo",
+		ScrapeConfigs: []ScrapeConfig{
+			{
+				Name:        "bar",
+				Username:    "baz",
+				Password:    "hunter2",
+				BearerToken: "hunter3",
+				TLS:         TLSConfig{ClientKey: "hunter4"},
+				Headers:     map[string]string{"X-Api-Key": "hunter5", "X-Org-Id": "qux"},
+				Notifiers: []NotifierConfig{
+					{Id: "quux", Type: SlackNotifier, Url: "https://hooks.slack.com/services/secret"},
+					{Id: "corge", Type: WebhookNotifier, Url: "https://example.com/hook"},
+				},
+			},
+		},
+	}
+
+	// Act
+	transformedConfig, err := transformSecrets(guildConfig, prefixSecret)
+
+	// Assert
+	assert.NoError(t, err)
+
+	scrapeConfig := transformedConfig.ScrapeConfigs[0]
+	assert.Equal(t, "baz", scrapeConfig.Username)
+	assert.Equal(t, "enc:hunter2", scrapeConfig.Password)
+	assert.Equal(t, "enc:hunter3", scrapeConfig.BearerToken)
+	assert.Equal(t, "enc:hunter4", scrapeConfig.TLS.ClientKey)
+	assert.Equal(t, map[string]string{"X-Api-Key": "enc:hunter5", "X-Org-Id": "enc:qux"}, scrapeConfig.Headers)
+	assert.Equal(t, "enc:https://hooks.slack.com/services/secret", scrapeConfig.Notifiers[0].Url)
+	assert.Equal(t, "enc:https://example.com/hook", scrapeConfig.Notifiers[1].Url)
+	assert.Equal(t, "quux", scrapeConfig.Notifiers[0].Id)
+}
+
+func TestTransformSecretsLeavesOriginalUnchanged(t *testing.T) {
+
+	// Arrange
+	guildConfig := GuildConfig{
+		GuildId: "foo",
+		ScrapeConfigs: []ScrapeConfig{
+			{
+				Name:      "bar",
+				Password:  "hunter2",
+				Headers:   map[string]string{"X-Api-Key": "hunter3"},
+				Notifiers: []NotifierConfig{{Id: "baz", Type: SlackNotifier, Url: "https://hooks.slack.com/services/secret"}},
+			},
+		},
+	}
+
+	// Act
+	_, err := transformSecrets(guildConfig, prefixSecret)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", guildConfig.ScrapeConfigs[0].Password)
+	assert.Equal(t, "hunter3", guildConfig.ScrapeConfigs[0].Headers["X-Api-Key"])
+	assert.Equal(t, "https://hooks.slack.com/services/secret", guildConfig.ScrapeConfigs[0].Notifiers[0].Url)
+}
+
+func TestTransformSecretsSkipsEmptyValues(t *testing.T) {
+
+	// Arrange
+	guildConfig := GuildConfig{
+		GuildId: "foo",
+		ScrapeConfigs: []ScrapeConfig{
+			{
+				Name:      "bar",
+				Headers:   map[string]string{"X-Api-Key": ""},
+				Notifiers: []NotifierConfig{{Id: "baz", Type: SlackNotifier}},
+			},
+		},
+	}
+
+	// Act
+	transformedConfig, err := transformSecrets(guildConfig, prefixSecret)
+
+	// Assert
+	assert.NoError(t, err)
+
+	scrapeConfig := transformedConfig.ScrapeConfigs[0]
+	assert.Empty(t, scrapeConfig.Password)
+	assert.Empty(t, scrapeConfig.BearerToken)
+	assert.Empty(t, scrapeConfig.TLS.ClientKey)
+	assert.Equal(t, map[string]string{"X-Api-Key": ""}, scrapeConfig.Headers)
+	assert.Empty(t, scrapeConfig.Notifiers[0].Url)
+}
+
+func TestTransformSecretsReturnsErrorForHeader(t *testing.T) {
+
+	// Arrange
+	guildConfig := GuildConfig{
+		GuildId: "foo",
+		ScrapeConfigs: []ScrapeConfig{
+			{
+				Name:    "bar",
+				Headers: map[string]string{"X-Api-Key": "hunter2"},
+			},
+		},
+	}
+
+	// Act
+	_, err := transformSecrets(guildConfig, func(string) (string, error) {
+		return "", errors.New("baz")
+	})
+
+	// Assert
+	assert.EqualError(t, err, "header X-Api-Key for bar: baz")
+}
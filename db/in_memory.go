@@ -5,12 +5,22 @@ import (
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/yukitsune/minialert/slices"
+	"sort"
+	"sync"
+	"time"
 )
 
 func SetupInMemoryDatabase(logger logrus.FieldLogger) Repo {
 	repo := &inMemoryRepo{
 		registeredCommands: make([]CommandRegistration, 0),
 		guildConfigs:       make([]GuildConfig, 0),
+		silences:           make([]Silence, 0),
+		acknowledgements:   make([]Acknowledgement, 0),
+		alertMessages:      make([]AlertMessage, 0),
+		alertEvents:        make([]AlertEvent, 0),
+		scrapeStates:       make([]ScrapeState, 0),
+		leases:             make(map[string]Lease),
+		members:            make(map[string]Member),
 		logger:             logger,
 	}
 
@@ -18,12 +28,23 @@ func SetupInMemoryDatabase(logger logrus.FieldLogger) Repo {
 }
 
 type inMemoryRepo struct {
+	mu                 sync.RWMutex
 	registeredCommands []CommandRegistration
 	guildConfigs       []GuildConfig
+	silences           []Silence
+	acknowledgements   []Acknowledgement
+	alertMessages      []AlertMessage
+	alertEvents        []AlertEvent
+	scrapeStates       []ScrapeState
+	leases             map[string]Lease
+	members            map[string]Member
 	logger             logrus.FieldLogger
 }
 
 func (r *inMemoryRepo) RegisterCommand(_ context.Context, guildId string, commandId string, commandName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	reg := CommandRegistration{
 		GuildId:     guildId,
 		CommandId:   commandId,
@@ -37,6 +58,9 @@ func (r *inMemoryRepo) RegisterCommand(_ context.Context, guildId string, comman
 }
 
 func (r *inMemoryRepo) GetRegisteredCommands(_ context.Context, guildId string) ([]CommandRegistration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var commands []CommandRegistration
 	for _, command := range r.registeredCommands {
 		if command.GuildId == guildId {
@@ -48,10 +72,16 @@ func (r *inMemoryRepo) GetRegisteredCommands(_ context.Context, guildId string)
 }
 
 func (r *inMemoryRepo) GetGuildConfigs(_ context.Context) ([]GuildConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	return r.guildConfigs, nil
 }
 
 func (r *inMemoryRepo) GetGuildConfig(_ context.Context, guildId string) (*GuildConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, config := range r.guildConfigs {
 		if config.GuildId == guildId {
 			return &config, nil
@@ -61,9 +91,26 @@ func (r *inMemoryRepo) GetGuildConfig(_ context.Context, guildId string) (*Guild
 	return nil, fmt.Errorf("no config found for guild %s", guildId)
 }
 
+func (r *inMemoryRepo) GetGuildConfigByReceiverTokenHash(_ context.Context, tokenHash string) (*GuildConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, config := range r.guildConfigs {
+		for _, scrapeConfig := range config.ScrapeConfigs {
+			if scrapeConfig.ReceiverTokenHash == tokenHash {
+				return &config, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
 func (r *inMemoryRepo) SetGuildConfig(_ context.Context, config *GuildConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	r.logger.Debugf("Setting guild config: %+v", config)
+	r.logger.Debugf("Setting guild config: %+v", config.Redacted())
 
 	for i, cfg := range r.guildConfigs {
 		if cfg.GuildId == config.GuildId {
@@ -77,6 +124,9 @@ func (r *inMemoryRepo) SetGuildConfig(_ context.Context, config *GuildConfig) er
 }
 
 func (r *inMemoryRepo) ClearGuildInfo(_ context.Context, guildId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.registeredCommands = slices.RemoveMatches(r.registeredCommands, func(command CommandRegistration) bool {
 		return command.GuildId == guildId
 	})
@@ -85,5 +135,276 @@ func (r *inMemoryRepo) ClearGuildInfo(_ context.Context, guildId string) error {
 		return config.GuildId == guildId
 	})
 
+	r.silences = slices.RemoveMatches(r.silences, func(silence Silence) bool {
+		return silence.GuildId == guildId
+	})
+
+	r.acknowledgements = slices.RemoveMatches(r.acknowledgements, func(acknowledgement Acknowledgement) bool {
+		return acknowledgement.GuildId == guildId
+	})
+
+	r.alertMessages = slices.RemoveMatches(r.alertMessages, func(message AlertMessage) bool {
+		return message.GuildId == guildId
+	})
+
+	r.alertEvents = slices.RemoveMatches(r.alertEvents, func(event AlertEvent) bool {
+		return event.GuildId == guildId
+	})
+
+	r.scrapeStates = slices.RemoveMatches(r.scrapeStates, func(state ScrapeState) bool {
+		return state.GuildId == guildId
+	})
+
+	return nil
+}
+
+func (r *inMemoryRepo) AddSilence(_ context.Context, silence *Silence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logger.Debugf("Adding silence: %+v", silence)
+
+	r.silences = append(r.silences, *silence)
+	return nil
+}
+
+func (r *inMemoryRepo) GetSilences(_ context.Context, guildId string) ([]Silence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var silences []Silence
+	for _, silence := range r.silences {
+		if silence.GuildId == guildId {
+			silences = append(silences, silence)
+		}
+	}
+
+	return silences, nil
+}
+
+func (r *inMemoryRepo) ExpireSilence(_ context.Context, guildId string, silenceId string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, silence := range r.silences {
+		if silence.GuildId == guildId && silence.Id == silenceId {
+			r.silences[i].EndsAt = now
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no silence found with id %s", silenceId)
+}
+
+func (r *inMemoryRepo) DeleteExpiredSilences(_ context.Context, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.silences = slices.RemoveMatches(r.silences, func(silence Silence) bool {
+		return silence.IsExpired(now)
+	})
+
+	return nil
+}
+
+func (r *inMemoryRepo) AddAcknowledgement(_ context.Context, acknowledgement *Acknowledgement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logger.Debugf("Adding acknowledgement: %+v", acknowledgement)
+
+	r.acknowledgements = append(r.acknowledgements, *acknowledgement)
+	return nil
+}
+
+func (r *inMemoryRepo) GetAcknowledgements(_ context.Context, guildId string, configName string, fingerprint string) ([]Acknowledgement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var acknowledgements []Acknowledgement
+	for _, acknowledgement := range r.acknowledgements {
+		if acknowledgement.GuildId == guildId && acknowledgement.ScrapeConfigName == configName && acknowledgement.Fingerprint == fingerprint {
+			acknowledgements = append(acknowledgements, acknowledgement)
+		}
+	}
+
+	return acknowledgements, nil
+}
+
+func (r *inMemoryRepo) GetAlertMessage(_ context.Context, guildId string, configName string, groupKey string, channelId string) (*AlertMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, message := range r.alertMessages {
+		if message.isFor(guildId, configName, groupKey, channelId) {
+			return &message, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *inMemoryRepo) SetAlertMessage(_ context.Context, message *AlertMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.alertMessages {
+		if existing.isFor(message.GuildId, message.ScrapeConfigName, message.GroupKey, message.ChannelId) {
+			r.alertMessages[i] = *message
+			return nil
+		}
+	}
+
+	r.alertMessages = append(r.alertMessages, *message)
+	return nil
+}
+
+func (r *inMemoryRepo) DeleteAlertMessage(_ context.Context, guildId string, configName string, groupKey string, channelId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alertMessages = slices.RemoveMatches(r.alertMessages, func(message AlertMessage) bool {
+		return message.isFor(guildId, configName, groupKey, channelId)
+	})
+
+	return nil
+}
+
+func (r *inMemoryRepo) AddAlertEvents(_ context.Context, events []AlertEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alertEvents = append(r.alertEvents, events...)
+	return nil
+}
+
+func (r *inMemoryRepo) GetAlertEvents(_ context.Context, query AlertEventQuery) ([]AlertEvent, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []AlertEvent
+	for _, event := range r.alertEvents {
+		if query.matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+
+	total := len(events)
+	if query.Offset >= total {
+		return nil, total, nil
+	}
+
+	events = events[query.Offset:]
+	if query.Limit > 0 && len(events) > query.Limit {
+		events = events[:query.Limit]
+	}
+
+	return events, total, nil
+}
+
+func (r *inMemoryRepo) SetScrapeState(_ context.Context, state *ScrapeState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.scrapeStates {
+		if existing.isFor(state.GuildId, state.ScrapeConfigName) {
+			r.scrapeStates[i] = *state
+			return nil
+		}
+	}
+
+	r.scrapeStates = append(r.scrapeStates, *state)
+	return nil
+}
+
+func (r *inMemoryRepo) GetScrapeStates(_ context.Context, guildId string) ([]ScrapeState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Filter(r.scrapeStates, func(state ScrapeState) bool {
+		return state.GuildId == guildId
+	}), nil
+}
+
+func (r *inMemoryRepo) AcquireLease(_ context.Context, name string, holderId string, now time.Time, duration time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lease, ok := r.leases[name]
+	if ok && lease.HolderId != holderId && now.Before(lease.ExpiresAt) {
+		return false, nil
+	}
+
+	r.leases[name] = Lease{
+		Name:      name,
+		HolderId:  holderId,
+		ExpiresAt: now.Add(duration),
+	}
+
+	return true, nil
+}
+
+func (r *inMemoryRepo) ReleaseLease(_ context.Context, name string, holderId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[name]; ok && lease.HolderId == holderId {
+		delete(r.leases, name)
+	}
+
+	return nil
+}
+
+func (r *inMemoryRepo) GetLease(_ context.Context, name string) (*Lease, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lease, ok := r.leases[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return &lease, nil
+}
+
+func (r *inMemoryRepo) Heartbeat(_ context.Context, memberId string, now time.Time, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.members[memberId] = Member{
+		Id:        memberId,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	return nil
+}
+
+func (r *inMemoryRepo) GetMembers(_ context.Context, now time.Time) ([]Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var members []Member
+	for _, member := range r.members {
+		if now.Before(member.ExpiresAt) {
+			members = append(members, member)
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Id < members[j].Id
+	})
+
+	return members, nil
+}
+
+func (r *inMemoryRepo) RemoveMember(_ context.Context, memberId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.members, memberId)
 	return nil
 }
@@ -2,11 +2,13 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"github.com/yukitsune/minialert/config"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"sync/atomic"
 	"time"
 )
 
@@ -46,11 +48,18 @@ func SetupMongoDatabase(cfg config.Database) Repo {
 		return cb(ctx, db)
 	}
 
-	return &lazyMongoRepo{dbFunc}
+	return &lazyMongoRepo{dbFunc: dbFunc}
 }
 
 type lazyMongoRepo struct {
 	dbFunc Func
+
+	// leaseIndexCreated and memberIndexCreated are set once the TTL indexes on the leases and members collections have been created
+	leaseIndexCreated  int32
+	memberIndexCreated int32
+
+	// receiverTokenIndexCreated is set once the index on the scrape configs' receiver token hashes has been created
+	receiverTokenIndexCreated int32
 }
 
 func (r *lazyMongoRepo) RegisterCommand(ctx context.Context, guildId string, commandId string, commandName string) error {
@@ -151,6 +160,32 @@ func (r *lazyMongoRepo) GetGuildConfig(ctx context.Context, guildId string) (cfg
 	return cfg, err
 }
 
+func (r *lazyMongoRepo) GetGuildConfigByReceiverTokenHash(ctx context.Context, tokenHash string) (cfg *GuildConfig, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(GuildConfigCollection.String())
+
+		// Alerts are received far more often than scrape configs change, so the lookup shouldn't scan every guild config
+		err := ensureIndex(ctx, coll, &r.receiverTokenIndexCreated, mongo.IndexModel{
+			Keys: bson.D{{"scrape_configs.receiver_token_hash", 1}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create receiver token index: %s", err.Error())
+		}
+
+		filter := bson.D{{"scrape_configs.receiver_token_hash", tokenHash}}
+
+		res := coll.FindOne(ctx, filter)
+		if res.Err() == mongo.ErrNoDocuments {
+			return nil
+		}
+
+		cfg = &GuildConfig{}
+		return res.Decode(cfg)
+	})
+
+	return cfg, err
+}
+
 func (r *lazyMongoRepo) SetGuildConfig(ctx context.Context, config *GuildConfig) error {
 	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
 		coll := db.Collection(GuildConfigCollection.String())
@@ -176,6 +211,11 @@ func (r *lazyMongoRepo) ClearGuildInfo(ctx context.Context, guildId string) erro
 		collections := []CollectionName{
 			CommandRegistrationsCollection,
 			GuildConfigCollection,
+			SilencesCollection,
+			AcknowledgementsCollection,
+			AlertMessagesCollection,
+			AlertEventsCollection,
+			ScrapeStatesCollection,
 		}
 
 		for _, collection := range collections {
@@ -189,3 +229,378 @@ func (r *lazyMongoRepo) ClearGuildInfo(ctx context.Context, guildId string) erro
 		return nil
 	})
 }
+
+func (r *lazyMongoRepo) AddSilence(ctx context.Context, silence *Silence) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(SilencesCollection.String())
+
+		_, err := coll.InsertOne(ctx, silence)
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) GetSilences(ctx context.Context, guildId string) (silences []Silence, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(SilencesCollection.String())
+
+		filter := bson.D{{"guild_id", guildId}}
+
+		cur, err := coll.Find(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, &silences)
+	})
+
+	return silences, err
+}
+
+func (r *lazyMongoRepo) ExpireSilence(ctx context.Context, guildId string, silenceId string, now time.Time) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(SilencesCollection.String())
+
+		filter := bson.D{
+			{"guild_id", guildId},
+			{"silence_id", silenceId},
+		}
+
+		update := bson.M{"$set": bson.M{"ends_at": now}}
+
+		res, err := coll.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+
+		if res.MatchedCount == 0 {
+			return fmt.Errorf("no silence found with id %s", silenceId)
+		}
+
+		return nil
+	})
+}
+
+func (r *lazyMongoRepo) DeleteExpiredSilences(ctx context.Context, now time.Time) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(SilencesCollection.String())
+
+		// Silences without an end time are stored with a zero end time, and never expire
+		filter := bson.D{{"ends_at", bson.D{
+			{"$gt", time.Time{}},
+			{"$lte", now},
+		}}}
+
+		_, err := coll.DeleteMany(ctx, filter)
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) AddAcknowledgement(ctx context.Context, acknowledgement *Acknowledgement) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(AcknowledgementsCollection.String())
+
+		_, err := coll.InsertOne(ctx, acknowledgement)
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) GetAcknowledgements(ctx context.Context, guildId string, configName string, fingerprint string) (acknowledgements []Acknowledgement, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(AcknowledgementsCollection.String())
+
+		filter := bson.D{
+			{"guild_id", guildId},
+			{"scrape_name", configName},
+			{"fingerprint", fingerprint},
+		}
+
+		cur, err := coll.Find(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, &acknowledgements)
+	})
+
+	return acknowledgements, err
+}
+
+func (r *lazyMongoRepo) GetAlertMessage(ctx context.Context, guildId string, configName string, groupKey string, channelId string) (message *AlertMessage, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(AlertMessagesCollection.String())
+
+		res := coll.FindOne(ctx, alertMessageFilter(guildId, configName, groupKey, channelId))
+		if res.Err() == mongo.ErrNoDocuments {
+			return nil
+		}
+
+		message = &AlertMessage{}
+		return res.Decode(message)
+	})
+
+	return message, err
+}
+
+func (r *lazyMongoRepo) SetAlertMessage(ctx context.Context, message *AlertMessage) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(AlertMessagesCollection.String())
+
+		filter := alertMessageFilter(message.GuildId, message.ScrapeConfigName, message.GroupKey, message.ChannelId)
+		upsert := bson.M{"$set": message}
+		upsertOpts := options.Update().SetUpsert(true)
+
+		_, err := coll.UpdateOne(ctx, filter, upsert, upsertOpts)
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) DeleteAlertMessage(ctx context.Context, guildId string, configName string, groupKey string, channelId string) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(AlertMessagesCollection.String())
+
+		_, err := coll.DeleteOne(ctx, alertMessageFilter(guildId, configName, groupKey, channelId))
+		return err
+	})
+}
+
+func alertMessageFilter(guildId string, configName string, groupKey string, channelId string) bson.D {
+	return bson.D{
+		{"guild_id", guildId},
+		{"scrape_name", configName},
+		{"group_key", groupKey},
+		{"channel_id", channelId},
+	}
+}
+
+func (r *lazyMongoRepo) AddAlertEvents(ctx context.Context, events []AlertEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(AlertEventsCollection.String())
+
+		docs := make([]interface{}, 0, len(events))
+		for _, event := range events {
+			docs = append(docs, event)
+		}
+
+		_, err := coll.InsertMany(ctx, docs)
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) GetAlertEvents(ctx context.Context, query AlertEventQuery) (events []AlertEvent, total int, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(AlertEventsCollection.String())
+
+		filter := bson.D{{"guild_id", query.GuildId}}
+		if len(query.ScrapeConfigName) > 0 {
+			filter = append(filter, bson.E{"scrape_name", query.ScrapeConfigName})
+		}
+
+		for k, v := range query.Labels {
+			filter = append(filter, bson.E{fmt.Sprintf("labels.%s", k), v})
+		}
+
+		timestampFilter := bson.D{}
+		if !query.Since.IsZero() {
+			timestampFilter = append(timestampFilter, bson.E{"$gte", query.Since})
+		}
+
+		if !query.Until.IsZero() {
+			timestampFilter = append(timestampFilter, bson.E{"$lt", query.Until})
+		}
+
+		if len(timestampFilter) > 0 {
+			filter = append(filter, bson.E{"timestamp", timestampFilter})
+		}
+
+		count, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		total = int(count)
+
+		findOpts := options.Find().
+			SetSort(bson.D{{"timestamp", -1}}).
+			SetSkip(int64(query.Offset))
+		if query.Limit > 0 {
+			findOpts.SetLimit(int64(query.Limit))
+		}
+
+		cur, err := coll.Find(ctx, filter, findOpts)
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, &events)
+	})
+
+	return events, total, err
+}
+
+func (r *lazyMongoRepo) SetScrapeState(ctx context.Context, state *ScrapeState) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(ScrapeStatesCollection.String())
+
+		filter := bson.D{{"guild_id", state.GuildId}, {"scrape_name", state.ScrapeConfigName}}
+		upsert := bson.M{"$set": state}
+		upsertOpts := options.Update().SetUpsert(true)
+
+		_, err := coll.UpdateOne(ctx, filter, upsert, upsertOpts)
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) GetScrapeStates(ctx context.Context, guildId string) (states []ScrapeState, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(ScrapeStatesCollection.String())
+
+		filter := bson.D{{"guild_id", guildId}}
+
+		cur, err := coll.Find(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, &states)
+	})
+
+	return states, err
+}
+
+func (r *lazyMongoRepo) AcquireLease(ctx context.Context, name string, holderId string, now time.Time, duration time.Duration) (bool, error) {
+	acquired := false
+	err := r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(LeasesCollection.String())
+
+		// Expired leases are deleted by MongoDB eventually, though they can be acquired as soon as they've expired
+		err := ensureExpiryIndex(ctx, coll, &r.leaseIndexCreated)
+		if err != nil {
+			return fmt.Errorf("failed to create lease index: %s", err.Error())
+		}
+
+		// The lease can only be taken over once it has expired.
+		// If it's held by someone else, the upsert attempts to insert a new lease with the same name, which fails.
+		filter := bson.D{
+			{"_id", name},
+			{"$or", bson.A{
+				bson.D{{"holder_id", holderId}},
+				bson.D{{"expires_at", bson.D{{"$lte", now}}}},
+			}},
+		}
+
+		update := bson.M{"$set": bson.M{
+			"holder_id":  holderId,
+			"expires_at": now.Add(duration),
+		}}
+
+		_, err = coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		acquired = true
+		return nil
+	})
+
+	return acquired, err
+}
+
+func (r *lazyMongoRepo) ReleaseLease(ctx context.Context, name string, holderId string) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(LeasesCollection.String())
+
+		_, err := coll.DeleteOne(ctx, bson.D{
+			{"_id", name},
+			{"holder_id", holderId},
+		})
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) GetLease(ctx context.Context, name string) (lease *Lease, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(LeasesCollection.String())
+
+		res := coll.FindOne(ctx, bson.D{{"_id", name}})
+		if res.Err() == mongo.ErrNoDocuments {
+			return nil
+		}
+
+		lease = &Lease{}
+		return res.Decode(lease)
+	})
+
+	return lease, err
+}
+
+func (r *lazyMongoRepo) Heartbeat(ctx context.Context, memberId string, now time.Time, ttl time.Duration) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(MembersCollection.String())
+
+		err := ensureExpiryIndex(ctx, coll, &r.memberIndexCreated)
+		if err != nil {
+			return fmt.Errorf("failed to create member index: %s", err.Error())
+		}
+
+		update := bson.M{"$set": bson.M{"expires_at": now.Add(ttl)}}
+		_, err = coll.UpdateByID(ctx, memberId, update, options.Update().SetUpsert(true))
+		return err
+	})
+}
+
+func (r *lazyMongoRepo) GetMembers(ctx context.Context, now time.Time) (members []Member, err error) {
+	err = r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(MembersCollection.String())
+
+		// Expired members may not have been deleted yet
+		filter := bson.D{{"expires_at", bson.D{{"$gt", now}}}}
+		cur, err := coll.Find(ctx, filter, options.Find().SetSort(bson.D{{"_id", 1}}))
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, &members)
+	})
+
+	return members, err
+}
+
+func (r *lazyMongoRepo) RemoveMember(ctx context.Context, memberId string) error {
+	return r.dbFunc(ctx, func(ctx context.Context, db *mongo.Database) error {
+		coll := db.Collection(MembersCollection.String())
+
+		_, err := coll.DeleteOne(ctx, bson.D{{"_id", memberId}})
+		return err
+	})
+}
+
+// ensureExpiryIndex creates a TTL index which deletes documents once their expires_at time has passed, unless it has already been created.
+func ensureExpiryIndex(ctx context.Context, coll *mongo.Collection, created *int32) error {
+	return ensureIndex(ctx, coll, created, mongo.IndexModel{
+		Keys:    bson.D{{"expires_at", 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+}
+
+// ensureIndex creates the index, unless it has already been created.
+func ensureIndex(ctx context.Context, coll *mongo.Collection, created *int32, model mongo.IndexModel) error {
+	if atomic.LoadInt32(created) == 1 {
+		return nil
+	}
+
+	_, err := coll.Indexes().CreateOne(ctx, model)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(created, 1)
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 )
 
 const databaseName string = "minialert_test"
@@ -200,13 +201,13 @@ func TestSetGuildConfigAddsScrapeConfigs(t *testing.T) {
 	scrapeConfigName := "My scrape config"
 	inhibitedAlertName := "test_alert"
 	scrapeConfig := ScrapeConfig{
-		Name:                  scrapeConfigName,
-		Endpoint:              "http://localhost:1234",
-		Username:              "foo",
-		Password:              "bar",
-		ScrapeIntervalMinutes: 1,
-		AlertChannelId:        "123",
-		InhibitedAlerts:       []string{inhibitedAlertName},
+		Name:            scrapeConfigName,
+		Endpoint:        "http://localhost:1234",
+		Username:        "foo",
+		Password:        "bar",
+		ScrapeInterval:  "1m0s",
+		AlertChannelId:  "123",
+		InhibitedAlerts: []string{inhibitedAlertName},
 	}
 
 	guildConfig.ScrapeConfigs = append(guildConfig.ScrapeConfigs, scrapeConfig)
@@ -240,13 +241,13 @@ func TestSetGuildConfigUpdatesScrapeConfigs(t *testing.T) {
 	scrapeConfigName := "My scrape config"
 	inhibitedAlertName := "test_alert"
 	scrapeConfig := ScrapeConfig{
-		Name:                  scrapeConfigName,
-		Endpoint:              "http://localhost:1234",
-		Username:              "foo",
-		Password:              "bar",
-		ScrapeIntervalMinutes: 1,
-		AlertChannelId:        "123",
-		InhibitedAlerts:       []string{inhibitedAlertName},
+		Name:            scrapeConfigName,
+		Endpoint:        "http://localhost:1234",
+		Username:        "foo",
+		Password:        "bar",
+		ScrapeInterval:  "1m0s",
+		AlertChannelId:  "123",
+		InhibitedAlerts: []string{inhibitedAlertName},
 	}
 
 	guildConfig := &GuildConfig{
@@ -347,6 +348,33 @@ func TestGetGuildConfigs(t *testing.T) {
 	assert.True(t, hasGuild2)
 }
 
+func TestGetGuildConfigByReceiverTokenHash(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+
+	guildConfig := &GuildConfig{
+		GuildId: "foo",
+		ScrapeConfigs: []ScrapeConfig{
+			{Name: "foo"},
+			{Name: "bar", ReceiverTokenHash: "hash"},
+		},
+	}
+
+	err := mongoRepo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	foundGuildConfig, err := mongoRepo.GetGuildConfigByReceiverTokenHash(ctx, "hash")
+	assert.NoError(t, err)
+
+	missingGuildConfig, err := mongoRepo.GetGuildConfigByReceiverTokenHash(ctx, "other-hash")
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "foo", foundGuildConfig.GuildId)
+	assert.Nil(t, missingGuildConfig)
+}
+
 func TestClearGuildInfo(t *testing.T) {
 	// Arrange
 	ctx := context.Background()
@@ -379,3 +407,294 @@ func TestClearGuildInfo(t *testing.T) {
 	configCount, err := configColl.CountDocuments(ctx, bson.D{{"guild_id", guildId}})
 	assert.Equal(t, int64(0), configCount)
 }
+
+func TestAddSilence(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	guildId := "foo"
+	silence := &Silence{
+		Id:               NewId(),
+		GuildId:          guildId,
+		ScrapeConfigName: "bar",
+		Matchers:         []Matcher{{Name: "alertname", Type: MatchEqual, Value: "baz"}},
+		StartsAt:         time.Now(),
+		EndsAt:           time.Now().Add(time.Hour),
+	}
+
+	// Act
+	err := mongoRepo.AddSilence(ctx, silence)
+	assert.NoError(t, err)
+
+	// Assert
+	silences, err := mongoRepo.GetSilences(ctx, guildId)
+	assert.NoError(t, err)
+
+	hasSilence := slices.HasMatching(silences, func(s Silence) bool {
+		return s.Id == silence.Id && len(s.Matchers) == 1 && s.Matchers[0] == silence.Matchers[0]
+	})
+
+	assert.True(t, hasSilence)
+}
+
+func TestExpireSilence(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	guildId := "foo"
+	silence := &Silence{
+		Id:       NewId(),
+		GuildId:  guildId,
+		StartsAt: time.Now(),
+	}
+
+	err := mongoRepo.AddSilence(ctx, silence)
+	assert.NoError(t, err)
+
+	// Act
+	now := time.Now()
+	err = mongoRepo.ExpireSilence(ctx, guildId, silence.Id, now)
+	assert.NoError(t, err)
+
+	// Assert
+	silences, err := mongoRepo.GetSilences(ctx, guildId)
+	assert.NoError(t, err)
+
+	expiredSilence, ok := slices.FindMatching(silences, func(s Silence) bool {
+		return s.Id == silence.Id
+	})
+
+	assert.True(t, ok)
+	assert.True(t, expiredSilence.IsExpired(now))
+}
+
+func TestDeleteExpiredSilences(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	guildId := "foo"
+	now := time.Now()
+
+	expiredSilence := &Silence{
+		Id:       NewId(),
+		GuildId:  guildId,
+		StartsAt: now.Add(-2 * time.Hour),
+		EndsAt:   now.Add(-time.Hour),
+	}
+
+	permanentSilence := &Silence{
+		Id:       NewId(),
+		GuildId:  guildId,
+		StartsAt: now.Add(-2 * time.Hour),
+	}
+
+	err := mongoRepo.AddSilence(ctx, expiredSilence)
+	assert.NoError(t, err)
+
+	err = mongoRepo.AddSilence(ctx, permanentSilence)
+	assert.NoError(t, err)
+
+	// Act
+	err = mongoRepo.DeleteExpiredSilences(ctx, now)
+	assert.NoError(t, err)
+
+	// Assert
+	silences, err := mongoRepo.GetSilences(ctx, guildId)
+	assert.NoError(t, err)
+
+	hasExpiredSilence := slices.HasMatching(silences, func(s Silence) bool {
+		return s.Id == expiredSilence.Id
+	})
+	assert.False(t, hasExpiredSilence)
+
+	hasPermanentSilence := slices.HasMatching(silences, func(s Silence) bool {
+		return s.Id == permanentSilence.Id
+	})
+	assert.True(t, hasPermanentSilence)
+}
+
+func TestAddAcknowledgement(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	acknowledgement := &Acknowledgement{
+		Id:               NewId(),
+		GuildId:          "foo",
+		ScrapeConfigName: "bar",
+		Fingerprint:      "baz",
+		AcknowledgedBy:   "qux",
+		AcknowledgedAt:   time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	// Act
+	err := mongoRepo.AddAcknowledgement(ctx, acknowledgement)
+	assert.NoError(t, err)
+
+	// Assert
+	acknowledgements, err := mongoRepo.GetAcknowledgements(ctx, acknowledgement.GuildId, acknowledgement.ScrapeConfigName, acknowledgement.Fingerprint)
+	assert.NoError(t, err)
+	assert.Len(t, acknowledgements, 1)
+	assert.Equal(t, *acknowledgement, acknowledgements[0])
+}
+
+func TestSetAlertMessageReplacesExistingMessage(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	message := &AlertMessage{
+		GuildId:          "foo",
+		ScrapeConfigName: "bar",
+		GroupKey:         "baz",
+		ChannelId:        "qux",
+		MessageId:        "1",
+		CreatedAt:        time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	err := mongoRepo.SetAlertMessage(ctx, message)
+	assert.NoError(t, err)
+
+	// Act
+	message.MessageId = "2"
+	err = mongoRepo.SetAlertMessage(ctx, message)
+	assert.NoError(t, err)
+
+	// Assert
+	storedMessage, err := mongoRepo.GetAlertMessage(ctx, message.GuildId, message.ScrapeConfigName, message.GroupKey, message.ChannelId)
+	assert.NoError(t, err)
+	assert.Equal(t, message, storedMessage)
+}
+
+func TestDeleteAlertMessage(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	message := &AlertMessage{
+		GuildId:          "foo",
+		ScrapeConfigName: "bar",
+		GroupKey:         "baz",
+		ChannelId:        "quux",
+		MessageId:        "1",
+	}
+
+	err := mongoRepo.SetAlertMessage(ctx, message)
+	assert.NoError(t, err)
+
+	// Act
+	err = mongoRepo.DeleteAlertMessage(ctx, message.GuildId, message.ScrapeConfigName, message.GroupKey, message.ChannelId)
+	assert.NoError(t, err)
+
+	// Assert
+	storedMessage, err := mongoRepo.GetAlertMessage(ctx, message.GuildId, message.ScrapeConfigName, message.GroupKey, message.ChannelId)
+	assert.NoError(t, err)
+	assert.Nil(t, storedMessage)
+}
+
+func TestGetAlertEventsFiltersAndPaginates(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	guildId := "alert-events"
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	var events []AlertEvent
+	for i := 0; i < 5; i++ {
+		events = append(events, AlertEvent{
+			Id:               NewId(),
+			GuildId:          guildId,
+			ScrapeConfigName: "foo",
+			Status:           AlertFiring,
+			Fingerprint:      "bar",
+			Labels:           map[string]string{"alertname": "Foo", "severity": "critical"},
+			Annotations:      map[string]string{},
+			FiringSince:      now,
+			Timestamp:        now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	events = append(events, AlertEvent{
+		Id:               NewId(),
+		GuildId:          guildId,
+		ScrapeConfigName: "foo",
+		Status:           AlertFiring,
+		Fingerprint:      "baz",
+		Labels:           map[string]string{"alertname": "Bar", "severity": "critical"},
+		Annotations:      map[string]string{},
+		FiringSince:      now,
+		Timestamp:        now,
+	})
+
+	err := mongoRepo.AddAlertEvents(ctx, events)
+	assert.NoError(t, err)
+
+	// Act
+	storedEvents, total, err := mongoRepo.GetAlertEvents(ctx, AlertEventQuery{
+		GuildId:          guildId,
+		ScrapeConfigName: "foo",
+		Labels:           map[string]string{"alertname": "Foo"},
+		Since:            now.Add(time.Minute),
+		Offset:           1,
+		Limit:            2,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []AlertEvent{events[3], events[2]}, storedEvents)
+}
+
+func TestAcquireLeaseOnlyTakesOverExpiredLeases(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	name := "leader"
+	now := time.Now()
+
+	acquired, err := mongoRepo.AcquireLease(ctx, name, "foo", now, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Act
+	renewed, err := mongoRepo.AcquireLease(ctx, name, "foo", now.Add(time.Second), time.Minute)
+	assert.NoError(t, err)
+
+	stolen, err := mongoRepo.AcquireLease(ctx, name, "bar", now.Add(time.Second), time.Minute)
+	assert.NoError(t, err)
+
+	takenOver, err := mongoRepo.AcquireLease(ctx, name, "bar", now.Add(2*time.Minute), time.Minute)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.True(t, renewed)
+	assert.False(t, stolen)
+	assert.True(t, takenOver)
+
+	lease, err := mongoRepo.GetLease(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", lease.HolderId)
+
+	err = mongoRepo.ReleaseLease(ctx, name, "bar")
+	assert.NoError(t, err)
+
+	lease, err = mongoRepo.GetLease(ctx, name)
+	assert.NoError(t, err)
+	assert.Nil(t, lease)
+}
+
+func TestGetMembersOnlyReturnsMembersWhichHaventExpired(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	now := time.Now()
+
+	err := mongoRepo.Heartbeat(ctx, "foo", now, time.Minute)
+	assert.NoError(t, err)
+
+	err = mongoRepo.Heartbeat(ctx, "bar", now.Add(-2*time.Minute), time.Minute)
+	assert.NoError(t, err)
+
+	err = mongoRepo.Heartbeat(ctx, "baz", now, time.Minute)
+	assert.NoError(t, err)
+
+	err = mongoRepo.RemoveMember(ctx, "baz")
+	assert.NoError(t, err)
+
+	// Act
+	members, err := mongoRepo.GetMembers(ctx, now)
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "foo", members[0].Id)
+	}
+}
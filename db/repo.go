@@ -2,7 +2,11 @@ package db
 
 import (
 	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"os"
+	"time"
 )
 
 type CollectionName string
@@ -10,6 +14,13 @@ type CollectionName string
 const (
 	CommandRegistrationsCollection CollectionName = "command_registrations"
 	GuildConfigCollection          CollectionName = "guild_config"
+	SilencesCollection             CollectionName = "silences"
+	AcknowledgementsCollection     CollectionName = "acknowledgements"
+	AlertMessagesCollection        CollectionName = "alert_messages"
+	AlertEventsCollection          CollectionName = "alert_events"
+	LeasesCollection               CollectionName = "leases"
+	MembersCollection              CollectionName = "members"
+	ScrapeStatesCollection         CollectionName = "scrape_states"
 )
 
 func (c CollectionName) String() string {
@@ -19,6 +30,62 @@ func (c CollectionName) String() string {
 type GuildConfig struct {
 	GuildId       string         `bson:"guild_id"`
 	ScrapeConfigs []ScrapeConfig `bson:"scrape_configs"`
+
+	// RolePermissions is the permission level granted to each role. Each role has at most one level.
+	RolePermissions []RolePermission `bson:"role_permissions"`
+
+	// MinScrapeInterval is the shortest scrape interval allowed for the guild's scrape configs, as a Go duration string.
+	// The global minimum still applies if it's longer.
+	MinScrapeInterval string `bson:"min_scrape_interval"`
+}
+
+// Redacted returns a copy of the guild config which is safe to log.
+func (c GuildConfig) Redacted() GuildConfig {
+	scrapeConfigs := make([]ScrapeConfig, 0, len(c.ScrapeConfigs))
+	for _, scrapeConfig := range c.ScrapeConfigs {
+		scrapeConfigs = append(scrapeConfigs, scrapeConfig.Redacted())
+	}
+
+	c.ScrapeConfigs = scrapeConfigs
+	return c
+}
+
+// PermissionLevel determines which commands a member can use. Each level includes the levels below it.
+type PermissionLevel string
+
+const (
+	ReadPermission    PermissionLevel = "read"
+	SilencePermission PermissionLevel = "silence"
+	ManagePermission  PermissionLevel = "manage"
+
+	// AdminPermission is only held by members with the Administrator or Manage Server permission, and can't be granted.
+	AdminPermission PermissionLevel = "admin"
+)
+
+var permissionRanks = map[PermissionLevel]int{
+	ReadPermission:    1,
+	SilencePermission: 2,
+	ManagePermission:  3,
+	AdminPermission:   4,
+}
+
+func (l PermissionLevel) String() string {
+	return string(l)
+}
+
+func (l PermissionLevel) IsValid() bool {
+	_, ok := permissionRanks[l]
+	return ok
+}
+
+// Includes returns true if the level grants everything the other level does.
+func (l PermissionLevel) Includes(other PermissionLevel) bool {
+	return l.IsValid() && permissionRanks[l] >= permissionRanks[other]
+}
+
+type RolePermission struct {
+	RoleId string          `bson:"role_id"`
+	Level  PermissionLevel `bson:"level"`
 }
 
 func NewGuildConfig(guildId string) *GuildConfig {
@@ -29,13 +96,407 @@ func NewGuildConfig(guildId string) *GuildConfig {
 }
 
 type ScrapeConfig struct {
-	Name                  string   `bson:"scrape_name"`
-	Endpoint              string   `bson:"endpoint"`
-	Username              string   `bson:"username"`
-	Password              string   `bson:"password"`
-	ScrapeIntervalMinutes int64    `bson:"scrape_interval_minutes"`
-	AlertChannelId        string   `bson:"alert_channel_id"`
-	InhibitedAlerts       []string `bson:"inhibited_alerts"`
+	Name     string `bson:"scrape_name"`
+	Endpoint string `bson:"endpoint"`
+	Username string `bson:"username"`
+	Password string `bson:"password"`
+
+	// ScrapeInterval is how often the endpoint is scraped, as a Go duration string, E.g: "30s" or "2m30s".
+	ScrapeInterval string `bson:"scrape_interval"`
+
+	// ScrapeTimeout is how long to wait for the endpoint to respond, as a Go duration string. The global timeout is used when empty.
+	ScrapeTimeout string `bson:"scrape_timeout"`
+
+	// Deprecated: ScrapeIntervalMinutes has been superseded by ScrapeInterval, and is only kept so that existing configs can be migrated.
+	ScrapeIntervalMinutes int64 `bson:"scrape_interval_minutes,omitempty"`
+
+	AlertChannelId string `bson:"alert_channel_id"`
+
+	// SourceType determines which API the endpoint serves. Defaults to PrometheusSource when empty.
+	SourceType SourceType `bson:"source_type"`
+
+	// TenantId is sent in the X-Scope-OrgID header, as required by multi-tenant Mimir and Cortex clusters.
+	TenantId string `bson:"tenant_id"`
+
+	// BearerToken is sent in the Authorization header instead of the username and password.
+	BearerToken string `bson:"bearer_token"`
+
+	// Headers are added to each request sent to the endpoint, E.g: X-Scope-OrgID for Mimir tenants.
+	Headers map[string]string `bson:"headers"`
+
+	TLS TLSConfig `bson:"tls"`
+
+	// Deprecated: InhibitedAlerts has been superseded by Silence, and is only kept so that existing configs can be migrated.
+	InhibitedAlerts []string `bson:"inhibited_alerts"`
+
+	// GroupBy is the list of label names used to group alerts into a single notification.
+	// Use "..." to group by all labels, effectively disabling grouping.
+	GroupBy               []string `bson:"group_by"`
+	GroupWaitSeconds      int64    `bson:"group_wait_seconds"`
+	GroupIntervalSeconds  int64    `bson:"group_interval_seconds"`
+	RepeatIntervalSeconds int64    `bson:"repeat_interval_seconds"`
+
+	InhibitionRules []InhibitionRule `bson:"inhibition_rules"`
+
+	// Routes determine which channels alerts are sent to.
+	// Alerts which don't match any routes are sent to the AlertChannelId.
+	Routes []Route `bson:"routes"`
+
+	// ReceiverTokenHash is the SHA-256 hash of the token used to push alerts to the receiver for this scrape config.
+	// When set, alerts are received from Prometheus rather than scraped.
+	ReceiverTokenHash string `bson:"receiver_token_hash"`
+
+	Notifiers []NotifierConfig `bson:"notifiers"`
+
+	// EscalationSteps are used to mention roles or users when critical alerts haven't been acknowledged.
+	EscalationSteps []EscalationStep `bson:"escalation_steps"`
+
+	// HeartbeatAlertName is the name of an alert which should always be firing, such as Prometheus' Watchdog alert.
+	// If it stops firing for longer than HeartbeatTimeoutSeconds, the alerting pipeline is considered broken.
+	HeartbeatAlertName      string `bson:"heartbeat_alert_name"`
+	HeartbeatTimeoutSeconds int64  `bson:"heartbeat_timeout_seconds"`
+}
+
+// SourceType is the kind of API alerts are scraped from.
+type SourceType string
+
+const (
+	// PrometheusSource scrapes the Prometheus /api/v1/alerts API.
+	PrometheusSource SourceType = "prometheus"
+
+	// PrometheusRulesSource scrapes the Prometheus /api/v1/rules API, which includes the rule group each alert belongs to.
+	PrometheusRulesSource SourceType = "prometheus-rules"
+
+	// AlertmanagerSource scrapes the Alertmanager /api/v2/alerts API.
+	AlertmanagerSource SourceType = "alertmanager"
+
+	// MimirSource scrapes the Mimir or Cortex ruler's Prometheus-compatible rules API.
+	MimirSource SourceType = "mimir"
+)
+
+func (t SourceType) String() string {
+	return string(t)
+}
+
+// TLSConfig contains the PEM encoded certificates used to connect to a scrape config's endpoint.
+type TLSConfig struct {
+	CaCert     string `bson:"ca_cert"`
+	ClientCert string `bson:"client_cert"`
+	ClientKey  string `bson:"client_key"`
+
+	// InsecureSkipVerify overrides the default when set.
+	InsecureSkipVerify *bool `bson:"insecure_skip_verify,omitempty"`
+}
+
+// Interval returns the parsed ScrapeInterval, falling back to ScrapeIntervalMinutes for configs which haven't been migrated yet.
+// Returns zero if neither are set.
+func (c *ScrapeConfig) Interval() time.Duration {
+	if len(c.ScrapeInterval) == 0 {
+		return time.Duration(c.ScrapeIntervalMinutes) * time.Minute
+	}
+
+	interval, err := time.ParseDuration(c.ScrapeInterval)
+	if err != nil {
+		return 0
+	}
+
+	return interval
+}
+
+// Timeout returns the parsed ScrapeTimeout, or zero if it isn't set.
+func (c *ScrapeConfig) Timeout() time.Duration {
+	timeout, err := time.ParseDuration(c.ScrapeTimeout)
+	if err != nil {
+		return 0
+	}
+
+	return timeout
+}
+
+func (c *ScrapeConfig) ReceiverEnabled() bool {
+	return len(c.ReceiverTokenHash) > 0
+}
+
+// redacted replaces secrets in values which are logged.
+const redacted = "<redacted>"
+
+// Redacted returns a copy of the scrape config with its secrets removed, so that it's safe to log.
+func (c ScrapeConfig) Redacted() ScrapeConfig {
+	if len(c.Password) > 0 {
+		c.Password = redacted
+	}
+
+	if len(c.BearerToken) > 0 {
+		c.BearerToken = redacted
+	}
+
+	if len(c.TLS.ClientKey) > 0 {
+		c.TLS.ClientKey = redacted
+	}
+
+	// Headers may contain credentials too, so only their names are kept
+	if len(c.Headers) > 0 {
+		headers := make(map[string]string, len(c.Headers))
+		for name := range c.Headers {
+			headers[name] = redacted
+		}
+
+		c.Headers = headers
+	}
+
+	if len(c.ReceiverTokenHash) > 0 {
+		c.ReceiverTokenHash = redacted
+	}
+
+	// Webhook URLs often contain tokens
+	notifiers := make([]NotifierConfig, 0, len(c.Notifiers))
+	for _, notifier := range c.Notifiers {
+		if len(notifier.Url) > 0 {
+			notifier.Url = redacted
+		}
+
+		notifiers = append(notifiers, notifier)
+	}
+
+	c.Notifiers = notifiers
+	return c
+}
+
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+func (t MatchType) String() string {
+	return string(t)
+}
+
+type Matcher struct {
+	Name  string    `bson:"name"`
+	Type  MatchType `bson:"type"`
+	Value string    `bson:"value"`
+}
+
+func (m Matcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// Silence prevents notifications from being sent for any alerts matching all of its matchers.
+type Silence struct {
+	Id               string    `bson:"silence_id"`
+	GuildId          string    `bson:"guild_id"`
+	ScrapeConfigName string    `bson:"scrape_config_name"`
+	Matchers         []Matcher `bson:"matchers"`
+	CreatedBy        string    `bson:"created_by"`
+	Comment          string    `bson:"comment"`
+	StartsAt         time.Time `bson:"starts_at"`
+
+	// EndsAt is the time at which the silence expires.
+	// A zero value means the silence never expires, and needs to be expired manually.
+	EndsAt time.Time `bson:"ends_at"`
+
+	// Inhibition is set for silences created by inhibiting an alert, which are listed and removed separately from other silences.
+	Inhibition bool `bson:"inhibition"`
+}
+
+func (s Silence) IsActive(now time.Time) bool {
+	return !now.Before(s.StartsAt) && !s.IsExpired(now)
+}
+
+func (s Silence) IsExpired(now time.Time) bool {
+	return !s.EndsAt.IsZero() && !now.Before(s.EndsAt)
+}
+
+// InhibitionRule mutes any alerts matching the target matchers while an alert matching the source matchers is firing.
+// Both alerts must have the same values for each of the labels in Equal.
+type InhibitionRule struct {
+	Id             string    `bson:"inhibition_rule_id"`
+	SourceMatchers []Matcher `bson:"source_matchers"`
+	TargetMatchers []Matcher `bson:"target_matchers"`
+	Equal          []string  `bson:"equal"`
+}
+
+// Route sends any alerts matching all of its matchers to a specific channel.
+type Route struct {
+	Id       string    `bson:"route_id"`
+	Matchers []Matcher `bson:"matchers"`
+
+	// ChannelId is the channel to send alerts to.
+	// If empty, the parent route's channel is used.
+	ChannelId string `bson:"channel_id"`
+
+	// Continue determines whether the following sibling routes are still evaluated after this route has matched.
+	Continue bool    `bson:"continue"`
+	Routes   []Route `bson:"routes"`
+}
+
+type NotifierType string
+
+const (
+	WebhookNotifier NotifierType = "webhook"
+	SlackNotifier   NotifierType = "slack"
+	EmailNotifier   NotifierType = "email"
+	FileNotifier    NotifierType = "file"
+)
+
+func (t NotifierType) String() string {
+	return string(t)
+}
+
+// NotifierConfig describes an additional destination for notifications, alongside the scrape config's Discord channels.
+type NotifierConfig struct {
+	Id   string       `bson:"notifier_id"`
+	Type NotifierType `bson:"type"`
+
+	// Url is the URL to send notifications to, used by webhook and slack notifiers.
+	Url string `bson:"url"`
+
+	// To is the list of email addresses to send notifications to, used by email notifiers.
+	To []string `bson:"to"`
+}
+
+// EscalationStep mentions a role or user if critical alerts haven't been acknowledged within DelayMinutes of first being notified.
+type EscalationStep struct {
+	Id           string `bson:"escalation_step_id"`
+	DelayMinutes int64  `bson:"delay_minutes"`
+	RoleId       string `bson:"role_id"`
+	UserId       string `bson:"user_id"`
+}
+
+// Acknowledgement records that someone has acknowledged a group of alerts, or a single alert.
+type Acknowledgement struct {
+	Id               string `bson:"acknowledgement_id"`
+	GuildId          string `bson:"guild_id"`
+	ScrapeConfigName string `bson:"scrape_name"`
+
+	// Fingerprint is either the group key of the acknowledged notification, or the fingerprint of the acknowledged alert.
+	Fingerprint    string    `bson:"fingerprint"`
+	AcknowledgedBy string    `bson:"acknowledged_by"`
+	AcknowledgedAt time.Time `bson:"acknowledged_at"`
+}
+
+type AlertEventStatus string
+
+const (
+	AlertFiring   AlertEventStatus = "firing"
+	AlertResolved AlertEventStatus = "resolved"
+)
+
+func (s AlertEventStatus) String() string {
+	return string(s)
+}
+
+// AlertEvent records an alert starting to fire or resolving.
+type AlertEvent struct {
+	Id               string            `bson:"alert_event_id"`
+	GuildId          string            `bson:"guild_id"`
+	ScrapeConfigName string            `bson:"scrape_name"`
+	Status           AlertEventStatus  `bson:"status"`
+	Fingerprint      string            `bson:"fingerprint"`
+	Labels           map[string]string `bson:"labels"`
+	Annotations      map[string]string `bson:"annotations"`
+	FiringSince      time.Time         `bson:"firing_since"`
+	ResolvedAt       time.Time         `bson:"resolved_at,omitempty"`
+	Timestamp        time.Time         `bson:"timestamp"`
+
+	// DurationSeconds is how long the alert was firing for. Only set for resolved events.
+	DurationSeconds int64 `bson:"duration_seconds,omitempty"`
+}
+
+// AlertEventQuery filters alert events. Zero values match everything.
+type AlertEventQuery struct {
+	GuildId          string
+	ScrapeConfigName string
+	Labels           map[string]string
+	Since            time.Time
+	Until            time.Time
+	Offset           int
+	Limit            int
+}
+
+func (q AlertEventQuery) matches(event AlertEvent) bool {
+	if event.GuildId != q.GuildId {
+		return false
+	}
+
+	if len(q.ScrapeConfigName) > 0 && event.ScrapeConfigName != q.ScrapeConfigName {
+		return false
+	}
+
+	for k, v := range q.Labels {
+		if event.Labels[k] != v {
+			return false
+		}
+	}
+
+	if !q.Since.IsZero() && event.Timestamp.Before(q.Since) {
+		return false
+	}
+
+	if !q.Until.IsZero() && !event.Timestamp.Before(q.Until) {
+		return false
+	}
+
+	return true
+}
+
+// AlertMessage is the Discord message which was sent for a group of alerts, so that it can be updated as the group changes.
+type AlertMessage struct {
+	GuildId          string    `bson:"guild_id"`
+	ScrapeConfigName string    `bson:"scrape_name"`
+	GroupKey         string    `bson:"group_key"`
+	ChannelId        string    `bson:"channel_id"`
+	MessageId        string    `bson:"message_id"`
+	CreatedAt        time.Time `bson:"created_at"`
+}
+
+func (m AlertMessage) isFor(guildId string, configName string, groupKey string, channelId string) bool {
+	return m.GuildId == guildId && m.ScrapeConfigName == configName && m.GroupKey == groupKey && m.ChannelId == channelId
+}
+
+// ScrapeState is the latest state of a scrape config, recorded by the replica which scrapes it so that it can be reported by any replica.
+type ScrapeState struct {
+	GuildId          string `bson:"guild_id"`
+	ScrapeConfigName string `bson:"scrape_name"`
+
+	// Scraped is false if the endpoint hadn't been scraped since its scraper was started.
+	Scraped             bool      `bson:"scraped"`
+	ConsecutiveFailures int       `bson:"consecutive_failures"`
+	LastSuccess         time.Time `bson:"last_success"`
+	LastError           string    `bson:"last_error"`
+	LastErrorAt         time.Time `bson:"last_error_at"`
+	Down                bool      `bson:"down"`
+
+	// Scheduled is false if the endpoint wasn't being scraped, E.g: because the receiver is enabled.
+	Scheduled             bool      `bson:"scheduled"`
+	ScrapeIntervalSeconds int64     `bson:"scrape_interval_seconds"`
+	ScrapeDelaySeconds    int64     `bson:"scrape_delay_seconds"`
+	NextScrape            time.Time `bson:"next_scrape"`
+
+	// ActiveAlertNames are the names of the scrape config's firing alerts.
+	ActiveAlertNames []string  `bson:"active_alert_names"`
+	UpdatedAt        time.Time `bson:"updated_at"`
+}
+
+func (s ScrapeState) isFor(guildId string, configName string) bool {
+	return s.GuildId == guildId && s.ScrapeConfigName == configName
+}
+
+// Lease grants its holder exclusive ownership of something, E.g: leadership, until it expires.
+type Lease struct {
+	Name      string    `bson:"_id"`
+	HolderId  string    `bson:"holder_id"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Member is a running replica. Members which haven't sent a heartbeat before they expire are considered to have left.
+type Member struct {
+	Id        string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expires_at"`
 }
 
 type CommandRegistration struct {
@@ -52,6 +513,66 @@ type Repo interface {
 	GetRegisteredCommands(ctx context.Context, guildId string) ([]CommandRegistration, error)
 	GetGuildConfigs(ctx context.Context) ([]GuildConfig, error)
 	GetGuildConfig(ctx context.Context, guildId string) (*GuildConfig, error)
+
+	// GetGuildConfigByReceiverTokenHash returns the guild config with a scrape config which has the given receiver token hash, or nil if there isn't one.
+	GetGuildConfigByReceiverTokenHash(ctx context.Context, tokenHash string) (*GuildConfig, error)
 	SetGuildConfig(ctx context.Context, config *GuildConfig) error
 	ClearGuildInfo(ctx context.Context, guildId string) error
+
+	AddSilence(ctx context.Context, silence *Silence) error
+	GetSilences(ctx context.Context, guildId string) ([]Silence, error)
+	ExpireSilence(ctx context.Context, guildId string, silenceId string, now time.Time) error
+	DeleteExpiredSilences(ctx context.Context, now time.Time) error
+
+	AddAcknowledgement(ctx context.Context, acknowledgement *Acknowledgement) error
+	GetAcknowledgements(ctx context.Context, guildId string, configName string, fingerprint string) ([]Acknowledgement, error)
+
+	// GetAlertMessage returns nil if no message has been sent for the group in the given channel.
+	GetAlertMessage(ctx context.Context, guildId string, configName string, groupKey string, channelId string) (*AlertMessage, error)
+	SetAlertMessage(ctx context.Context, message *AlertMessage) error
+	DeleteAlertMessage(ctx context.Context, guildId string, configName string, groupKey string, channelId string) error
+
+	AddAlertEvents(ctx context.Context, events []AlertEvent) error
+
+	// SetScrapeState replaces the scrape config's previously recorded state.
+	SetScrapeState(ctx context.Context, state *ScrapeState) error
+	GetScrapeStates(ctx context.Context, guildId string) ([]ScrapeState, error)
+
+	// GetAlertEvents returns the events matching the query, newest first, along with the total number of matching events.
+	GetAlertEvents(ctx context.Context, query AlertEventQuery) ([]AlertEvent, int, error)
+
+	// AcquireLease acquires or renews the named lease for the holder until now + duration.
+	// Returns false if the lease is held by someone else and hasn't expired.
+	AcquireLease(ctx context.Context, name string, holderId string, now time.Time, duration time.Duration) (bool, error)
+
+	// ReleaseLease releases the named lease if it's held by the holder, allowing someone else to acquire it straight away.
+	ReleaseLease(ctx context.Context, name string, holderId string) error
+
+	// GetLease returns nil if the named lease has never been acquired, or has been released.
+	GetLease(ctx context.Context, name string) (*Lease, error)
+
+	// Heartbeat adds the member, or keeps it from expiring, until now + ttl.
+	Heartbeat(ctx context.Context, memberId string, now time.Time, ttl time.Duration) error
+
+	// GetMembers returns the members which haven't expired, sorted by ID.
+	GetMembers(ctx context.Context, now time.Time) ([]Member, error)
+
+	// RemoveMember removes the member straight away, rather than waiting for it to expire.
+	RemoveMember(ctx context.Context, memberId string) error
+}
+
+// NewId generates a new unique identifier for an entity.
+func NewId() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// NewReplicaId generates an identifier for this replica, E.g: for holding leases.
+func NewReplicaId() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %s", err.Error())
+	}
+
+	// The hostname alone isn't unique if replicas are restarted on the same host before their old leases have expired
+	return fmt.Sprintf("%s-%s", hostname, NewId()), nil
 }
@@ -2,11 +2,19 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/yukitsune/minialert/alerts"
 	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/notify"
 	"github.com/yukitsune/minialert/prometheus"
 	"github.com/yukitsune/minialert/scraper"
 	"github.com/yukitsune/minialert/slices"
+	"sort"
+	"time"
 )
 
 func GetAlerts(ctx context.Context, repo db.Repo, clientFactory prometheus.ClientFactory, guildId string, configName string) (prometheus.Alerts, error) {
@@ -23,62 +31,823 @@ func GetAlerts(ctx context.Context, repo db.Repo, clientFactory prometheus.Clien
 		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
 	}
 
-	client := clientFactory(scrapeConfig)
-	alerts, err := client.GetAlerts()
+	client, err := clientFactory(scrapeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %s", err.Error())
+	}
+
+	alerts, err := client.GetAlerts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %s", err.Error())
+	}
+
+	silences, err := GetSilences(ctx, repo, guildId, configName)
+	if err != nil {
+		return nil, err
+	}
+
+	filteredAlerts, err := prometheus.InhibitAlerts(alerts, scrapeConfig.InhibitionRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inhibit alerts: %s", err.Error())
+	}
+
+	filteredAlerts, err = prometheus.FilterAlerts(filteredAlerts, silences, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter alerts: %s", err.Error())
+	}
+
+	return filteredAlerts, nil
+}
+
+// GetInhibitions returns the names of all alerts which have been inhibited using InhibitAlert.
+func GetInhibitions(ctx context.Context, configName string, guildId string, repo db.Repo) ([]string, error) {
+
+	silences, err := GetSilences(ctx, repo, guildId, configName)
+	if err != nil {
+		return nil, err
+	}
+
+	var alertNames []string
+	for _, silence := range silences {
+		if isInhibition(silence) {
+			alertNames = append(alertNames, silence.Matchers[0].Value)
+		}
+	}
+
+	return alertNames, nil
+}
+
+// InhibitAlert silences all alerts with the given name until UninhibitAlert is used.
+func InhibitAlert(ctx context.Context, configName string, guildId string, alertName string, createdBy string, repo db.Repo) error {
+	_, err := createSilence(ctx, repo, guildId, configName, []db.Matcher{newAlertNameMatcher(alertName)}, createdBy, inhibitionComment, 0, true)
+	return err
+}
+
+func UninhibitAlert(ctx context.Context, configName string, guildId string, alertName string, repo db.Repo) error {
+	silences, err := GetSilences(ctx, repo, guildId, configName)
+	if err != nil {
+		return err
+	}
+
+	for _, silence := range silences {
+		if isInhibition(silence) && silence.Matchers[0].Value == alertName {
+			err = repo.ExpireSilence(ctx, guildId, silence.Id, time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to expire silence: %s", err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+const inhibitionComment = "Inhibited alert"
+
+func newAlertNameMatcher(alertName string) db.Matcher {
+	return db.Matcher{
+		Name:  "alertname",
+		Type:  db.MatchEqual,
+		Value: alertName,
+	}
+}
+
+// isInhibition determines whether the silence was created by InhibitAlert.
+func isInhibition(silence db.Silence) bool {
+	if len(silence.Matchers) != 1 || silence.Matchers[0].Name != "alertname" || silence.Matchers[0].Type != db.MatchEqual {
+		return false
+	}
+
+	// Inhibitions created before they were marked can only be recognised by their comment
+	return silence.Inhibition || (silence.EndsAt.IsZero() && silence.Comment == inhibitionComment)
+}
+
+// CreateSilence silences all alerts matching the given matchers for the given duration.
+// A duration of 0 will create a silence which never expires.
+func CreateSilence(ctx context.Context, repo db.Repo, guildId string, configName string, matchers []db.Matcher, createdBy string, comment string, duration time.Duration) (*db.Silence, error) {
+	return createSilence(ctx, repo, guildId, configName, matchers, createdBy, comment, duration, false)
+}
+
+func createSilence(ctx context.Context, repo db.Repo, guildId string, configName string, matchers []db.Matcher, createdBy string, comment string, duration time.Duration, inhibition bool) (*db.Silence, error) {
+
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+
+	if duration < 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	if !slices.HasMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	}) {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	now := time.Now()
+	silence := &db.Silence{
+		Id:               db.NewId(),
+		GuildId:          guildId,
+		ScrapeConfigName: configName,
+		Matchers:         matchers,
+		CreatedBy:        createdBy,
+		Comment:          comment,
+		StartsAt:         now,
+		Inhibition:       inhibition,
+	}
+
+	if duration > 0 {
+		silence.EndsAt = now.Add(duration)
+	}
+
+	err = repo.AddSilence(ctx, silence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add silence: %s", err.Error())
+	}
+
+	return silence, nil
+}
+
+// GetSilences returns all silences for the given scrape config which haven't expired yet.
+func GetSilences(ctx context.Context, repo db.Repo, guildId string, configName string) ([]db.Silence, error) {
+	silences, err := repo.GetSilences(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get silences: %s", err.Error())
+	}
+
+	now := time.Now()
+	return slices.Filter(silences, func(silence db.Silence) bool {
+		return silence.ScrapeConfigName == configName && !silence.IsExpired(now)
+	}), nil
+}
+
+// GetActiveSilences returns the silences for all of the guild's scrape configs which haven't expired.
+func GetActiveSilences(ctx context.Context, repo db.Repo, guildId string) ([]db.Silence, error) {
+	silences, err := repo.GetSilences(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get silences: %s", err.Error())
+	}
+
+	now := time.Now()
+	return slices.Filter(silences, func(silence db.Silence) bool {
+		return !silence.IsExpired(now)
+	}), nil
+}
+
+func ExpireSilence(ctx context.Context, repo db.Repo, guildId string, silenceId string) error {
+	err := repo.ExpireSilence(ctx, guildId, silenceId, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to expire silence: %s", err.Error())
+	}
+
+	return nil
+}
+
+func AddInhibitionRule(ctx context.Context, repo db.Repo, guildId string, configName string, rule db.InhibitionRule) (*db.InhibitionRule, error) {
+
+	if len(rule.SourceMatchers) == 0 || len(rule.TargetMatchers) == 0 {
+		return nil, fmt.Errorf("source and target matchers are required")
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	rule.Id = db.NewId()
+	scrapeConfig.InhibitionRules = append(scrapeConfig.InhibitionRules, rule)
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	return &rule, nil
+}
+
+func GetInhibitionRules(ctx context.Context, repo db.Repo, guildId string, configName string) ([]db.InhibitionRule, error) {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	return scrapeConfig.InhibitionRules, nil
+}
+
+func RemoveInhibitionRule(ctx context.Context, repo db.Repo, guildId string, configName string, ruleId string) error {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	if !slices.HasMatching(scrapeConfig.InhibitionRules, func(rule db.InhibitionRule) bool {
+		return rule.Id == ruleId
+	}) {
+		return fmt.Errorf("couldn't find inhibition rule with id \"%s\"", ruleId)
+	}
+
+	scrapeConfig.InhibitionRules = slices.RemoveMatches(scrapeConfig.InhibitionRules, func(rule db.InhibitionRule) bool {
+		return rule.Id == ruleId
+	})
+
+	return repo.SetGuildConfig(ctx, guildConfig)
+}
+
+// AddRoute adds a route to the scrape config's routing tree.
+// If a parent route ID is provided, the route is added as a child of that route, otherwise it's added to the top level.
+func AddRoute(ctx context.Context, repo db.Repo, guildId string, configName string, parentRouteId string, route db.Route) (*db.Route, error) {
+
+	if len(route.Matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	route.Id = db.NewId()
+	if len(parentRouteId) == 0 {
+		scrapeConfig.Routes = append(scrapeConfig.Routes, route)
+	} else {
+		parent := findRoute(scrapeConfig.Routes, parentRouteId)
+		if parent == nil {
+			return nil, fmt.Errorf("couldn't find route with id \"%s\"", parentRouteId)
+		}
+
+		parent.Routes = append(parent.Routes, route)
+	}
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	return &route, nil
+}
+
+func GetRoutes(ctx context.Context, repo db.Repo, guildId string, configName string) ([]db.Route, error) {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	return scrapeConfig.Routes, nil
+}
+
+// RemoveRoute removes a route, and all of its child routes, from the scrape config's routing tree.
+func RemoveRoute(ctx context.Context, repo db.Repo, guildId string, configName string, routeId string) error {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	routes, removed := removeRoute(scrapeConfig.Routes, routeId)
+	if !removed {
+		return fmt.Errorf("couldn't find route with id \"%s\"", routeId)
+	}
+
+	scrapeConfig.Routes = routes
+	return repo.SetGuildConfig(ctx, guildConfig)
+}
+
+func findRoute(routes []db.Route, routeId string) *db.Route {
+	for i := range routes {
+		if routes[i].Id == routeId {
+			return &routes[i]
+		}
+
+		if route := findRoute(routes[i].Routes, routeId); route != nil {
+			return route
+		}
+	}
+
+	return nil
+}
+
+func removeRoute(routes []db.Route, routeId string) ([]db.Route, bool) {
+	for i := range routes {
+		if routes[i].Id == routeId {
+			return append(routes[:i], routes[i+1:]...), true
+		}
+
+		if children, removed := removeRoute(routes[i].Routes, routeId); removed {
+			routes[i].Routes = children
+			return routes, true
+		}
+	}
+
+	return routes, false
+}
+
+// AddNotifier adds a notifier to the scrape config.
+// The notifier factory is used to ensure the notifier config is valid before it's added.
+func AddNotifier(ctx context.Context, repo db.Repo, notifierFactory notify.Factory, guildId string, configName string, notifier db.NotifierConfig) (*db.NotifierConfig, error) {
+
+	if _, err := notifierFactory(notifier); err != nil {
+		return nil, fmt.Errorf("invalid notifier: %s", err.Error())
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	notifier.Id = db.NewId()
+	scrapeConfig.Notifiers = append(scrapeConfig.Notifiers, notifier)
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	return &notifier, nil
+}
+
+func GetNotifiers(ctx context.Context, repo db.Repo, guildId string, configName string) ([]db.NotifierConfig, error) {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	return scrapeConfig.Notifiers, nil
+}
+
+func RemoveNotifier(ctx context.Context, repo db.Repo, guildId string, configName string, notifierId string) error {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	if !slices.HasMatching(scrapeConfig.Notifiers, func(notifier db.NotifierConfig) bool {
+		return notifier.Id == notifierId
+	}) {
+		return fmt.Errorf("couldn't find notifier with id \"%s\"", notifierId)
+	}
+
+	scrapeConfig.Notifiers = slices.RemoveMatches(scrapeConfig.Notifiers, func(notifier db.NotifierConfig) bool {
+		return notifier.Id == notifierId
+	})
+
+	return repo.SetGuildConfig(ctx, guildConfig)
+}
+
+// AcknowledgeAlert records that the alert, or group of alerts, with the given fingerprint has been acknowledged.
+func AcknowledgeAlert(ctx context.Context, repo db.Repo, guildId string, configName string, fingerprint string, acknowledgedBy string, now time.Time) (*db.Acknowledgement, error) {
+
+	acknowledgement := &db.Acknowledgement{
+		Id:               db.NewId(),
+		GuildId:          guildId,
+		ScrapeConfigName: configName,
+		Fingerprint:      fingerprint,
+		AcknowledgedBy:   acknowledgedBy,
+		AcknowledgedAt:   now,
+	}
+
+	err := repo.AddAcknowledgement(ctx, acknowledgement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add acknowledgement: %s", err.Error())
+	}
+
+	return acknowledgement, nil
+}
+
+// IsAcknowledged returns true if the alert, or group of alerts, with the given fingerprint has been acknowledged since the given time.
+func IsAcknowledged(ctx context.Context, repo db.Repo, guildId string, configName string, fingerprint string, since time.Time) (bool, error) {
+	acknowledgements, err := repo.GetAcknowledgements(ctx, guildId, configName, fingerprint)
+	if err != nil {
+		return false, fmt.Errorf("failed to get acknowledgements: %s", err.Error())
+	}
+
+	for _, acknowledgement := range acknowledgements {
+		if !acknowledgement.AcknowledgedAt.Before(since) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// knownAlertNamesHistorySize is how many of the most recent alert history events are used to find known alert names.
+const knownAlertNamesHistorySize = 100
+
+// GetKnownAlertNames returns the names of the alerts which are currently firing, or have recently fired, for the scrape config.
+func GetKnownAlertNames(ctx context.Context, repo db.Repo, tracker alerts.Tracker, guildId string, configName string) ([]string, error) {
+
+	var alertNames []string
+	addAlertName := func(alertName string) {
+		if len(alertName) > 0 && !slices.Contains(alertNames, alertName) {
+			alertNames = append(alertNames, alertName)
+		}
+	}
+
+	for _, alert := range tracker.Active(guildId, configName) {
+		addAlertName(alert.Labels["alertname"])
+	}
+
+	// Alerts are only tracked by the replica which scrapes the scrape config, so the names it last recorded are included too
+	scrapeStates, err := repo.GetScrapeStates(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrape states: %s", err.Error())
+	}
+
+	for _, scrapeState := range scrapeStates {
+		if scrapeState.ScrapeConfigName != configName {
+			continue
+		}
+
+		for _, alertName := range scrapeState.ActiveAlertNames {
+			addAlertName(alertName)
+		}
+	}
+
+	events, _, err := repo.GetAlertEvents(ctx, db.AlertEventQuery{
+		GuildId:          guildId,
+		ScrapeConfigName: configName,
+		Limit:            knownAlertNamesHistorySize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert events: %s", err.Error())
+	}
+
+	for _, event := range events {
+		addAlertName(event.Labels["alertname"])
+	}
+
+	sort.Strings(alertNames)
+	return alertNames, nil
+}
+
+// AlertHistoryPageSize is the number of alert events shown on each page of alert history.
+const AlertHistoryPageSize = 10
+
+// RecordAlertEvents adds an entry to the alert history for each alert which started firing or resolved.
+func RecordAlertEvents(ctx context.Context, repo db.Repo, guildId string, configName string, events []alerts.Event, now time.Time) error {
+
+	var alertEvents []db.AlertEvent
+	for _, event := range events {
+		alertEvent := db.AlertEvent{
+			Id:               db.NewId(),
+			GuildId:          guildId,
+			ScrapeConfigName: configName,
+			Fingerprint:      event.Fingerprint,
+			Labels:           event.Alert.Labels,
+			Annotations:      event.Alert.Annotations,
+			FiringSince:      event.FiringSince,
+			Timestamp:        now,
+		}
+
+		switch event.Type {
+		case alerts.FiringEvent:
+			alertEvent.Status = db.AlertFiring
+
+		case alerts.ResolvedEvent:
+			alertEvent.Status = db.AlertResolved
+			alertEvent.ResolvedAt = event.ResolvedAt
+			alertEvent.DurationSeconds = int64(event.ResolvedAt.Sub(event.FiringSince).Seconds())
+
+		default:
+			continue
+		}
+
+		alertEvents = append(alertEvents, alertEvent)
+	}
+
+	if len(alertEvents) == 0 {
+		return nil
+	}
+
+	err := repo.AddAlertEvents(ctx, alertEvents)
+	if err != nil {
+		return fmt.Errorf("failed to add alert events: %s", err.Error())
+	}
+
+	return nil
+}
+
+// GetAlertHistory returns a page of alert events which occurred between since and until, newest first, along with the total number of pages.
+// The config name is optional, and only equality matchers are supported.
+func GetAlertHistory(ctx context.Context, repo db.Repo, guildId string, configName string, matchers []db.Matcher, since time.Time, until time.Time, page int) ([]db.AlertEvent, int, error) {
+
+	if page < 1 {
+		return nil, 0, fmt.Errorf("page must be at least 1")
+	}
+
+	if !until.IsZero() && !since.Before(until) {
+		return nil, 0, fmt.Errorf("start of the time range must be before the end")
+	}
+
+	labels := make(map[string]string)
+	for _, matcher := range matchers {
+		if matcher.Type != db.MatchEqual {
+			return nil, 0, fmt.Errorf("only = matchers are supported")
+		}
+
+		labels[matcher.Name] = matcher.Value
+	}
+
+	if len(configName) > 0 {
+		guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get guild config: %s", err.Error())
+		}
+
+		if !slices.HasMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+			return cfg.Name == configName
+		}) {
+			return nil, 0, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+		}
+	}
+
+	events, total, err := repo.GetAlertEvents(ctx, db.AlertEventQuery{
+		GuildId:          guildId,
+		ScrapeConfigName: configName,
+		Labels:           labels,
+		Since:            since,
+		Until:            until,
+		Offset:           (page - 1) * AlertHistoryPageSize,
+		Limit:            AlertHistoryPageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get alert events: %s", err.Error())
+	}
+
+	pages := (total + AlertHistoryPageSize - 1) / AlertHistoryPageSize
+	return events, pages, nil
+}
+
+// AddEscalationStep adds an escalation step to the scrape config.
+// Steps are kept in order of their delay.
+func AddEscalationStep(ctx context.Context, repo db.Repo, guildId string, configName string, step db.EscalationStep) (*db.EscalationStep, error) {
+
+	if len(step.RoleId) == 0 && len(step.UserId) == 0 {
+		return nil, fmt.Errorf("a role or user is required")
+	}
+
+	if step.DelayMinutes < 0 {
+		return nil, fmt.Errorf("delay must not be negative")
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	step.Id = db.NewId()
+	scrapeConfig.EscalationSteps = append(scrapeConfig.EscalationSteps, step)
+	sort.SliceStable(scrapeConfig.EscalationSteps, func(i, j int) bool {
+		return scrapeConfig.EscalationSteps[i].DelayMinutes < scrapeConfig.EscalationSteps[j].DelayMinutes
+	})
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	return &step, nil
+}
+
+func GetEscalationSteps(ctx context.Context, repo db.Repo, guildId string, configName string) ([]db.EscalationStep, error) {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	return scrapeConfig.EscalationSteps, nil
+}
+
+func RemoveEscalationStep(ctx context.Context, repo db.Repo, guildId string, configName string, stepId string) error {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	if !slices.HasMatching(scrapeConfig.EscalationSteps, func(step db.EscalationStep) bool {
+		return step.Id == stepId
+	}) {
+		return fmt.Errorf("couldn't find escalation step with id \"%s\"", stepId)
+	}
+
+	scrapeConfig.EscalationSteps = slices.RemoveMatches(scrapeConfig.EscalationSteps, func(step db.EscalationStep) bool {
+		return step.Id == stepId
+	})
+
+	return repo.SetGuildConfig(ctx, guildConfig)
+}
+
+// EnableReceiver generates a new receiver token for the scrape config, replacing any existing token.
+// Only the hash of the token is stored, so the returned token cannot be retrieved again.
+// Once enabled, the scrape config will no longer be scraped.
+func EnableReceiver(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, guildId string, configName string) (string, error) {
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %s", err.Error())
+	}
+
+	token := hex.EncodeToString(tokenBytes)
+	err := setReceiverTokenHash(ctx, repo, scrapeManager, guildId, configName, HashReceiverToken(token))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// DisableReceiver removes the receiver token from the scrape config, and resumes scraping.
+func DisableReceiver(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, guildId string, configName string) error {
+	return setReceiverTokenHash(ctx, repo, scrapeManager, guildId, configName, "")
+}
+
+func setReceiverTokenHash(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, guildId string, configName string, tokenHash string) error {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+		return cfg.Name == configName
+	})
+	if !ok {
+		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+	}
+
+	scrapeConfig.ReceiverTokenHash = tokenHash
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
 
-	filteredAlerts, err := prometheus.FilterAlerts(alerts, scrapeConfig.InhibitedAlerts)
+	err = scrapeManager.Restart(guildId, scrapeConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to filter alerts: %s", err.Error())
+		return fmt.Errorf("failed to restart scraper: %s", err.Error())
 	}
 
-	return filteredAlerts, nil
+	return nil
 }
 
-func GetInhibitions(ctx context.Context, configName string, guildId string, repo db.Repo) ([]string, error) {
+// SetCredentials sets the credentials used to scrape the scrape config's endpoint.
+// Either a username and password, or a bearer token, can be used. If all of them are empty, the credentials are removed.
+// Headers are given as comma-separated name=value pairs, which are merged with the existing headers. Headers without a value are removed.
+func SetCredentials(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, guildId string, configName string, username string, password string, bearerToken string, headers string) error {
+
+	if (len(username) == 0) != (len(password) == 0) {
+		return fmt.Errorf("both a username and password are required, or neither to remove the credentials")
+	}
+
+	if len(username) > 0 && len(bearerToken) > 0 {
+		return fmt.Errorf("a bearer token can't be used alongside a username and password")
+	}
+
+	parsedHeaders, err := prometheus.ParseHeaders(headers)
+	if err != nil {
+		return fmt.Errorf("invalid headers: %s", err.Error())
+	}
 
 	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
 	}
 
 	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
 		return cfg.Name == configName
 	})
 	if !ok {
-		return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
 	}
 
-	return scrapeConfig.InhibitedAlerts, nil
-}
+	scrapeConfig.Username = username
+	scrapeConfig.Password = password
+	scrapeConfig.BearerToken = bearerToken
 
-func InhibitAlert(ctx context.Context, configName string, guildId string, alertName string, repo db.Repo) error {
-	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
-	if err != nil {
-		return err
+	if scrapeConfig.Headers == nil && len(parsedHeaders) > 0 {
+		scrapeConfig.Headers = make(map[string]string)
 	}
 
-	var scrapeConfig *db.ScrapeConfig
-	for i, config := range guildConfig.ScrapeConfigs {
-		if config.Name == configName {
-			// Note: Here, `config` is a copy, need to use the index instead
-			// https://stackoverflow.com/a/65292086
-			scrapeConfig = &guildConfig.ScrapeConfigs[i]
+	for name, value := range parsedHeaders {
+		if len(value) == 0 {
+			delete(scrapeConfig.Headers, name)
+			continue
 		}
-	}
 
-	if scrapeConfig == nil {
-		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+		scrapeConfig.Headers[name] = value
 	}
 
-	scrapeConfig.InhibitedAlerts = append(scrapeConfig.InhibitedAlerts, alertName)
-
-	// Bug: Not working. Seems that the guild config isn't getting updated
 	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
 
-	return err
+	err = scrapeManager.Restart(guildId, scrapeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to restart scraper: %s", err.Error())
+	}
+
+	return nil
 }
 
-func UninhibitAlert(ctx context.Context, configName string, guildId string, alertName string, repo db.Repo) error {
+// SetTLS sets the PEM encoded certificates used to connect to the scrape config's endpoint.
+// If the client certificate is given without a key, the existing key is kept. Removing the client certificate also removes its key.
+func SetTLS(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, guildId string, configName string, caCert string, clientCert string, clientKey string) error {
+
 	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
 	if err != nil {
 		return fmt.Errorf("failed to get guild config: %s", err.Error())
@@ -91,12 +860,366 @@ func UninhibitAlert(ctx context.Context, configName string, guildId string, aler
 		return fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
 	}
 
-	scrapeConfig.InhibitedAlerts = slices.RemoveMatches(scrapeConfig.InhibitedAlerts, func(inhibitedAlert string) bool {
-		return inhibitedAlert == alertName
+	tlsConfig := db.TLSConfig{
+		CaCert:             caCert,
+		ClientCert:         clientCert,
+		ClientKey:          clientKey,
+		InsecureSkipVerify: scrapeConfig.TLS.InsecureSkipVerify,
+	}
+
+	if len(clientCert) == 0 {
+		tlsConfig.ClientKey = ""
+	} else if len(clientKey) == 0 {
+		tlsConfig.ClientKey = scrapeConfig.TLS.ClientKey
+	}
+
+	if len(tlsConfig.ClientCert) > 0 && len(tlsConfig.ClientKey) == 0 {
+		return fmt.Errorf("a client key is required when using a client certificate")
+	}
+
+	_, err = prometheus.NewTLSConfig(tlsConfig, nil, false)
+	if err != nil {
+		return err
+	}
+
+	scrapeConfig.TLS = tlsConfig
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	err = scrapeManager.Restart(guildId, scrapeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to restart scraper: %s", err.Error())
+	}
+
+	return nil
+}
+
+// ScrapeStatus describes the health of a scrape config's endpoint.
+type ScrapeStatus struct {
+	ScrapeConfigName string
+	ReceiverEnabled  bool
+
+	// Scraped is false if the endpoint hasn't been scraped since the bot started.
+	Scraped bool
+	Health  scraper.Health
+
+	// Scheduled is false if the endpoint isn't being scraped, E.g: because the receiver is enabled.
+	Scheduled bool
+	Schedule  scraper.Schedule
+}
+
+// GetScrapeStatuses returns the status of each of the guild's scrape configs, or just the given scrape config if a name is provided.
+func GetScrapeStatuses(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, healthTracker scraper.HealthTracker, guildId string, configName string) ([]ScrapeStatus, error) {
+
+	scrapeConfigs, err := GetScrapeConfigs(ctx, repo, guildId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configName) > 0 {
+		scrapeConfigs = slices.Filter(scrapeConfigs, func(cfg db.ScrapeConfig) bool {
+			return cfg.Name == configName
+		})
+
+		if len(scrapeConfigs) == 0 {
+			return nil, fmt.Errorf("couldn't find scrape config with name \"%s\"", configName)
+		}
+	}
+
+	scrapeStates, err := repo.GetScrapeStates(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrape states: %s", err.Error())
+	}
+
+	var statuses []ScrapeStatus
+	for _, scrapeConfig := range scrapeConfigs {
+		status := ScrapeStatus{
+			ScrapeConfigName: scrapeConfig.Name,
+			ReceiverEnabled:  scrapeConfig.ReceiverEnabled(),
+		}
+
+		// Scrape configs are only scraped by one replica, so the state it last recorded is used if this replica isn't scraping it
+		status.Health, status.Scraped = healthTracker.Get(guildId, scrapeConfig.Name)
+		status.Schedule, status.Scheduled = scrapeManager.Schedule(guildId, scrapeConfig.Name)
+		if !status.Scraped && !status.Scheduled {
+			scrapeState, ok := slices.FindMatching(scrapeStates, func(state db.ScrapeState) bool {
+				return state.ScrapeConfigName == scrapeConfig.Name
+			})
+			if ok {
+				applyScrapeState(&status, scrapeState)
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func applyScrapeState(status *ScrapeStatus, state *db.ScrapeState) {
+	status.Scraped = state.Scraped
+	status.Health = scraper.Health{
+		ConsecutiveFailures: state.ConsecutiveFailures,
+		LastSuccess:         state.LastSuccess,
+		LastError:           state.LastError,
+		LastErrorAt:         state.LastErrorAt,
+		Down:                state.Down,
+	}
+
+	status.Scheduled = state.Scheduled
+	status.Schedule = scraper.Schedule{
+		Interval:            time.Duration(state.ScrapeIntervalSeconds) * time.Second,
+		Delay:               time.Duration(state.ScrapeDelaySeconds) * time.Second,
+		ConsecutiveFailures: state.ConsecutiveFailures,
+		NextScrape:          state.NextScrape,
+	}
+}
+
+// RecordScrapeState records the scrape config's health, schedule, and firing alerts, so that they can be reported by replicas which aren't scraping it.
+func RecordScrapeState(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, healthTracker scraper.HealthTracker, tracker alerts.Tracker, guildId string, configName string, now time.Time) error {
+
+	health, scraped := healthTracker.Get(guildId, configName)
+	schedule, scheduled := scrapeManager.Schedule(guildId, configName)
+
+	var activeAlertNames []string
+	for _, alert := range tracker.Active(guildId, configName) {
+		alertName, ok := alert.Labels["alertname"]
+		if ok && !slices.Contains(activeAlertNames, alertName) {
+			activeAlertNames = append(activeAlertNames, alertName)
+		}
+	}
+
+	sort.Strings(activeAlertNames)
+
+	err := repo.SetScrapeState(ctx, &db.ScrapeState{
+		GuildId:               guildId,
+		ScrapeConfigName:      configName,
+		Scraped:               scraped,
+		ConsecutiveFailures:   health.ConsecutiveFailures,
+		LastSuccess:           health.LastSuccess,
+		LastError:             health.LastError,
+		LastErrorAt:           health.LastErrorAt,
+		Down:                  health.Down,
+		Scheduled:             scheduled,
+		ScrapeIntervalSeconds: int64(schedule.Interval / time.Second),
+		ScrapeDelaySeconds:    int64(schedule.Delay / time.Second),
+		NextScrape:            schedule.NextScrape,
+		ActiveAlertNames:      activeAlertNames,
+		UpdatedAt:             now,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to set scrape state: %s", err.Error())
+	}
+
+	return nil
+}
+
+// GetScrapeConfigByReceiverToken finds the scrape config, and the ID of the guild it belongs to, which the given receiver token was generated for.
+func GetScrapeConfigByReceiverToken(ctx context.Context, repo db.Repo, token string) (string, *db.ScrapeConfig, error) {
+
+	tokenHash := HashReceiverToken(token)
+	guildConfig, err := repo.GetGuildConfigByReceiverTokenHash(ctx, tokenHash)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	if guildConfig != nil {
+		scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(cfg db.ScrapeConfig) bool {
+			return cfg.ReceiverEnabled() && cfg.ReceiverTokenHash == tokenHash
+		})
+		if ok {
+			return guildConfig.GuildId, scrapeConfig, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("couldn't find scrape config for receiver token")
+}
+
+func HashReceiverToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// MigrateInhibitedAlerts replaces the deprecated inhibited alerts on each scrape config with an equivalent silence.
+func MigrateInhibitedAlerts(ctx context.Context, repo db.Repo) error {
+	guildConfigs, err := repo.GetGuildConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get guild configs: %s", err.Error())
+	}
+
+	for _, guildConfig := range guildConfigs {
+		migrated := false
+		for i, scrapeConfig := range guildConfig.ScrapeConfigs {
+			if len(scrapeConfig.InhibitedAlerts) == 0 {
+				continue
+			}
+
+			// The migration may have failed part way through before, so alerts which have already been migrated are skipped rather than silenced twice
+			inhibitedAlertNames, err := GetInhibitions(ctx, scrapeConfig.Name, guildConfig.GuildId, repo)
+			if err != nil {
+				return fmt.Errorf("failed to get inhibitions: %s", err.Error())
+			}
+
+			for _, alertName := range scrapeConfig.InhibitedAlerts {
+				if slices.Contains(inhibitedAlertNames, alertName) {
+					continue
+				}
+
+				inhibitedAlertNames = append(inhibitedAlertNames, alertName)
+
+				silence := &db.Silence{
+					Id:               db.NewId(),
+					GuildId:          guildConfig.GuildId,
+					ScrapeConfigName: scrapeConfig.Name,
+					Matchers:         []db.Matcher{newAlertNameMatcher(alertName)},
+					Comment:          inhibitionComment,
+					StartsAt:         time.Now(),
+					Inhibition:       true,
+				}
+
+				err = repo.AddSilence(ctx, silence)
+				if err != nil {
+					return fmt.Errorf("failed to add silence: %s", err.Error())
+				}
+			}
+
+			guildConfig.ScrapeConfigs[i].InhibitedAlerts = nil
+			migrated = true
+		}
+
+		if migrated {
+			err = repo.SetGuildConfig(ctx, &guildConfig)
+			if err != nil {
+				return fmt.Errorf("failed to set guild config: %s", err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// MigrateScrapeIntervals replaces the deprecated scrape interval minutes on each scrape config with an equivalent duration string.
+func MigrateScrapeIntervals(ctx context.Context, repo db.Repo) error {
+	guildConfigs, err := repo.GetGuildConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get guild configs: %s", err.Error())
+	}
+
+	for _, guildConfig := range guildConfigs {
+		migrated := false
+		for i, scrapeConfig := range guildConfig.ScrapeConfigs {
+			if scrapeConfig.ScrapeIntervalMinutes == 0 {
+				continue
+			}
+
+			if len(scrapeConfig.ScrapeInterval) == 0 {
+				guildConfig.ScrapeConfigs[i].ScrapeInterval = (time.Duration(scrapeConfig.ScrapeIntervalMinutes) * time.Minute).String()
+			}
+
+			guildConfig.ScrapeConfigs[i].ScrapeIntervalMinutes = 0
+			migrated = true
+		}
+
+		if migrated {
+			err = repo.SetGuildConfig(ctx, &guildConfig)
+			if err != nil {
+				return fmt.Errorf("failed to set guild config: %s", err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetMinScrapeInterval returns the shortest scrape interval allowed for the guild's scrape configs, which is the longer of the global and guild minimums.
+func GetMinScrapeInterval(ctx context.Context, repo db.Repo, globalMin time.Duration, guildId string) (time.Duration, error) {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	if len(guildConfig.MinScrapeInterval) == 0 {
+		return globalMin, nil
+	}
+
+	guildMin, err := time.ParseDuration(guildConfig.MinScrapeInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid minimum scrape interval: %s", err.Error())
+	}
+
+	if guildMin > globalMin {
+		return guildMin, nil
+	}
+
+	return globalMin, nil
+}
+
+// ParseScrapeInterval parses a Go duration string, E.g: "30s" or "2m30s", ensuring it isn't shorter than the minimum.
+func ParseScrapeInterval(value string, min time.Duration) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval, use a duration such as 30s or 2m30s")
+	}
+
+	if interval <= 0 {
+		return 0, fmt.Errorf("interval must be positive")
+	}
+
+	if interval < min {
+		return 0, fmt.Errorf("interval must be at least %s", min)
+	}
+
+	return interval, nil
+}
+
+// SetMinScrapeInterval sets the shortest scrape interval allowed for the guild's scrape configs. A zero interval removes the guild's minimum.
+// Any scrape configs with shorter intervals are raised to the new minimum, and their names returned.
+func SetMinScrapeInterval(ctx context.Context, repo db.Repo, scrapeManager scraper.ScrapeManager, guildId string, interval time.Duration) ([]string, error) {
+
+	if interval < 0 {
+		return nil, fmt.Errorf("interval can't be negative")
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	guildConfig.MinScrapeInterval = ""
+	if interval > 0 {
+		guildConfig.MinScrapeInterval = interval.String()
+	}
+
+	var raised []string
+	for i, scrapeConfig := range guildConfig.ScrapeConfigs {
+		if scrapeConfig.Interval() < interval {
+			guildConfig.ScrapeConfigs[i].ScrapeInterval = interval.String()
+			guildConfig.ScrapeConfigs[i].ScrapeIntervalMinutes = 0
+			raised = append(raised, scrapeConfig.Name)
+		}
+	}
 
 	err = repo.SetGuildConfig(ctx, guildConfig)
-	return err
+	if err != nil {
+		return nil, fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	for i, scrapeConfig := range guildConfig.ScrapeConfigs {
+		if !slices.Contains(raised, scrapeConfig.Name) {
+			continue
+		}
+
+		err = scrapeManager.Restart(guildId, &guildConfig.ScrapeConfigs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to restart scraper: %s", err.Error())
+		}
+	}
+
+	return raised, nil
 }
 
 func GetScrapeConfigs(ctx context.Context, repo db.Repo, guildId string) ([]db.ScrapeConfig, error) {
@@ -133,10 +1256,135 @@ func RemoveScrapeConfig(ctx context.Context, repo db.Repo, scrapeManager scraper
 		return fmt.Errorf("failed to set guild config: %s", err.Error())
 	}
 
+	// The scraper may be running on another replica, which stops it when it next reconciles its scrapers
 	err = scrapeManager.Stop(guildConfig.GuildId, configName)
-	if err != nil {
+	if err != nil && !errors.Is(err, scraper.ErrNotRunning) {
 		return fmt.Errorf("failed to stop scraper: %s", err.Error())
 	}
 
 	return nil
 }
+
+// RotateEncryptionKey re-encrypts the secrets in each guild config which weren't encrypted with the cipher's current key.
+// The repo must not encrypt secrets itself. The cipher must be able to decrypt secrets encrypted with both the old and new keys, so that rotating can be resumed if it fails part way through.
+// Returns the number of guild configs which were re-encrypted.
+func RotateEncryptionKey(ctx context.Context, repo db.Repo, cipher db.Cipher) (int, error) {
+
+	guildConfigs, err := repo.GetGuildConfigs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get guild configs: %s", err.Error())
+	}
+
+	encryptedRepo := db.NewEncryptedRepo(repo, cipher)
+
+	count := 0
+	for _, guildConfig := range guildConfigs {
+		// Guild configs which were re-encrypted before rotating was interrupted can be skipped
+		if db.IsEncryptedWithCurrentKey(guildConfig, cipher) {
+			continue
+		}
+
+		decryptedConfig, err := encryptedRepo.GetGuildConfig(ctx, guildConfig.GuildId)
+		if err != nil {
+			return count, fmt.Errorf("failed to get guild config %s: %s", guildConfig.GuildId, err.Error())
+		}
+
+		err = encryptedRepo.SetGuildConfig(ctx, decryptedConfig)
+		if err != nil {
+			return count, fmt.Errorf("failed to set guild config %s: %s", guildConfig.GuildId, err.Error())
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// GrantPermission grants the permission level to the role, replacing any level it had already been granted.
+func GrantPermission(ctx context.Context, repo db.Repo, guildId string, roleId string, level db.PermissionLevel) error {
+
+	if !level.IsValid() || level == db.AdminPermission {
+		return fmt.Errorf("permission level must be one of %s, %s or %s", db.ReadPermission, db.SilencePermission, db.ManagePermission)
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	guildConfig.RolePermissions = slices.RemoveMatches(guildConfig.RolePermissions, func(permission db.RolePermission) bool {
+		return permission.RoleId == roleId
+	})
+
+	guildConfig.RolePermissions = append(guildConfig.RolePermissions, db.RolePermission{
+		RoleId: roleId,
+		Level:  level,
+	})
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	return nil
+}
+
+func RevokePermission(ctx context.Context, repo db.Repo, guildId string, roleId string) error {
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	if !slices.HasMatching(guildConfig.RolePermissions, func(permission db.RolePermission) bool {
+		return permission.RoleId == roleId
+	}) {
+		return fmt.Errorf("role %s hasn't been granted any permissions", roleId)
+	}
+
+	guildConfig.RolePermissions = slices.RemoveMatches(guildConfig.RolePermissions, func(permission db.RolePermission) bool {
+		return permission.RoleId == roleId
+	})
+
+	err = repo.SetGuildConfig(ctx, guildConfig)
+	if err != nil {
+		return fmt.Errorf("failed to set guild config: %s", err.Error())
+	}
+
+	return nil
+}
+
+func GetPermissions(ctx context.Context, repo db.Repo, guildId string) ([]db.RolePermission, error) {
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	return guildConfig.RolePermissions, nil
+}
+
+// HasPermission determines whether a member with the given roles has the required permission level.
+// Admins have every permission. Until any permissions have been granted, everyone has the manage level, as they did before permissions could be granted.
+func HasPermission(ctx context.Context, repo db.Repo, guildId string, roleIds []string, isAdmin bool, required db.PermissionLevel) (bool, error) {
+
+	if isAdmin {
+		return true, nil
+	}
+
+	guildConfig, err := repo.GetGuildConfig(ctx, guildId)
+	if err != nil {
+		return false, fmt.Errorf("failed to get guild config: %s", err.Error())
+	}
+
+	if len(guildConfig.RolePermissions) == 0 {
+		return db.ManagePermission.Includes(required), nil
+	}
+
+	for _, permission := range guildConfig.RolePermissions {
+		if slices.Contains(roleIds, permission.RoleId) && permission.Level.Includes(required) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
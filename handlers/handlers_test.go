@@ -2,20 +2,26 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/alerts"
 	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/notify"
 	"github.com/yukitsune/minialert/prometheus"
 	"github.com/yukitsune/minialert/scraper"
+	"github.com/yukitsune/minialert/secrets"
 	"github.com/yukitsune/minialert/slices"
 	"testing"
+	"time"
 )
 
 type FakePrometheusClient struct {
 	Alerts prometheus.Alerts
 }
 
-func (f *FakePrometheusClient) GetAlerts() (prometheus.Alerts, error) {
+func (f *FakePrometheusClient) GetAlerts(_ context.Context) (prometheus.Alerts, error) {
 	return f.Alerts, nil
 }
 
@@ -43,11 +49,45 @@ func (f *FakeScrapeManager) Restart(_ string, _ *db.ScrapeConfig) error {
 	return nil
 }
 
+func (f *FakeScrapeManager) Reconcile(scrapeConfigs map[string][]db.ScrapeConfig) {
+	f.ActiveScrapers = nil
+	for guildId, configs := range scrapeConfigs {
+		for i := range configs {
+			f.Start(guildId, &configs[i])
+		}
+	}
+}
+
+func (f *FakeScrapeManager) StopAll(_ context.Context) error {
+	f.ActiveScrapers = nil
+	return nil
+}
+
+func (f *FakeScrapeManager) Schedule(guildId string, configName string) (scraper.Schedule, bool) {
+	s, ok := slices.FindMatching(f.ActiveScrapers, func(s Scraper) bool {
+		return s.GuildId == guildId && s.Config.Name == configName
+	})
+
+	if !ok {
+		return scraper.Schedule{}, false
+	}
+
+	return scraper.Schedule{
+		Interval: s.Config.Interval(),
+		Delay:    s.Config.Interval(),
+	}, true
+}
+
 func (f *FakeScrapeManager) Stop(guildId string, configName string) error {
+	running := len(f.ActiveScrapers)
 	f.ActiveScrapers = slices.RemoveMatches(f.ActiveScrapers, func(s Scraper) bool {
 		return s.GuildId == guildId && s.Config.Name == configName
 	})
 
+	if len(f.ActiveScrapers) == running {
+		return scraper.ErrNotRunning
+	}
+
 	return nil
 }
 
@@ -65,13 +105,12 @@ func TestGetAlertsGetsAlertsFromPrometheus(t *testing.T) {
 		GuildId: guildId,
 		ScrapeConfigs: []db.ScrapeConfig{
 			{
-				Name:                  configName,
-				Endpoint:              "",
-				Username:              "",
-				Password:              "",
-				ScrapeIntervalMinutes: 0,
-				AlertChannelId:        "",
-				InhibitedAlerts:       []string{},
+				Name:            configName,
+				Endpoint:        "",
+				Username:        "",
+				Password:        "",
+				AlertChannelId:  "",
+				InhibitedAlerts: []string{},
 			},
 		},
 	}
@@ -84,8 +123,8 @@ func TestGetAlertsGetsAlertsFromPrometheus(t *testing.T) {
 		prometheus.Alert{Value: value},
 	}
 
-	clientFactory := func(config *db.ScrapeConfig) prometheus.Client {
-		return &FakePrometheusClient{Alerts: alerts}
+	clientFactory := func(config *db.ScrapeConfig) (prometheus.Client, error) {
+		return &FakePrometheusClient{Alerts: alerts}, nil
 	}
 
 	// Act
@@ -112,15 +151,11 @@ func TestGetAlertsFiltersInhibitedAlerts(t *testing.T) {
 		GuildId: guildId,
 		ScrapeConfigs: []db.ScrapeConfig{
 			{
-				Name:                  configName,
-				Endpoint:              "",
-				Username:              "",
-				Password:              "",
-				ScrapeIntervalMinutes: 0,
-				AlertChannelId:        "",
-				InhibitedAlerts: []string{
-					alertNameToFilter,
-				},
+				Name:           configName,
+				Endpoint:       "",
+				Username:       "",
+				Password:       "",
+				AlertChannelId: "",
 			},
 		},
 	}
@@ -128,6 +163,9 @@ func TestGetAlertsFiltersInhibitedAlerts(t *testing.T) {
 	err := repo.SetGuildConfig(ctx, guildConfig)
 	assert.NoError(t, err)
 
+	err = InhibitAlert(ctx, configName, guildId, alertNameToFilter, "", repo)
+	assert.NoError(t, err)
+
 	value := "foo"
 	valueToFilter := "bar"
 	alertName := "zag"
@@ -145,8 +183,8 @@ func TestGetAlertsFiltersInhibitedAlerts(t *testing.T) {
 		},
 	}
 
-	clientFactory := func(config *db.ScrapeConfig) prometheus.Client {
-		return &FakePrometheusClient{Alerts: alerts}
+	clientFactory := func(config *db.ScrapeConfig) (prometheus.Client, error) {
+		return &FakePrometheusClient{Alerts: alerts}, nil
 	}
 
 	// Act
@@ -158,7 +196,7 @@ func TestGetAlertsFiltersInhibitedAlerts(t *testing.T) {
 	assert.Equal(t, foundAlerts[0].Value, value)
 }
 
-func TestInhibitAlertUpdatesConfig(t *testing.T) {
+func TestInhibitAlertCreatesSilence(t *testing.T) {
 
 	// Arrange
 	ctx := context.Background()
@@ -171,13 +209,11 @@ func TestInhibitAlertUpdatesConfig(t *testing.T) {
 		GuildId: guildId,
 		ScrapeConfigs: []db.ScrapeConfig{
 			{
-				Name:                  configName,
-				Endpoint:              "",
-				Username:              "",
-				Password:              "",
-				ScrapeIntervalMinutes: 0,
-				AlertChannelId:        "",
-				InhibitedAlerts:       []string{},
+				Name:           configName,
+				Endpoint:       "",
+				Username:       "",
+				Password:       "",
+				AlertChannelId: "",
 			},
 		},
 	}
@@ -187,22 +223,22 @@ func TestInhibitAlertUpdatesConfig(t *testing.T) {
 
 	// Act
 	alertName := "fizz"
-	err = InhibitAlert(ctx, configName, guildId, alertName, repo)
+	userId := "buzz"
+	err = InhibitAlert(ctx, configName, guildId, alertName, userId, repo)
 	assert.NoError(t, err)
 
 	// Assert
-	guildConfig, err = repo.GetGuildConfig(ctx, guildId)
+	silences, err := repo.GetSilences(ctx, guildId)
 	assert.NoError(t, err)
 
-	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(c db.ScrapeConfig) bool {
-		return c.Name == configName
-	})
-
-	assert.True(t, ok)
-	assert.Contains(t, scrapeConfig.InhibitedAlerts, alertName)
+	assert.Len(t, silences, 1)
+	assert.Equal(t, configName, silences[0].ScrapeConfigName)
+	assert.Equal(t, userId, silences[0].CreatedBy)
+	assert.Equal(t, []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: alertName}}, silences[0].Matchers)
+	assert.True(t, silences[0].EndsAt.IsZero())
 }
 
-func TestUninhibitAlertUpdatesConfig(t *testing.T) {
+func TestUninhibitAlertExpiresSilence(t *testing.T) {
 
 	// Arrange
 	ctx := context.Background()
@@ -216,13 +252,11 @@ func TestUninhibitAlertUpdatesConfig(t *testing.T) {
 		GuildId: guildId,
 		ScrapeConfigs: []db.ScrapeConfig{
 			{
-				Name:                  configName,
-				Endpoint:              "",
-				Username:              "",
-				Password:              "",
-				ScrapeIntervalMinutes: 0,
-				AlertChannelId:        "",
-				InhibitedAlerts:       []string{alertName},
+				Name:           configName,
+				Endpoint:       "",
+				Username:       "",
+				Password:       "",
+				AlertChannelId: "",
 			},
 		},
 	}
@@ -230,20 +264,52 @@ func TestUninhibitAlertUpdatesConfig(t *testing.T) {
 	err := repo.SetGuildConfig(ctx, guildConfig)
 	assert.NoError(t, err)
 
+	err = InhibitAlert(ctx, configName, guildId, alertName, "", repo)
+	assert.NoError(t, err)
+
 	// Act
 	err = UninhibitAlert(ctx, configName, guildId, alertName, repo)
 	assert.NoError(t, err)
 
 	// Assert
-	guildConfig, err = repo.GetGuildConfig(ctx, guildId)
+	alertNames, err := GetInhibitions(ctx, configName, guildId, repo)
 	assert.NoError(t, err)
+	assert.Empty(t, alertNames)
+}
+
+func TestGetInhibitionsIgnoresOtherSilences(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
 
-	scrapeConfig, ok := slices.FindMatching(guildConfig.ScrapeConfigs, func(c db.ScrapeConfig) bool {
-		return c.Name == configName
+	guildId := "foo"
+	configName := "bar"
+	alertName := "zig"
+	err := repo.SetGuildConfig(ctx, &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
 	})
+	assert.NoError(t, err)
 
-	assert.True(t, ok)
-	assert.Empty(t, scrapeConfig.InhibitedAlerts)
+	// A silence which looks like an inhibition, but was created by silencing the alert indefinitely
+	_, err = CreateSilence(ctx, repo, guildId, configName, []db.Matcher{newAlertNameMatcher(alertName)}, "", "Maintenance", 0)
+	assert.NoError(t, err)
+
+	// Act
+	alertNames, err := GetInhibitions(ctx, configName, guildId, repo)
+	assert.NoError(t, err)
+
+	err = UninhibitAlert(ctx, configName, guildId, alertName, repo)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Empty(t, alertNames)
+
+	silences, err := GetSilences(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+	assert.Len(t, silences, 1)
 }
 
 func TestGetInhibitionsGetsInhibitions(t *testing.T) {
@@ -260,13 +326,11 @@ func TestGetInhibitionsGetsInhibitions(t *testing.T) {
 		GuildId: guildId,
 		ScrapeConfigs: []db.ScrapeConfig{
 			{
-				Name:                  configName,
-				Endpoint:              "",
-				Username:              "",
-				Password:              "",
-				ScrapeIntervalMinutes: 0,
-				AlertChannelId:        "",
-				InhibitedAlerts:       []string{alertName},
+				Name:           configName,
+				Endpoint:       "",
+				Username:       "",
+				Password:       "",
+				AlertChannelId: "",
 			},
 		},
 	}
@@ -274,6 +338,9 @@ func TestGetInhibitionsGetsInhibitions(t *testing.T) {
 	err := repo.SetGuildConfig(ctx, guildConfig)
 	assert.NoError(t, err)
 
+	err = InhibitAlert(ctx, configName, guildId, alertName, "", repo)
+	assert.NoError(t, err)
+
 	// Act
 	alertNames, err := GetInhibitions(ctx, configName, guildId, repo)
 	assert.NoError(t, err)
@@ -283,10 +350,97 @@ func TestGetInhibitionsGetsInhibitions(t *testing.T) {
 	assert.Equal(t, alertNames[0], alertName)
 }
 
-// Todo: CreateScrapeConfigCreatesScrapeConfig
-// Todo: CreateScrapeConfigStartsScraper
+func TestCreateSilenceRequiresScrapeConfig(t *testing.T) {
 
-func TestGetScrapeConfigs(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	err := repo.SetGuildConfig(ctx, db.NewGuildConfig(guildId))
+	assert.NoError(t, err)
+
+	matchers := []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "zig"}}
+
+	// Act
+	_, err = CreateSilence(ctx, repo, guildId, "bar", matchers, "", "", time.Hour)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestGetAlertsFiltersSilencedAlerts(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	matchers := []db.Matcher{{Name: "severity", Type: db.MatchRegexp, Value: "warn.*"}}
+	_, err = CreateSilence(ctx, repo, guildId, configName, matchers, "", "", time.Hour)
+	assert.NoError(t, err)
+
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Value: "silenced", Labels: map[string]string{"severity": "warning"}},
+		prometheus.Alert{Value: "not silenced", Labels: map[string]string{"severity": "critical"}},
+	}
+
+	clientFactory := func(config *db.ScrapeConfig) (prometheus.Client, error) {
+		return &FakePrometheusClient{Alerts: alerts}, nil
+	}
+
+	// Act
+	foundAlerts, err := GetAlerts(ctx, repo, clientFactory, guildId, configName)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, foundAlerts, 1)
+	assert.Equal(t, "not silenced", foundAlerts[0].Value)
+}
+
+func TestExpireSilenceUnsilencesAlerts(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	matchers := []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "zig"}}
+	silence, err := CreateSilence(ctx, repo, guildId, configName, matchers, "", "", time.Hour)
+	assert.NoError(t, err)
+
+	// Act
+	err = ExpireSilence(ctx, repo, guildId, silence.Id)
+	assert.NoError(t, err)
+
+	// Assert
+	silences, err := GetSilences(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+	assert.Empty(t, silences)
+}
+
+func TestMigrateInhibitedAlertsCreatesSilences(t *testing.T) {
 
 	// Arrange
 	ctx := context.Background()
@@ -300,13 +454,8 @@ func TestGetScrapeConfigs(t *testing.T) {
 		GuildId: guildId,
 		ScrapeConfigs: []db.ScrapeConfig{
 			{
-				Name:                  configName,
-				Endpoint:              "",
-				Username:              "",
-				Password:              "",
-				ScrapeIntervalMinutes: 0,
-				AlertChannelId:        "",
-				InhibitedAlerts:       []string{alertName},
+				Name:            configName,
+				InhibitedAlerts: []string{alertName},
 			},
 		},
 	}
@@ -315,36 +464,34 @@ func TestGetScrapeConfigs(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Act
-	foundScrapeConfigs, err := GetScrapeConfigs(ctx, repo, guildId)
+	err = MigrateInhibitedAlerts(ctx, repo)
 	assert.NoError(t, err)
 
 	// Assert
-	assert.Len(t, foundScrapeConfigs, 1)
-	assert.Equal(t, foundScrapeConfigs[0].Name, configName)
+	alertNames, err := GetInhibitions(ctx, configName, guildId, repo)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{alertName}, alertNames)
+
+	guildConfig, err = repo.GetGuildConfig(ctx, guildId)
+	assert.NoError(t, err)
+	assert.Empty(t, guildConfig.ScrapeConfigs[0].InhibitedAlerts)
 }
 
-func TestRemoveScrapeConfigRemovesScrapeConfig(t *testing.T) {
+func TestMigrateInhibitedAlertsSkipsAlertsWhichWereAlreadyMigrated(t *testing.T) {
 
 	// Arrange
 	ctx := context.Background()
 	logger := logrus.New()
 	repo := db.SetupInMemoryDatabase(logger)
-	scrapeManager := &FakeScrapeManager{}
 
 	guildId := "foo"
 	configName := "bar"
-	alertName := "zig"
 	guildConfig := &db.GuildConfig{
 		GuildId: guildId,
 		ScrapeConfigs: []db.ScrapeConfig{
 			{
-				Name:                  configName,
-				Endpoint:              "",
-				Username:              "",
-				Password:              "",
-				ScrapeIntervalMinutes: 0,
-				AlertChannelId:        "",
-				InhibitedAlerts:       []string{alertName},
+				Name:            configName,
+				InhibitedAlerts: []string{"zig", "zag"},
 			},
 		},
 	}
@@ -352,53 +499,1148 @@ func TestRemoveScrapeConfigRemovesScrapeConfig(t *testing.T) {
 	err := repo.SetGuildConfig(ctx, guildConfig)
 	assert.NoError(t, err)
 
+	// A previous migration created the silence but failed to clear the inhibited alerts
+	err = InhibitAlert(ctx, configName, guildId, "zig", "", repo)
+	assert.NoError(t, err)
+
 	// Act
-	err = RemoveScrapeConfig(ctx, repo, scrapeManager, guildId, configName)
+	err = MigrateInhibitedAlerts(ctx, repo)
 	assert.NoError(t, err)
 
 	// Assert
-	guildConfig, err = repo.GetGuildConfig(ctx, guildId)
+	alertNames, err := GetInhibitions(ctx, configName, guildId, repo)
 	assert.NoError(t, err)
-	assert.Empty(t, guildConfig.ScrapeConfigs)
+	assert.ElementsMatch(t, []string{"zig", "zag"}, alertNames)
 }
 
-func TestRemoveScrapeConfigStopsScraper(t *testing.T) {
+func TestAddInhibitionRuleAddsRule(t *testing.T) {
 
 	// Arrange
 	ctx := context.Background()
 	logger := logrus.New()
 	repo := db.SetupInMemoryDatabase(logger)
-	scrapeManager := &FakeScrapeManager{}
 
 	guildId := "foo"
 	configName := "bar"
-	alertName := "zig"
-	scrapeConfig := db.ScrapeConfig{
-		Name:                  configName,
-		Endpoint:              "",
-		Username:              "",
-		Password:              "",
-		ScrapeIntervalMinutes: 0,
-		AlertChannelId:        "",
-		InhibitedAlerts:       []string{alertName},
-	}
 	guildConfig := &db.GuildConfig{
 		GuildId:       guildId,
-		ScrapeConfigs: []db.ScrapeConfig{scrapeConfig},
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
 	}
 
 	err := repo.SetGuildConfig(ctx, guildConfig)
 	assert.NoError(t, err)
 
-	scrapeManager.Start(guildId, &scrapeConfig)
+	rule := db.InhibitionRule{
+		SourceMatchers: []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "NodeDown"}},
+		TargetMatchers: []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "HighLatency"}},
+		Equal:          []string{"instance"},
+	}
 
-	// Sanity check
-	assert.Len(t, scrapeManager.ActiveScrapers, 1)
+	// Act
+	addedRule, err := AddInhibitionRule(ctx, repo, guildId, configName, rule)
+	assert.NoError(t, err)
+
+	// Assert
+	rules, err := GetInhibitionRules(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+
+	assert.Len(t, rules, 1)
+	assert.NotEmpty(t, rules[0].Id)
+	assert.Equal(t, addedRule.Id, rules[0].Id)
+	assert.Equal(t, rule.Equal, rules[0].Equal)
+}
+
+func TestRemoveInhibitionRuleRemovesRule(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	ruleId := "baz"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{
+				Name:            configName,
+				InhibitionRules: []db.InhibitionRule{{Id: ruleId}},
+			},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
 
 	// Act
-	err = RemoveScrapeConfig(ctx, repo, scrapeManager, guildId, configName)
+	err = RemoveInhibitionRule(ctx, repo, guildId, configName, ruleId)
 	assert.NoError(t, err)
 
 	// Assert
-	assert.Empty(t, scrapeManager.ActiveScrapers)
+	rules, err := GetInhibitionRules(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestGetAlertsAppliesInhibitionRules(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{
+				Name: configName,
+				InhibitionRules: []db.InhibitionRule{
+					{
+						SourceMatchers: []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "NodeDown"}},
+						TargetMatchers: []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "HighLatency"}},
+						Equal:          []string{"instance"},
+					},
+				},
+			},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	alerts := prometheus.Alerts{
+		prometheus.Alert{Value: "source", Labels: map[string]string{"alertname": "NodeDown", "instance": "a"}},
+		prometheus.Alert{Value: "inhibited", Labels: map[string]string{"alertname": "HighLatency", "instance": "a"}},
+	}
+
+	clientFactory := func(config *db.ScrapeConfig) (prometheus.Client, error) {
+		return &FakePrometheusClient{Alerts: alerts}, nil
+	}
+
+	// Act
+	foundAlerts, err := GetAlerts(ctx, repo, clientFactory, guildId, configName)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, foundAlerts, 1)
+	assert.Equal(t, "source", foundAlerts[0].Value)
+}
+
+func TestAddRouteAddsChildRoute(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	parentRouteId := "baz"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{
+				Name:   configName,
+				Routes: []db.Route{{Id: parentRouteId}},
+			},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	route := db.Route{
+		Matchers:  []db.Matcher{{Name: "team", Type: db.MatchEqual, Value: "db"}},
+		ChannelId: "123",
+	}
+
+	// Act
+	addedRoute, err := AddRoute(ctx, repo, guildId, configName, parentRouteId, route)
+	assert.NoError(t, err)
+
+	// Assert
+	routes, err := GetRoutes(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+
+	assert.Len(t, routes, 1)
+	assert.Len(t, routes[0].Routes, 1)
+	assert.Equal(t, addedRoute.Id, routes[0].Routes[0].Id)
+	assert.Equal(t, route.ChannelId, routes[0].Routes[0].ChannelId)
+}
+
+func TestRemoveRouteRemovesChildRoute(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	routeId := "baz"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{
+				Name: configName,
+				Routes: []db.Route{
+					{
+						Id:     "parent",
+						Routes: []db.Route{{Id: routeId}},
+					},
+				},
+			},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = RemoveRoute(ctx, repo, guildId, configName, routeId)
+	assert.NoError(t, err)
+
+	// Assert
+	routes, err := GetRoutes(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+
+	assert.Len(t, routes, 1)
+	assert.Empty(t, routes[0].Routes)
+}
+
+func TestEnableReceiverGeneratesToken(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	token, err := EnableReceiver(ctx, repo, scrapeManager, guildId, configName)
+	assert.NoError(t, err)
+
+	// Assert
+	foundGuildId, scrapeConfig, err := GetScrapeConfigByReceiverToken(ctx, repo, token)
+	assert.NoError(t, err)
+	assert.Equal(t, guildId, foundGuildId)
+	assert.Equal(t, configName, scrapeConfig.Name)
+	assert.NotEqual(t, token, scrapeConfig.ReceiverTokenHash)
+
+	_, _, err = GetScrapeConfigByReceiverToken(ctx, repo, "baz")
+	assert.Error(t, err)
+}
+
+func TestDisableReceiverInvalidatesToken(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	token, err := EnableReceiver(ctx, repo, scrapeManager, guildId, configName)
+	assert.NoError(t, err)
+
+	// Act
+	err = DisableReceiver(ctx, repo, scrapeManager, guildId, configName)
+	assert.NoError(t, err)
+
+	// Assert
+	_, _, err = GetScrapeConfigByReceiverToken(ctx, repo, token)
+	assert.Error(t, err)
+}
+
+func TestSetCredentialsRequiresUsernameAndPassword(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = SetCredentials(ctx, repo, scrapeManager, guildId, configName, "baz", "", "", "")
+	assert.Error(t, err)
+
+	err = SetCredentials(ctx, repo, scrapeManager, guildId, configName, "baz", "qux", "", "")
+	assert.NoError(t, err)
+
+	// Assert
+	scrapeConfigs, err := GetScrapeConfigs(ctx, repo, guildId)
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", scrapeConfigs[0].Username)
+	assert.Equal(t, "qux", scrapeConfigs[0].Password)
+}
+
+func TestSetCredentialsRejectsBearerTokenWithBasicAuth(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = SetCredentials(ctx, repo, scrapeManager, guildId, configName, "baz", "qux", "hunter2", "")
+	assert.Error(t, err)
+
+	err = SetCredentials(ctx, repo, scrapeManager, guildId, configName, "", "", "hunter2", "")
+	assert.NoError(t, err)
+
+	// Assert
+	scrapeConfigs, err := GetScrapeConfigs(ctx, repo, guildId)
+	assert.NoError(t, err)
+	assert.Empty(t, scrapeConfigs[0].Username)
+	assert.Equal(t, "hunter2", scrapeConfigs[0].BearerToken)
+}
+
+func TestSetCredentialsMergesHeaders(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{
+			Name:    configName,
+			Headers: map[string]string{"X-Scope-OrgID": "tenant-1", "X-Api-Key": "hunter2"},
+		}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = SetCredentials(ctx, repo, scrapeManager, guildId, configName, "", "", "", "X-Scope-OrgID")
+	assert.Error(t, err)
+
+	err = SetCredentials(ctx, repo, scrapeManager, guildId, configName, "", "", "", "X-Scope-OrgID=, X-Api-Key=hunter3")
+	assert.NoError(t, err)
+
+	// Assert
+	scrapeConfigs, err := GetScrapeConfigs(ctx, repo, guildId)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-Api-Key": "hunter3"}, scrapeConfigs[0].Headers)
+}
+
+func TestSetTLSRejectsInvalidCertificates(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = SetTLS(ctx, repo, scrapeManager, guildId, configName, "baz", "", "")
+
+	// Assert
+	assert.Error(t, err)
+
+	scrapeConfigs, err := GetScrapeConfigs(ctx, repo, guildId)
+	assert.NoError(t, err)
+	assert.Empty(t, scrapeConfigs[0].TLS.CaCert)
+}
+
+func TestAddNotifierRejectsInvalidNotifiers(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	notifierFactory := func(cfg db.NotifierConfig) (notify.Notifier, error) {
+		return nil, fmt.Errorf("invalid url")
+	}
+
+	// Act
+	_, err = AddNotifier(ctx, repo, notifierFactory, guildId, configName, db.NotifierConfig{Type: db.WebhookNotifier})
+
+	// Assert
+	assert.Error(t, err)
+
+	notifiers, err := GetNotifiers(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+	assert.Empty(t, notifiers)
+}
+
+func TestAcknowledgeAlertRecordsAcknowledgement(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	fingerprint := "baz"
+	userId := "qux"
+	now := time.Now()
+
+	// Act
+	_, err := AcknowledgeAlert(ctx, repo, guildId, configName, fingerprint, userId, now)
+	assert.NoError(t, err)
+
+	// Assert
+	acknowledgements, err := repo.GetAcknowledgements(ctx, guildId, configName, fingerprint)
+	assert.NoError(t, err)
+	assert.Len(t, acknowledgements, 1)
+	assert.Equal(t, userId, acknowledgements[0].AcknowledgedBy)
+	assert.Equal(t, now, acknowledgements[0].AcknowledgedAt)
+}
+
+func TestIsAcknowledgedIgnoresEarlierAcknowledgements(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	fingerprint := "baz"
+	now := time.Now()
+
+	_, err := AcknowledgeAlert(ctx, repo, guildId, configName, fingerprint, "qux", now)
+	assert.NoError(t, err)
+
+	// Act
+	acknowledged, err := IsAcknowledged(ctx, repo, guildId, configName, fingerprint, now)
+	assert.NoError(t, err)
+
+	acknowledgedBefore, err := IsAcknowledged(ctx, repo, guildId, configName, fingerprint, now.Add(time.Second))
+	assert.NoError(t, err)
+
+	// Assert
+	assert.True(t, acknowledged)
+	assert.False(t, acknowledgedBefore, "acknowledgements from before the group started firing shouldn't apply")
+}
+
+func TestAddEscalationStepKeepsStepsInOrder(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = AddEscalationStep(ctx, repo, guildId, configName, db.EscalationStep{DelayMinutes: 15, UserId: "lead"})
+	assert.NoError(t, err)
+
+	_, err = AddEscalationStep(ctx, repo, guildId, configName, db.EscalationStep{DelayMinutes: 5, RoleId: "oncall"})
+	assert.NoError(t, err)
+
+	_, err = AddEscalationStep(ctx, repo, guildId, configName, db.EscalationStep{DelayMinutes: 30})
+	assert.Error(t, err)
+
+	// Assert
+	steps, err := GetEscalationSteps(ctx, repo, guildId, configName)
+	assert.NoError(t, err)
+	assert.Len(t, steps, 2)
+	assert.Equal(t, "oncall", steps[0].RoleId)
+	assert.Equal(t, "lead", steps[1].UserId)
+}
+
+func TestRecordAlertEventsRecordsTransitions(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: configName}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	firingSince := now.Add(-time.Hour)
+	events := []alerts.Event{
+		{
+			Type:        alerts.FiringEvent,
+			Fingerprint: "firing",
+			Alert:       prometheus.Alert{Labels: map[string]string{"alertname": "Foo"}},
+			FiringSince: now,
+		},
+		{
+			Type:        alerts.StillFiringEvent,
+			Fingerprint: "still-firing",
+			Alert:       prometheus.Alert{Labels: map[string]string{"alertname": "Foo"}},
+			FiringSince: firingSince,
+		},
+		{
+			Type:        alerts.ResolvedEvent,
+			Fingerprint: "resolved",
+			Alert:       prometheus.Alert{Labels: map[string]string{"alertname": "Bar"}},
+			FiringSince: firingSince,
+			ResolvedAt:  now,
+		},
+	}
+
+	// Act
+	err = RecordAlertEvents(ctx, repo, guildId, configName, events, now)
+	assert.NoError(t, err)
+
+	// Assert
+	history, pages, err := GetAlertHistory(ctx, repo, guildId, configName, nil, now.Add(-time.Minute), time.Time{}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pages)
+	assert.Len(t, history, 2)
+
+	resolved, ok := slices.FindMatching(history, func(event db.AlertEvent) bool {
+		return event.Fingerprint == "resolved"
+	})
+	assert.True(t, ok)
+	assert.Equal(t, db.AlertResolved, resolved.Status)
+	assert.Equal(t, int64(3600), resolved.DurationSeconds)
+}
+
+func TestGetAlertHistoryFiltersByLabels(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	now := time.Now()
+
+	var events []db.AlertEvent
+	for i := 0; i < AlertHistoryPageSize+1; i++ {
+		events = append(events, db.AlertEvent{
+			Id:        db.NewId(),
+			GuildId:   guildId,
+			Status:    db.AlertFiring,
+			Labels:    map[string]string{"alertname": "Foo"},
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	events = append(events, db.AlertEvent{
+		Id:        db.NewId(),
+		GuildId:   guildId,
+		Status:    db.AlertFiring,
+		Labels:    map[string]string{"alertname": "Bar"},
+		Timestamp: now,
+	})
+
+	err := repo.AddAlertEvents(ctx, events)
+	assert.NoError(t, err)
+
+	matchers, err := prometheus.ParseMatchers("alertname=\"Foo\"")
+	assert.NoError(t, err)
+
+	// Act
+	history, pages, err := GetAlertHistory(ctx, repo, guildId, "", matchers, now.Add(-time.Minute), time.Time{}, 2)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pages)
+	assert.Equal(t, []db.AlertEvent{events[0]}, history)
+}
+
+func newTestKey(t *testing.T) *secrets.Key {
+	encoded, err := secrets.GenerateKey()
+	assert.NoError(t, err)
+
+	key, err := secrets.ParseKey(encoded)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func TestRotateEncryptionKeyReencryptsSecrets(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	currentKey := newTestKey(t)
+	newKey := newTestKey(t)
+
+	currentRepo := db.NewEncryptedRepo(repo, currentKey)
+	newRepo := db.NewEncryptedRepo(repo, newKey)
+
+	// The second guild config was re-encrypted before an earlier attempt to rotate the key failed
+	err := currentRepo.SetGuildConfig(ctx, &db.GuildConfig{
+		GuildId:       "foo",
+		ScrapeConfigs: []db.ScrapeConfig{{Name: "bar", Username: "baz", Password: "hunter2"}},
+	})
+	assert.NoError(t, err)
+
+	err = newRepo.SetGuildConfig(ctx, &db.GuildConfig{
+		GuildId:       "qux",
+		ScrapeConfigs: []db.ScrapeConfig{{Name: "quux", BearerToken: "hunter3"}},
+	})
+	assert.NoError(t, err)
+
+	// Act
+	count, err := RotateEncryptionKey(ctx, repo, secrets.NewKeyring(currentKey).Rotate(newKey))
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, 1, count)
+
+	_, err = currentRepo.GetGuildConfig(ctx, "foo")
+	assert.Error(t, err)
+
+	rotatedConfig, err := newRepo.GetGuildConfig(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", rotatedConfig.ScrapeConfigs[0].Password)
+
+	skippedConfig, err := newRepo.GetGuildConfig(ctx, "qux")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter3", skippedConfig.ScrapeConfigs[0].BearerToken)
+}
+
+func TestGrantPermissionReplacesExistingLevel(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	roleId := "bar"
+	err := repo.SetGuildConfig(ctx, db.NewGuildConfig(guildId))
+	assert.NoError(t, err)
+
+	// Act
+	err = GrantPermission(ctx, repo, guildId, roleId, db.ReadPermission)
+	assert.NoError(t, err)
+
+	err = GrantPermission(ctx, repo, guildId, roleId, db.ManagePermission)
+	assert.NoError(t, err)
+
+	err = GrantPermission(ctx, repo, guildId, roleId, db.AdminPermission)
+	assert.Error(t, err)
+
+	// Assert
+	permissions, err := GetPermissions(ctx, repo, guildId)
+	assert.NoError(t, err)
+	assert.Equal(t, []db.RolePermission{{RoleId: roleId, Level: db.ManagePermission}}, permissions)
+}
+
+func TestRevokePermissionRemovesPermission(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	roleId := "bar"
+	err := repo.SetGuildConfig(ctx, db.NewGuildConfig(guildId))
+	assert.NoError(t, err)
+
+	err = GrantPermission(ctx, repo, guildId, roleId, db.SilencePermission)
+	assert.NoError(t, err)
+
+	// Act
+	err = RevokePermission(ctx, repo, guildId, roleId)
+	assert.NoError(t, err)
+
+	// Assert
+	permissions, err := GetPermissions(ctx, repo, guildId)
+	assert.NoError(t, err)
+	assert.Empty(t, permissions)
+
+	err = RevokePermission(ctx, repo, guildId, roleId)
+	assert.Error(t, err)
+}
+
+func TestHasPermissionDefaultsToManageUntilPermissionsAreGranted(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	err := repo.SetGuildConfig(ctx, db.NewGuildConfig(guildId))
+	assert.NoError(t, err)
+
+	// Act
+	canSilence, err := HasPermission(ctx, repo, guildId, nil, false, db.SilencePermission)
+	assert.NoError(t, err)
+
+	canManage, err := HasPermission(ctx, repo, guildId, nil, false, db.ManagePermission)
+	assert.NoError(t, err)
+
+	canGrant, err := HasPermission(ctx, repo, guildId, nil, false, db.AdminPermission)
+	assert.NoError(t, err)
+
+	adminCanGrant, err := HasPermission(ctx, repo, guildId, nil, true, db.AdminPermission)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.True(t, canSilence)
+	assert.True(t, canManage)
+	assert.False(t, canGrant)
+	assert.True(t, adminCanGrant)
+}
+
+func TestHasPermissionUsesHighestLevelOfRoles(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	err := repo.SetGuildConfig(ctx, db.NewGuildConfig(guildId))
+	assert.NoError(t, err)
+
+	err = GrantPermission(ctx, repo, guildId, "readers", db.ReadPermission)
+	assert.NoError(t, err)
+
+	err = GrantPermission(ctx, repo, guildId, "operators", db.ManagePermission)
+	assert.NoError(t, err)
+
+	// Act
+	readerCanSilence, err := HasPermission(ctx, repo, guildId, []string{"readers"}, false, db.SilencePermission)
+	assert.NoError(t, err)
+
+	operatorCanSilence, err := HasPermission(ctx, repo, guildId, []string{"readers", "operators"}, false, db.SilencePermission)
+	assert.NoError(t, err)
+
+	operatorCanAdmin, err := HasPermission(ctx, repo, guildId, []string{"operators"}, false, db.AdminPermission)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.False(t, readerCanSilence)
+	assert.True(t, operatorCanSilence)
+	assert.False(t, operatorCanAdmin)
+}
+
+// Todo: CreateScrapeConfigCreatesScrapeConfig
+// Todo: CreateScrapeConfigStartsScraper
+
+func TestGetScrapeConfigs(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	configName := "bar"
+	alertName := "zig"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{
+				Name:            configName,
+				Endpoint:        "",
+				Username:        "",
+				Password:        "",
+				AlertChannelId:  "",
+				InhibitedAlerts: []string{alertName},
+			},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	foundScrapeConfigs, err := GetScrapeConfigs(ctx, repo, guildId)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, foundScrapeConfigs, 1)
+	assert.Equal(t, foundScrapeConfigs[0].Name, configName)
+}
+
+func TestGetScrapeStatusesIncludesUnscrapedConfigs(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+	healthTracker := scraper.NewHealthTracker(1)
+
+	guildId := "foo"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: "bar", ScrapeInterval: "30s"}, {Name: "baz"}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	scrapeManager.Start(guildId, &guildConfig.ScrapeConfigs[0])
+	healthTracker.RecordFailure(guildId, "bar", errors.New("connection refused"), time.Now())
+
+	// Act
+	statuses, err := GetScrapeStatuses(ctx, repo, scrapeManager, healthTracker, guildId, "")
+	assert.NoError(t, err)
+
+	_, err = GetScrapeStatuses(ctx, repo, scrapeManager, healthTracker, guildId, "qux")
+	assert.Error(t, err)
+
+	// Assert
+	assert.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Scraped)
+	assert.True(t, statuses[0].Health.Down)
+	assert.True(t, statuses[0].Scheduled)
+	assert.Equal(t, 30*time.Second, statuses[0].Schedule.Interval)
+	assert.False(t, statuses[1].Scraped)
+	assert.False(t, statuses[1].Scheduled)
+}
+
+func TestGetScrapeStatusesUsesStateRecordedByOtherReplicas(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{{Name: "bar", ScrapeInterval: "30s"}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// The scrape config is scraped by another replica, which records its state
+	ownerScrapeManager := &FakeScrapeManager{}
+	ownerHealthTracker := scraper.NewHealthTracker(1)
+	ownerTracker := alerts.NewTracker()
+
+	now := time.Now()
+	ownerScrapeManager.Start(guildId, &guildConfig.ScrapeConfigs[0])
+	ownerHealthTracker.RecordFailure(guildId, "bar", errors.New("connection refused"), now)
+	ownerTracker.Process(guildId, "bar", prometheus.Alerts{{Labels: map[string]string{"alertname": "HighLatency"}}}, now)
+
+	err = RecordScrapeState(ctx, repo, ownerScrapeManager, ownerHealthTracker, ownerTracker, guildId, "bar", now)
+	assert.NoError(t, err)
+
+	// Act
+	statuses, err := GetScrapeStatuses(ctx, repo, &FakeScrapeManager{}, scraper.NewHealthTracker(1), guildId, "")
+	assert.NoError(t, err)
+
+	alertNames, err := GetKnownAlertNames(ctx, repo, alerts.NewTracker(), guildId, "bar")
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Scraped)
+	assert.True(t, statuses[0].Health.Down)
+	assert.Equal(t, "connection refused", statuses[0].Health.LastError)
+	assert.True(t, statuses[0].Scheduled)
+	assert.Equal(t, 30*time.Second, statuses[0].Schedule.Interval)
+	assert.Equal(t, []string{"HighLatency"}, alertNames)
+}
+
+func TestRemoveScrapeConfigRemovesScrapeConfig(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	alertName := "zig"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{
+				Name:            configName,
+				Endpoint:        "",
+				Username:        "",
+				Password:        "",
+				AlertChannelId:  "",
+				InhibitedAlerts: []string{alertName},
+			},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = RemoveScrapeConfig(ctx, repo, scrapeManager, guildId, configName)
+	assert.NoError(t, err)
+
+	// Assert
+	guildConfig, err = repo.GetGuildConfig(ctx, guildId)
+	assert.NoError(t, err)
+	assert.Empty(t, guildConfig.ScrapeConfigs)
+}
+
+func TestRemoveScrapeConfigStopsScraper(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	configName := "bar"
+	alertName := "zig"
+	scrapeConfig := db.ScrapeConfig{
+		Name:            configName,
+		Endpoint:        "",
+		Username:        "",
+		Password:        "",
+		AlertChannelId:  "",
+		InhibitedAlerts: []string{alertName},
+	}
+	guildConfig := &db.GuildConfig{
+		GuildId:       guildId,
+		ScrapeConfigs: []db.ScrapeConfig{scrapeConfig},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	scrapeManager.Start(guildId, &scrapeConfig)
+
+	// Sanity check
+	assert.Len(t, scrapeManager.ActiveScrapers, 1)
+
+	// Act
+	err = RemoveScrapeConfig(ctx, repo, scrapeManager, guildId, configName)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Empty(t, scrapeManager.ActiveScrapers)
+}
+
+func TestRemoveScrapeConfigSucceedsWhenScraperIsRunningElsewhere(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := scraper.NewScrapeManager(nil, 0, 0, logger)
+
+	guildConfig := &db.GuildConfig{
+		GuildId:       "foo",
+		ScrapeConfigs: []db.ScrapeConfig{{Name: "bar"}},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = RemoveScrapeConfig(ctx, repo, scrapeManager, "foo", "bar")
+
+	// Assert
+	assert.NoError(t, err)
+
+	guildConfig, err = repo.GetGuildConfig(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Empty(t, guildConfig.ScrapeConfigs)
+}
+
+func TestMigrateScrapeIntervalsConvertsMinutes(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildId := "foo"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{Name: "bar", ScrapeIntervalMinutes: 5},
+			{Name: "baz", ScrapeInterval: "30s"},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	err = MigrateScrapeIntervals(ctx, repo)
+	assert.NoError(t, err)
+
+	// Assert
+	migratedConfig, err := repo.GetGuildConfig(ctx, guildId)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "5m0s", migratedConfig.ScrapeConfigs[0].ScrapeInterval)
+	assert.Zero(t, migratedConfig.ScrapeConfigs[0].ScrapeIntervalMinutes)
+	assert.Equal(t, 5*time.Minute, migratedConfig.ScrapeConfigs[0].Interval())
+	assert.Equal(t, 30*time.Second, migratedConfig.ScrapeConfigs[1].Interval())
+}
+
+func TestParseScrapeIntervalEnforcesMinimum(t *testing.T) {
+
+	// Arrange
+	min := 10 * time.Second
+
+	// Act
+	interval, err := ParseScrapeInterval("2m30s", min)
+	assert.NoError(t, err)
+
+	_, tooShortErr := ParseScrapeInterval("5s", min)
+	_, zeroErr := ParseScrapeInterval("0s", 0)
+	_, invalidErr := ParseScrapeInterval("5", min)
+
+	// Assert
+	assert.Equal(t, 150*time.Second, interval)
+	assert.Error(t, tooShortErr)
+	assert.Error(t, zeroErr)
+	assert.Error(t, invalidErr)
+}
+
+func TestGetMinScrapeIntervalUsesLongerMinimum(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	err := repo.SetGuildConfig(ctx, db.NewGuildConfig(guildId))
+	assert.NoError(t, err)
+
+	// Act
+	defaultMin, err := GetMinScrapeInterval(ctx, repo, 10*time.Second, guildId)
+	assert.NoError(t, err)
+
+	_, err = SetMinScrapeInterval(ctx, repo, scrapeManager, guildId, time.Minute)
+	assert.NoError(t, err)
+
+	guildMin, err := GetMinScrapeInterval(ctx, repo, 10*time.Second, guildId)
+	assert.NoError(t, err)
+
+	globalMin, err := GetMinScrapeInterval(ctx, repo, 5*time.Minute, guildId)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, 10*time.Second, defaultMin)
+	assert.Equal(t, time.Minute, guildMin)
+	assert.Equal(t, 5*time.Minute, globalMin)
+}
+
+func TestSetMinScrapeIntervalRaisesShorterIntervals(t *testing.T) {
+
+	// Arrange
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	scrapeManager := &FakeScrapeManager{}
+
+	guildId := "foo"
+	guildConfig := &db.GuildConfig{
+		GuildId: guildId,
+		ScrapeConfigs: []db.ScrapeConfig{
+			{Name: "bar", ScrapeInterval: "15s"},
+			{Name: "baz", ScrapeInterval: "2m0s"},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	// Act
+	raised, err := SetMinScrapeInterval(ctx, repo, scrapeManager, guildId, time.Minute)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"bar"}, raised)
+
+	updatedConfig, err := repo.GetGuildConfig(ctx, guildId)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "1m0s", updatedConfig.MinScrapeInterval)
+	assert.Equal(t, time.Minute, updatedConfig.ScrapeConfigs[0].Interval())
+	assert.Equal(t, 2*time.Minute, updatedConfig.ScrapeConfigs[1].Interval())
 }
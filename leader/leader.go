@@ -0,0 +1,205 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/config"
+	"github.com/yukitsune/minialert/db"
+	"sync/atomic"
+	"time"
+)
+
+// LeaseName is the name of the lease held by the leader.
+const LeaseName = "leader"
+
+// releaseTimeout is how long to wait for the lease to be released when stepping down.
+const releaseTimeout = 5 * time.Second
+
+// Elector decides which replica is the leader.
+type Elector interface {
+	// Run campaigns for leadership until the context is done.
+	// Each time this replica becomes the leader, onStartedLeading is called with a context which is cancelled once leadership is lost.
+	// onStartedLeading should block until its context is done, so that the leader's work has stopped before another replica can take over.
+	Run(ctx context.Context, onStartedLeading func(ctx context.Context))
+	IsLeader() bool
+	Id() string
+}
+
+// NewElector creates a lease-based Elector if leader election is enabled.
+// Otherwise, the returned Elector always leads.
+func NewElector(cfg config.LeaderElection, repo db.Repo, logger logrus.FieldLogger) (Elector, error) {
+	if !cfg.Enabled() {
+		return NewStaticElector(), nil
+	}
+
+	id := cfg.Id()
+	if len(id) == 0 {
+		var err error
+		id, err = db.NewReplicaId()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.RenewInterval() <= 0 || cfg.RenewInterval() >= cfg.LeaseDuration() {
+		return nil, fmt.Errorf("leader election renew interval must be positive and shorter than the lease duration")
+	}
+
+	return NewLeaseElector(repo, id, cfg.LeaseDuration(), cfg.RenewInterval(), logger), nil
+}
+
+type leaseElector struct {
+	repo          db.Repo
+	id            string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+	logger        logrus.FieldLogger
+	leading       int32
+}
+
+// NewLeaseElector creates an Elector which leads while it holds the leader lease.
+// The lease is renewed every renewInterval, and can be taken over by another replica once it hasn't been renewed for the lease duration.
+func NewLeaseElector(repo db.Repo, id string, leaseDuration time.Duration, renewInterval time.Duration, logger logrus.FieldLogger) Elector {
+	return &leaseElector{
+		repo:          repo,
+		id:            id,
+		leaseDuration: leaseDuration,
+		renewInterval: renewInterval,
+		logger:        logger.WithField("replica_id", id),
+	}
+}
+
+func (e *leaseElector) Run(ctx context.Context, onStartedLeading func(ctx context.Context)) {
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+	var renewDeadline time.Time
+	var stopLeading func()
+
+	for {
+		// A slow database can't keep this replica leading past the renew deadline
+		attemptCtx, cancel := context.WithTimeout(ctx, e.renewInterval)
+		if stopLeading != nil {
+			cancel()
+			attemptCtx, cancel = context.WithDeadline(ctx, renewDeadline)
+		}
+
+		now := time.Now()
+		acquired, err := e.repo.AcquireLease(attemptCtx, LeaseName, e.id, now, e.leaseDuration)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			e.logger.Errorf("Failed to acquire leader lease: %s", err.Error())
+		}
+
+		// The lease expires relative to when it was requested, rather than when the request returned
+		if err == nil && acquired {
+			renewDeadline = now.Add(e.leaseDuration - e.renewInterval)
+		}
+
+		// If the lease can't be renewed, leadership is given up before the lease expires so that two replicas never lead at once
+		lost := err == nil && !acquired
+		shouldLead := !lost && time.Now().Before(renewDeadline)
+
+		if shouldLead && stopLeading == nil {
+			e.logger.Infoln("👑 Became the leader")
+			stopLeading = e.startLeading(ctx, onStartedLeading)
+		} else if !shouldLead && stopLeading != nil {
+			e.logger.Warnln("Lost leadership")
+			stopLeading()
+			stopLeading = nil
+		}
+
+		// Leadership is given up as soon as the renew deadline passes, rather than at the next tick
+		var stepDown <-chan time.Time
+		var stepDownTimer *time.Timer
+		if stopLeading != nil {
+			stepDownTimer = time.NewTimer(time.Until(renewDeadline))
+			stepDown = stepDownTimer.C
+		}
+
+		select {
+		case <-ticker.C:
+
+		case <-stepDown:
+			e.logger.Warnln("Lost leadership")
+			stopLeading()
+			stopLeading = nil
+
+		case <-ctx.Done():
+			if stopLeading != nil {
+				stopLeading()
+				e.release()
+			}
+
+			return
+		}
+
+		if stepDownTimer != nil {
+			stepDownTimer.Stop()
+		}
+	}
+}
+
+func (e *leaseElector) startLeading(ctx context.Context, onStartedLeading func(ctx context.Context)) func() {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	atomic.StoreInt32(&e.leading, 1)
+	go func() {
+		defer close(done)
+		onStartedLeading(leaderCtx)
+	}()
+
+	return func() {
+		atomic.StoreInt32(&e.leading, 0)
+		cancel()
+		<-done
+	}
+}
+
+// release gives up the lease so that another replica can take over without waiting for it to expire.
+func (e *leaseElector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	err := e.repo.ReleaseLease(ctx, LeaseName, e.id)
+	if err != nil {
+		e.logger.Errorf("Failed to release leader lease: %s", err.Error())
+		return
+	}
+
+	e.logger.Infoln("Released leader lease")
+}
+
+func (e *leaseElector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leading) == 1
+}
+
+func (e *leaseElector) Id() string {
+	return e.id
+}
+
+type staticElector struct {
+	leading int32
+}
+
+// NewStaticElector creates an Elector which always leads, for when only a single replica is run.
+func NewStaticElector() Elector {
+	return &staticElector{}
+}
+
+func (e *staticElector) Run(ctx context.Context, onStartedLeading func(ctx context.Context)) {
+	atomic.StoreInt32(&e.leading, 1)
+	defer atomic.StoreInt32(&e.leading, 0)
+
+	onStartedLeading(ctx)
+}
+
+func (e *staticElector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leading) == 1
+}
+
+func (e *staticElector) Id() string {
+	return ""
+}
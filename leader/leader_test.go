@@ -0,0 +1,161 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testLeaseDuration = 100 * time.Millisecond
+	testRenewInterval = 20 * time.Millisecond
+	testWaitFor       = 2 * time.Second
+)
+
+// FailingRepo fails to acquire leases while it is set to fail.
+type FailingRepo struct {
+	db.Repo
+	failing int32
+}
+
+func (r *FailingRepo) SetFailing(failing bool) {
+	var v int32
+	if failing {
+		v = 1
+	}
+
+	atomic.StoreInt32(&r.failing, v)
+}
+
+func (r *FailingRepo) AcquireLease(ctx context.Context, name string, holderId string, now time.Time, duration time.Duration) (bool, error) {
+	if atomic.LoadInt32(&r.failing) == 1 {
+		return false, errors.New("connection refused")
+	}
+
+	return r.Repo.AcquireLease(ctx, name, holderId, now, duration)
+}
+
+// leaders counts how many electors are leading at once, failing the test if it's ever more than one.
+type leaders struct {
+	t     *testing.T
+	count int32
+}
+
+func (l *leaders) lead(ctx context.Context) {
+	if atomic.AddInt32(&l.count, 1) > 1 {
+		l.t.Error("more than one elector is leading")
+	}
+
+	<-ctx.Done()
+	atomic.AddInt32(&l.count, -1)
+}
+
+func runElector(ctx context.Context, wg *sync.WaitGroup, elector Elector, l *leaders) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		elector.Run(ctx, l.lead)
+	}()
+}
+
+func TestLeaseElectorFailsOverWhenLeaderStops(t *testing.T) {
+
+	// Arrange
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	l := &leaders{t: t}
+
+	first := NewLeaseElector(repo, "foo", testLeaseDuration, testRenewInterval, logger)
+	second := NewLeaseElector(repo, "bar", testLeaseDuration, testRenewInterval, logger)
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+
+	var wg sync.WaitGroup
+	runElector(firstCtx, &wg, first, l)
+	assert.Eventually(t, first.IsLeader, testWaitFor, testRenewInterval)
+
+	runElector(secondCtx, &wg, second, l)
+	time.Sleep(2 * testLeaseDuration)
+
+	secondLedEarly := second.IsLeader()
+
+	// Act
+	stopFirst()
+
+	// Assert
+	assert.False(t, secondLedEarly, "second elector shouldn't lead while the first holds the lease")
+	assert.Eventually(t, second.IsLeader, testWaitFor, testRenewInterval)
+	assert.False(t, first.IsLeader())
+
+	stopSecond()
+	wg.Wait()
+
+	lease, err := repo.GetLease(context.Background(), LeaseName)
+	assert.NoError(t, err)
+	assert.Nil(t, lease, "lease should be released when the leader stops")
+}
+
+func TestLeaseElectorStepsDownWhenLeaseCantBeRenewed(t *testing.T) {
+
+	// Arrange
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	failingRepo := &FailingRepo{Repo: repo}
+	l := &leaders{t: t}
+
+	first := NewLeaseElector(failingRepo, "foo", testLeaseDuration, testRenewInterval, logger)
+	second := NewLeaseElector(repo, "bar", testLeaseDuration, testRenewInterval, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	runElector(ctx, &wg, first, l)
+	assert.Eventually(t, first.IsLeader, testWaitFor, testRenewInterval)
+
+	runElector(ctx, &wg, second, l)
+
+	// Act
+	failingRepo.SetFailing(true)
+
+	// Assert
+	assert.Eventually(t, func() bool { return !first.IsLeader() }, testWaitFor, testRenewInterval)
+	assert.Eventually(t, second.IsLeader, testWaitFor, testRenewInterval)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestStaticElectorAlwaysLeads(t *testing.T) {
+
+	// Arrange
+	elector := NewStaticElector()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	leading := make(chan bool)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		elector.Run(ctx, func(ctx context.Context) {
+			leading <- elector.IsLeader()
+			<-ctx.Done()
+		})
+	}()
+
+	// Act
+	wasLeading := <-leading
+	cancel()
+	<-done
+
+	// Assert
+	assert.True(t, wasLeading)
+	assert.False(t, elector.IsLeader())
+}
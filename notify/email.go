@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/config"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emailTimeout is how long to wait for an email to be sent, consistent with the timeout used for webhooks.
+const emailTimeout = 10 * time.Second
+
+type emailNotifier struct {
+	cfg config.Notifiers
+	to  []string
+}
+
+// NewEmailNotifier creates a Notifier which sends notifications as plain-text emails using the configured SMTP server.
+func NewEmailNotifier(cfg config.Notifiers, to []string) Notifier {
+	return &emailNotifier{
+		cfg: cfg,
+		to:  to,
+	}
+}
+
+func (n *emailNotifier) Notify(ctx context.Context, notification alerts.Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+
+	host := n.cfg.SmtpHost()
+	addr := host + ":" + strconv.Itoa(n.cfg.SmtpPort())
+
+	var auth smtp.Auth
+	if len(n.cfg.SmtpUsername()) > 0 {
+		auth = smtp.PlainAuth("", n.cfg.SmtpUsername(), n.cfg.SmtpPassword(), host)
+	}
+
+	msg := newEmailMessage(n.cfg.SmtpFrom(), n.to, notification, time.Now())
+
+	err := sendMail(ctx, addr, host, auth, n.cfg.SmtpFrom(), n.to, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %s", err.Error())
+	}
+
+	return nil
+}
+
+// newEmailMessage creates a plain-text email for the notification.
+// The subject is encoded, since label values can contain line breaks which would otherwise be treated as the start of another header.
+func newEmailMessage(from string, to []string, notification alerts.Notification, now time.Time) []byte {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(to, ", ")))
+	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("utf-8", Title(notification))))
+	msg.WriteString(fmt.Sprintf("Date: %s\r\n", now.Format(time.RFC1123Z)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(Text(notification), "\n", "\r\n"))
+
+	return []byte(msg.String())
+}
+
+// sendMail sends the email the same way as smtp.SendMail, but gives up once the context is done.
+func sendMail(ctx context.Context, addr string, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	// The SMTP client doesn't accept a context, so the connection is closed to interrupt it instead
+	sent := make(chan struct{})
+	defer close(sent)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-sent:
+		}
+	}()
+
+	err = sendMailUsingConn(conn, host, auth, from, to, msg)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+func sendMailUsingConn(conn net.Conn, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server doesn't support AUTH")
+		}
+
+		err = c.Auth(auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(from)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range to {
+		err = c.Rcpt(addr)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/yukitsune/minialert/alerts"
+	"io"
+	"os"
+	"sync"
+)
+
+type fileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier creates a Notifier which appends each notification to the file at the given path as a line of JSON.
+// If the path is empty, notifications are written to stdout.
+func NewFileNotifier(path string) Notifier {
+	return &fileNotifier{
+		path: path,
+	}
+}
+
+func (n *fileNotifier) Notify(_ context.Context, notification alerts.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.path) == 0 {
+		return writeJsonLine(os.Stdout, notification)
+	}
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %s", err.Error())
+	}
+
+	defer f.Close()
+
+	return writeJsonLine(f, notification)
+}
+
+func writeJsonLine(w io.Writer, notification alerts.Notification) error {
+	b, err := json.Marshal(NewWebhookPayload(notification))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %s", err.Error())
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,181 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/config"
+	"github.com/yukitsune/minialert/db"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notifier sends notifications for groups of alerts to some destination.
+type Notifier interface {
+	Notify(ctx context.Context, notification alerts.Notification) error
+}
+
+// ChannelNotifierFactory creates a Notifier which sends notifications to a specific Discord channel.
+type ChannelNotifierFactory func(channelId string) Notifier
+
+// Factory creates a Notifier from a scrape config's NotifierConfig.
+type Factory func(cfg db.NotifierConfig) (Notifier, error)
+
+// NewFactory creates a Factory for all non-Discord notifier types.
+func NewFactory(cfg config.Notifiers) Factory {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	// The file notifier is shared so that concurrent writes to the same file don't interleave
+	fileNotifier := NewFileNotifier(cfg.FilePath())
+
+	return func(notifierCfg db.NotifierConfig) (Notifier, error) {
+		switch notifierCfg.Type {
+		case db.WebhookNotifier:
+			if err := validateUrl(notifierCfg.Url); err != nil {
+				return nil, err
+			}
+
+			return NewWebhookNotifier(client, notifierCfg.Url), nil
+
+		case db.SlackNotifier:
+			if err := validateUrl(notifierCfg.Url); err != nil {
+				return nil, err
+			}
+
+			return NewSlackNotifier(client, notifierCfg.Url), nil
+
+		case db.EmailNotifier:
+			if len(cfg.SmtpHost()) == 0 {
+				return nil, fmt.Errorf("smtp is not configured")
+			}
+
+			if len(notifierCfg.To) == 0 {
+				return nil, fmt.Errorf("at least one recipient is required")
+			}
+
+			return NewEmailNotifier(cfg, notifierCfg.To), nil
+
+		case db.FileNotifier:
+			return fileNotifier, nil
+
+		default:
+			return nil, fmt.Errorf("unknown notifier type %s", notifierCfg.Type)
+		}
+	}
+}
+
+// Dispatch sends the notification to each of the Discord channels determined by the scrape config's routes, and to each of its notifiers.
+// A failure to send to one destination doesn't prevent sending to the others.
+func Dispatch(ctx context.Context, scrapeConfig *db.ScrapeConfig, notification alerts.Notification, channelNotifierFactory ChannelNotifierFactory, factory Factory) error {
+	var errs []string
+
+	routedNotifications, err := alerts.RouteNotification(notification, scrapeConfig.Routes, scrapeConfig.AlertChannelId)
+	if err != nil {
+		return fmt.Errorf("failed to route notification: %s", err.Error())
+	}
+
+	for channelId, routedNotification := range routedNotifications {
+		if len(channelId) == 0 {
+			continue
+		}
+
+		err = channelNotifierFactory(channelId).Notify(ctx, routedNotification)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("channel %s: %s", channelId, err.Error()))
+		}
+	}
+
+	for _, notifierCfg := range scrapeConfig.Notifiers {
+		notifier, err := factory(notifierCfg)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("notifier %s: %s", notifierCfg.Id, err.Error()))
+			continue
+		}
+
+		err = notifier.Notify(ctx, notification)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("notifier %s: %s", notifierCfg.Id, err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send notification: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// FormatLabels formats the labels as a comma-separated list of key=value pairs, sorted by key.
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+
+	return strings.Join(pairs, ", ")
+}
+
+// Title returns a short summary of the notification, E.g: "[FIRING:2] alertname=Foo".
+func Title(notification alerts.Notification) string {
+	if len(notification.Firing) > 0 {
+		return fmt.Sprintf("[FIRING:%d] %s", len(notification.Firing), FormatLabels(notification.GroupLabels))
+	}
+
+	return fmt.Sprintf("[RESOLVED:%d] %s", len(notification.Resolved), FormatLabels(notification.GroupLabels))
+}
+
+// Text returns a plain-text description of each alert in the notification.
+func Text(notification alerts.Notification) string {
+	var sb strings.Builder
+	writeEvents := func(heading string, events []alerts.Event, resolved bool) {
+		if len(events) == 0 {
+			return
+		}
+
+		sb.WriteString(fmt.Sprintf("%s:\n", heading))
+		for _, event := range events {
+			sb.WriteString(fmt.Sprintf("- %s", FormatLabels(event.Alert.Labels)))
+			if resolved {
+				sb.WriteString(fmt.Sprintf(" (resolved after %s)", event.ResolvedAt.Sub(event.FiringSince).Round(time.Second)))
+			} else {
+				sb.WriteString(fmt.Sprintf(" (firing since %s)", event.FiringSince.Format(time.RFC3339)))
+			}
+
+			sb.WriteString("\n")
+
+			if description := event.Alert.Annotations["description"]; len(description) > 0 {
+				sb.WriteString(fmt.Sprintf("  %s\n", description))
+			}
+		}
+	}
+
+	writeEvents("Firing", notification.Firing, false)
+	writeEvents("Resolved", notification.Resolved, true)
+
+	return sb.String()
+}
+
+func validateUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return fmt.Errorf("invalid url: %s", err.Error())
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url must use http or https")
+	}
+
+	return nil
+}
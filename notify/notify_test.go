@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/config"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/prometheus"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type FakeNotifier struct {
+	Notifications []alerts.Notification
+	Err           error
+}
+
+func (f *FakeNotifier) Notify(_ context.Context, notification alerts.Notification) error {
+	f.Notifications = append(f.Notifications, notification)
+	return f.Err
+}
+
+func newTestNotification() alerts.Notification {
+	return alerts.Notification{
+		GuildId:          "foo",
+		ScrapeConfigName: "bar",
+		GroupKey:         "baz",
+		GroupLabels:      map[string]string{"alertname": "HighLatency"},
+		Firing: []alerts.Event{
+			{
+				Type:        alerts.FiringEvent,
+				Fingerprint: "a",
+				Alert:       prometheus.Alert{Labels: map[string]string{"alertname": "HighLatency", "team": "db"}},
+			},
+			{
+				Type:        alerts.FiringEvent,
+				Fingerprint: "b",
+				Alert:       prometheus.Alert{Labels: map[string]string{"alertname": "HighLatency", "team": "web"}},
+			},
+		},
+	}
+}
+
+func TestDispatchSendsToChannelsAndNotifiers(t *testing.T) {
+
+	// Arrange
+	scrapeConfig := &db.ScrapeConfig{
+		AlertChannelId: "default",
+		Routes: []db.Route{
+			{
+				Matchers:  []db.Matcher{{Name: "team", Type: db.MatchEqual, Value: "db"}},
+				ChannelId: "db",
+			},
+		},
+		Notifiers: []db.NotifierConfig{
+			{Id: "1", Type: db.WebhookNotifier},
+			{Id: "2", Type: db.SlackNotifier},
+		},
+	}
+
+	channelNotifiers := make(map[string]*FakeNotifier)
+	channelNotifierFactory := func(channelId string) Notifier {
+		if _, ok := channelNotifiers[channelId]; !ok {
+			channelNotifiers[channelId] = &FakeNotifier{}
+		}
+
+		return channelNotifiers[channelId]
+	}
+
+	notifiers := make(map[db.NotifierType]*FakeNotifier)
+	factory := func(cfg db.NotifierConfig) (Notifier, error) {
+		notifiers[cfg.Type] = &FakeNotifier{}
+		return notifiers[cfg.Type], nil
+	}
+
+	// Act
+	err := Dispatch(context.Background(), scrapeConfig, newTestNotification(), channelNotifierFactory, factory)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, channelNotifiers, 2)
+	assert.Len(t, channelNotifiers["db"].Notifications[0].Firing, 1)
+	assert.Len(t, channelNotifiers["default"].Notifications[0].Firing, 1)
+
+	assert.Len(t, notifiers, 2)
+	assert.Len(t, notifiers[db.WebhookNotifier].Notifications[0].Firing, 2)
+	assert.Len(t, notifiers[db.SlackNotifier].Notifications[0].Firing, 2)
+}
+
+func TestDispatchContinuesAfterFailure(t *testing.T) {
+
+	// Arrange
+	scrapeConfig := &db.ScrapeConfig{
+		AlertChannelId: "default",
+		Notifiers: []db.NotifierConfig{
+			{Id: "1", Type: db.WebhookNotifier},
+		},
+	}
+
+	channelNotifier := &FakeNotifier{Err: fmt.Errorf("discord is down")}
+	notifier := &FakeNotifier{}
+
+	// Act
+	err := Dispatch(context.Background(), scrapeConfig, newTestNotification(),
+		func(_ string) Notifier { return channelNotifier },
+		func(_ db.NotifierConfig) (Notifier, error) { return notifier, nil })
+
+	// Assert
+	assert.ErrorContains(t, err, "discord is down")
+	assert.Len(t, notifier.Notifications, 1)
+}
+
+func TestWebhookNotifierPostsPayload(t *testing.T) {
+
+	// Arrange
+	var payload WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.Client(), server.URL)
+
+	// Act
+	err := notifier.Notify(context.Background(), newTestNotification())
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "firing", payload.Status)
+	assert.Equal(t, "baz", payload.GroupKey)
+	assert.Len(t, payload.Alerts, 2)
+	assert.Equal(t, "a", payload.Alerts[0].Fingerprint)
+}
+
+func TestFileNotifierAppendsJsonLines(t *testing.T) {
+
+	// Arrange
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	notifier := NewFileNotifier(path)
+
+	// Act
+	assert.NoError(t, notifier.Notify(context.Background(), newTestNotification()))
+	assert.NoError(t, notifier.Notify(context.Background(), newTestNotification()))
+
+	// Assert
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 2)
+
+	var payload WebhookPayload
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &payload))
+	assert.Equal(t, "bar", payload.ScrapeConfigName)
+}
+
+func TestEmailSubjectCantAddHeaders(t *testing.T) {
+
+	// Arrange
+	notification := newTestNotification()
+	notification.GroupLabels = map[string]string{"alertname": "HighLatency\r\nBcc: attacker@example.com"}
+
+	// Act
+	msg := string(newEmailMessage("minialert@example.com", []string{"oncall@example.com"}, notification, time.Now()))
+
+	// Assert
+	headers, _, ok := strings.Cut(msg, "\r\n\r\n")
+	assert.True(t, ok)
+
+	for _, header := range strings.Split(headers, "\r\n") {
+		assert.False(t, strings.HasPrefix(header, "Bcc:"), header)
+	}
+}
+
+func TestEmailNotifierGivesUpOnceContextIsDone(t *testing.T) {
+
+	// Arrange
+	// The server accepts connections but never responds
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	assert.NoError(t, err)
+
+	v := viper.New()
+	v.Set("notifiers.smtp.host", host)
+	v.Set("notifiers.smtp.port", port)
+	notifier := NewEmailNotifier(config.NewConfigProvider(v).Notifiers(), []string{"oncall@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Act
+	start := time.Now()
+	err = notifier.Notify(ctx, newTestNotification())
+
+	// Assert
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), emailTimeout)
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"github.com/yukitsune/minialert/alerts"
+	"net/http"
+)
+
+type slackPayload struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color string `json:"color"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type slackNotifier struct {
+	client *http.Client
+	url    string
+}
+
+// NewSlackNotifier creates a Notifier which sends notifications to a Slack-compatible incoming webhook.
+func NewSlackNotifier(client *http.Client, url string) Notifier {
+	return &slackNotifier{
+		client: client,
+		url:    url,
+	}
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, notification alerts.Notification) error {
+	color := "good"
+	if len(notification.Firing) > 0 {
+		color = "danger"
+	}
+
+	payload := slackPayload{
+		Text: Title(notification),
+		Attachments: []slackAttachment{
+			{
+				Color: color,
+				Title: notification.ScrapeConfigName,
+				Text:  Text(notification),
+			},
+		},
+	}
+
+	return postJson(ctx, n.client, n.url, payload)
+}
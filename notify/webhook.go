@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/yukitsune/minialert/alerts"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebhookPayload is the JSON body sent by the webhook notifier.
+// It's modelled after Alertmanager's webhook payload so that existing receivers can be reused.
+type WebhookPayload struct {
+	Version          string            `json:"version"`
+	GroupKey         string            `json:"groupKey"`
+	Status           string            `json:"status"`
+	GuildId          string            `json:"guildId"`
+	ScrapeConfigName string            `json:"scrapeConfigName"`
+	GroupLabels      map[string]string `json:"groupLabels"`
+	Alerts           []WebhookAlert    `json:"alerts"`
+}
+
+type WebhookAlert struct {
+	Status      string            `json:"status"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+	Fingerprint string            `json:"fingerprint"`
+}
+
+func NewWebhookPayload(notification alerts.Notification) WebhookPayload {
+	payload := WebhookPayload{
+		Version:          "1",
+		GroupKey:         notification.GroupKey,
+		Status:           string(alerts.ResolvedEvent),
+		GuildId:          notification.GuildId,
+		ScrapeConfigName: notification.ScrapeConfigName,
+		GroupLabels:      notification.GroupLabels,
+		Alerts:           []WebhookAlert{},
+	}
+
+	if len(notification.Firing) > 0 {
+		payload.Status = string(alerts.FiringEvent)
+	}
+
+	for _, event := range notification.Firing {
+		payload.Alerts = append(payload.Alerts, newWebhookAlert(event, alerts.FiringEvent))
+	}
+
+	for _, event := range notification.Resolved {
+		payload.Alerts = append(payload.Alerts, newWebhookAlert(event, alerts.ResolvedEvent))
+	}
+
+	return payload
+}
+
+func newWebhookAlert(event alerts.Event, status alerts.EventType) WebhookAlert {
+	return WebhookAlert{
+		Status:      string(status),
+		Labels:      event.Alert.Labels,
+		Annotations: event.Alert.Annotations,
+		StartsAt:    event.FiringSince,
+		EndsAt:      event.ResolvedAt,
+		Fingerprint: event.Fingerprint,
+	}
+}
+
+type webhookNotifier struct {
+	client *http.Client
+	url    string
+}
+
+func NewWebhookNotifier(client *http.Client, url string) Notifier {
+	return &webhookNotifier{
+		client: client,
+		url:    url,
+	}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, notification alerts.Notification) error {
+	return postJson(ctx, n.client, n.url, NewWebhookPayload(notification))
+}
+
+func postJson(ctx context.Context, client *http.Client, rawUrl string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal body: %s", err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawUrl, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		// The URL is left out of the error, as webhook URLs often contain tokens
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("request failed: %s", urlErr.Err.Error())
+		}
+
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// AlertmanagerAlert is an alert as returned by the Alertmanager /api/v2/alerts API.
+type AlertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+	Status      struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// alertmanagerClient gets alerts from the Alertmanager API.
+// Alerts which have been silenced or inhibited by Alertmanager are excluded.
+type alertmanagerClient struct {
+	httpClient
+}
+
+func NewAlertmanagerClient(client http.Client, endpoint string, opts ClientOptions) Client {
+	return &alertmanagerClient{
+		httpClient: httpClient{
+			client:   client,
+			endpoint: endpoint,
+			opts:     opts,
+		},
+	}
+}
+
+func (c *alertmanagerClient) GetAlerts(ctx context.Context) (Alerts, error) {
+	endpoint, err := withQuery(c.endpoint, map[string]string{
+		"active":    "true",
+		"silenced":  "false",
+		"inhibited": "false",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resData []AlertmanagerAlert
+	err = c.getJson(ctx, endpoint, &resData)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := Alerts{}
+	for _, alert := range resData {
+		alerts = append(alerts, Alert{
+			ActiveAt:    alert.StartsAt,
+			Annotations: alert.Annotations,
+			Labels:      alert.Labels,
+			State:       "firing",
+		})
+	}
+
+	return alerts, nil
+}
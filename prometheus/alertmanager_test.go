@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAlertmanagerSourceGetsActiveAlerts(t *testing.T) {
+
+	// Arrange
+	var req *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		_, _ = w.Write([]byte(`[{"labels":{"alertname":"Foo"},"annotations":{"description":"Bar"},"startsAt":"2022-01-02T03:04:05Z","status":{"state":"active"}}]`))
+	}))
+	defer server.Close()
+
+	scrapeConfig := &db.ScrapeConfig{
+		Endpoint:   server.URL,
+		SourceType: db.AlertmanagerSource,
+	}
+
+	client, err := NewClientFromScrapeConfig(scrapeConfig, nil)
+	assert.NoError(t, err)
+
+	// Act
+	alerts, err := client.GetAlerts(context.Background())
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "/api/v2/alerts", req.URL.Path)
+	assert.Equal(t, "false", req.URL.Query().Get("silenced"))
+	assert.Equal(t, "false", req.URL.Query().Get("inhibited"))
+
+	expected := Alerts{
+		{
+			ActiveAt:    time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+			Annotations: map[string]string{"description": "Bar"},
+			Labels:      map[string]string{"alertname": "Foo"},
+			State:       "firing",
+		},
+	}
+
+	assert.Equal(t, expected, alerts)
+}
+
+func TestGetAlertsFailsOnErrorStatus(t *testing.T) {
+
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client, err := NewClientFromScrapeConfig(&db.ScrapeConfig{Endpoint: server.URL, SourceType: db.AlertmanagerSource}, nil)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = client.GetAlerts(context.Background())
+
+	// Assert
+	assert.Error(t, err)
+}
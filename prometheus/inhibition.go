@@ -0,0 +1,81 @@
+package prometheus
+
+import (
+	"fmt"
+	"github.com/yukitsune/minialert/db"
+)
+
+// InhibitAlerts removes any alerts which have been inhibited by another alert according to the given inhibition rules.
+func InhibitAlerts(alerts Alerts, rules []db.InhibitionRule) (Alerts, error) {
+
+	var newAlerts Alerts
+	for _, alert := range alerts {
+		inhibited, err := IsInhibited(alert, alerts, rules)
+		if err != nil {
+			return nil, err
+		}
+
+		if !inhibited {
+			newAlerts = append(newAlerts, alert)
+		}
+	}
+
+	return newAlerts, nil
+}
+
+// IsInhibited determines whether the alert is inhibited by any of the given firing alerts.
+func IsInhibited(alert Alert, firingAlerts Alerts, rules []db.InhibitionRule) (bool, error) {
+	for _, rule := range rules {
+		isTarget, err := MatchesAll(rule.TargetMatchers, alert.Labels)
+		if err != nil {
+			return false, fmt.Errorf("failed to evaluate inhibition rule %s: %s", rule.Id, err.Error())
+		}
+
+		if !isTarget {
+			continue
+		}
+
+		for _, source := range firingAlerts {
+			isSource, err := MatchesAll(rule.SourceMatchers, source.Labels)
+			if err != nil {
+				return false, fmt.Errorf("failed to evaluate inhibition rule %s: %s", rule.Id, err.Error())
+			}
+
+			// An alert can't inhibit itself
+			if !isSource || isSameAlert(source, alert) {
+				continue
+			}
+
+			if hasEqualLabels(source, alert, rule.Equal) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// hasEqualLabels determines whether both alerts have the same values for the given labels.
+func hasEqualLabels(a Alert, b Alert, labels []string) bool {
+	for _, label := range labels {
+		if a.Labels[label] != b.Labels[label] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isSameAlert(a Alert, b Alert) bool {
+	if len(a.Labels) != len(b.Labels) {
+		return false
+	}
+
+	for k, v := range a.Labels {
+		if bv, ok := b.Labels[k]; !ok || bv != v {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,75 @@
+package prometheus
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"testing"
+)
+
+var nodeDownRule = db.InhibitionRule{
+	SourceMatchers: []db.Matcher{
+		{Name: "alertname", Type: db.MatchEqual, Value: "NodeDown"},
+		{Name: "severity", Type: db.MatchEqual, Value: "critical"},
+	},
+	TargetMatchers: []db.Matcher{
+		{Name: "alertname", Type: db.MatchEqual, Value: "HighLatency"},
+		{Name: "severity", Type: db.MatchEqual, Value: "warning"},
+	},
+	Equal: []string{"instance"},
+}
+
+func TestInhibitAlertsInhibitsTargetsWithEqualLabels(t *testing.T) {
+
+	// Arrange
+	alerts := Alerts{
+		Alert{Labels: map[string]string{"alertname": "NodeDown", "severity": "critical", "instance": "a"}},
+		Alert{Labels: map[string]string{"alertname": "HighLatency", "severity": "warning", "instance": "a"}},
+		Alert{Labels: map[string]string{"alertname": "HighLatency", "severity": "warning", "instance": "b"}},
+	}
+
+	// Act
+	filtered, err := InhibitAlerts(alerts, []db.InhibitionRule{nodeDownRule})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, filtered, 2)
+	assert.Equal(t, "NodeDown", filtered[0].Labels["alertname"])
+	assert.Equal(t, "b", filtered[1].Labels["instance"])
+}
+
+func TestInhibitAlertsRequiresSourceToBeFiring(t *testing.T) {
+
+	// Arrange
+	alerts := Alerts{
+		Alert{Labels: map[string]string{"alertname": "HighLatency", "severity": "warning", "instance": "a"}},
+	}
+
+	// Act
+	filtered, err := InhibitAlerts(alerts, []db.InhibitionRule{nodeDownRule})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, filtered, 1)
+}
+
+func TestInhibitAlertsDoesNotInhibitItself(t *testing.T) {
+
+	// Arrange
+	rule := db.InhibitionRule{
+		SourceMatchers: []db.Matcher{{Name: "severity", Type: db.MatchEqual, Value: "critical"}},
+		TargetMatchers: []db.Matcher{{Name: "severity", Type: db.MatchRegexp, Value: "warning|critical"}},
+	}
+
+	alerts := Alerts{
+		Alert{Labels: map[string]string{"alertname": "NodeDown", "severity": "critical"}},
+		Alert{Labels: map[string]string{"alertname": "HighLatency", "severity": "warning"}},
+	}
+
+	// Act
+	filtered, err := InhibitAlerts(alerts, []db.InhibitionRule{rule})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "NodeDown", filtered[0].Labels["alertname"])
+}
@@ -0,0 +1,110 @@
+package prometheus
+
+import (
+	"fmt"
+	"github.com/yukitsune/minialert/db"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var matcherRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*("(?:[^"\\]|\\.)*"|[^,"]*?)\s*(?:,|$)`)
+
+// ParseMatchers parses a comma-separated list of label matchers, E.g: `alertname="Foo", severity=~"warning|critical"`.
+// The surrounding braces are optional, as are the quotes around values which don't contain commas.
+func ParseMatchers(s string) ([]db.Matcher, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "{")
+	s = strings.TrimSuffix(s, "}")
+
+	var matchers []db.Matcher
+	for len(strings.TrimSpace(s)) > 0 {
+		parts := matcherRegexp.FindStringSubmatch(s)
+		if parts == nil {
+			return nil, fmt.Errorf("invalid matcher: %s", s)
+		}
+
+		value := parts[3]
+		if strings.HasPrefix(value, "\"") {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid matcher value %s: %s", value, err.Error())
+			}
+
+			value = unquoted
+		}
+
+		matcher := db.Matcher{
+			Name:  parts[1],
+			Type:  db.MatchType(parts[2]),
+			Value: value,
+		}
+
+		if matcher.Type == db.MatchRegexp || matcher.Type == db.MatchNotRegexp {
+			if _, err := compileMatcherRegexp(matcher.Value); err != nil {
+				return nil, fmt.Errorf("invalid regular expression in %s: %s", matcher, err.Error())
+			}
+		}
+
+		matchers = append(matchers, matcher)
+		s = s[len(parts[0]):]
+	}
+
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+
+	return matchers, nil
+}
+
+// Matches determines whether the given labels satisfy the matcher.
+// Missing labels are treated as empty strings, consistent with Prometheus.
+func Matches(matcher db.Matcher, labels map[string]string) (bool, error) {
+	value := labels[matcher.Name]
+
+	switch matcher.Type {
+	case db.MatchEqual:
+		return value == matcher.Value, nil
+	case db.MatchNotEqual:
+		return value != matcher.Value, nil
+	case db.MatchRegexp, db.MatchNotRegexp:
+		re, err := compileMatcherRegexp(matcher.Value)
+		if err != nil {
+			return false, err
+		}
+
+		return re.MatchString(value) == (matcher.Type == db.MatchRegexp), nil
+	default:
+		return false, fmt.Errorf("unknown match type %s", matcher.Type)
+	}
+}
+
+// MatchesAll determines whether the given labels satisfy every matcher.
+func MatchesAll(matchers []db.Matcher, labels map[string]string) (bool, error) {
+	for _, matcher := range matchers {
+		ok, err := Matches(matcher, labels)
+		if err != nil {
+			return false, err
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func FormatMatchers(matchers []db.Matcher) string {
+	var strs []string
+	for _, matcher := range matchers {
+		strs = append(strs, matcher.String())
+	}
+
+	return strings.Join(strs, ", ")
+}
+
+func compileMatcherRegexp(value string) (*regexp.Regexp, error) {
+	// Regular expressions are fully anchored, consistent with Prometheus
+	return regexp.Compile(fmt.Sprintf("^(?:%s)$", value))
+}
@@ -0,0 +1,93 @@
+package prometheus
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"testing"
+	"time"
+)
+
+func TestParseMatchersParsesAllMatchTypes(t *testing.T) {
+
+	// Arrange
+	input := `{alertname="Foo, Bar", severity=~"warning|critical", instance!="a", job!~node.*}`
+
+	// Act
+	matchers, err := ParseMatchers(input)
+	assert.NoError(t, err)
+
+	// Assert
+	expected := []db.Matcher{
+		{Name: "alertname", Type: db.MatchEqual, Value: "Foo, Bar"},
+		{Name: "severity", Type: db.MatchRegexp, Value: "warning|critical"},
+		{Name: "instance", Type: db.MatchNotEqual, Value: "a"},
+		{Name: "job", Type: db.MatchNotRegexp, Value: "node.*"},
+	}
+
+	assert.Equal(t, expected, matchers)
+}
+
+func TestParseMatchersRejectsInvalidMatchers(t *testing.T) {
+
+	inputs := []string{
+		"",
+		"alertname",
+		`alertname=~"("`,
+		`1alertname="foo"`,
+	}
+
+	for _, input := range inputs {
+		// Act
+		_, err := ParseMatchers(input)
+
+		// Assert
+		assert.Error(t, err, input)
+	}
+}
+
+func TestMatchesAnchorsRegularExpressions(t *testing.T) {
+
+	// Arrange
+	matcher := db.Matcher{Name: "severity", Type: db.MatchRegexp, Value: "warn"}
+
+	// Act
+	partial, err := Matches(matcher, map[string]string{"severity": "warning"})
+	assert.NoError(t, err)
+
+	exact, err := Matches(matcher, map[string]string{"severity": "warn"})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.False(t, partial)
+	assert.True(t, exact)
+}
+
+func TestFilterAlertsIgnoresExpiredSilences(t *testing.T) {
+
+	// Arrange
+	now := time.Now()
+	silences := []db.Silence{
+		{
+			Matchers: []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "foo"}},
+			StartsAt: now.Add(-2 * time.Hour),
+			EndsAt:   now.Add(-time.Hour),
+		},
+		{
+			Matchers: []db.Matcher{{Name: "alertname", Type: db.MatchEqual, Value: "bar"}},
+			StartsAt: now.Add(-2 * time.Hour),
+		},
+	}
+
+	alerts := Alerts{
+		Alert{Labels: map[string]string{"alertname": "foo"}},
+		Alert{Labels: map[string]string{"alertname": "bar"}},
+	}
+
+	// Act
+	filtered, err := FilterAlerts(alerts, silences, now)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "foo", filtered[0].Labels["alertname"])
+}
@@ -2,11 +2,17 @@ package prometheus
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"fmt"
+	"github.com/yukitsune/minialert/config"
 	"github.com/yukitsune/minialert/db"
-	"github.com/yukitsune/minialert/slices"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -25,6 +31,9 @@ type Alert struct {
 	Labels      map[string]string
 	State       string
 	Value       string
+
+	// RuleGroup is the name of the rule group the alert belongs to, if known.
+	RuleGroup string
 }
 
 type BasicAuthDetails struct {
@@ -32,66 +41,310 @@ type BasicAuthDetails struct {
 	Password string
 }
 
+// ClientOptions determine how requests to the endpoint are authenticated.
+type ClientOptions struct {
+	BasicAuth   *BasicAuthDetails
+	BearerToken string
+	Headers     map[string]string
+}
+
 type Client interface {
-	GetAlerts() (Alerts, error)
+	GetAlerts(ctx context.Context) (Alerts, error)
 }
 
-type ClientFactory func(config *db.ScrapeConfig) Client
+type ClientFactory func(config *db.ScrapeConfig) (Client, error)
 
 type httpClient struct {
-	client           http.Client
-	endpoint         string
-	basicAuthDetails *BasicAuthDetails
+	client   http.Client
+	endpoint string
+	opts     ClientOptions
 }
 
 func NewPrometheusClient(client http.Client, endpoint string) Client {
-	return NewPrometheusClientWithBasicAuth(client, endpoint, nil)
+	return NewPrometheusClientWithOptions(client, endpoint, ClientOptions{})
 }
 
 func NewPrometheusClientWithBasicAuth(client http.Client, endpoint string, creds *BasicAuthDetails) Client {
+	return NewPrometheusClientWithOptions(client, endpoint, ClientOptions{BasicAuth: creds})
+}
+
+func NewPrometheusClientWithOptions(client http.Client, endpoint string, opts ClientOptions) Client {
 	return &httpClient{
-		client:           client,
-		endpoint:         endpoint,
-		basicAuthDetails: creds,
+		client:   client,
+		endpoint: endpoint,
+		opts:     opts,
 	}
 }
 
-func NewClientFromScrapeConfig(config *db.ScrapeConfig) Client {
+// NewClientFactory creates a ClientFactory which uses the given defaults for any settings the scrape config doesn't specify.
+func NewClientFactory(defaults config.Prometheus) ClientFactory {
+	return func(scrapeConfig *db.ScrapeConfig) (Client, error) {
+		return NewClientFromScrapeConfig(scrapeConfig, defaults)
+	}
+}
+
+// DefaultTimeout is how long to wait for an endpoint to respond when neither the scrape config nor the defaults specify a timeout.
+const DefaultTimeout = 10 * time.Second
+
+// TenantHeader is the header used by Mimir and Cortex to determine which tenant a request is for.
+const TenantHeader = "X-Scope-OrgID"
+
+// NewClientFromScrapeConfig creates a Client for the scrape config's source type.
+// If the endpoint doesn't have a path, the default path for the source type is used.
+// The default headers and certificates are only used if the endpoint's host is trusted.
+func NewClientFromScrapeConfig(scrapeConfig *db.ScrapeConfig, defaults config.Prometheus) (Client, error) {
+	trusted, err := isTrustedHost(scrapeConfig.Endpoint, defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := NewTLSConfig(scrapeConfig.TLS, defaults, trusted)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := scrapeConfig.Timeout()
+	if timeout <= 0 && defaults != nil {
+		timeout = defaults.Timeout()
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
 	client := http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
 	}
 
-	if len(config.Username) > 0 && len(config.Password) > 0 {
-		return NewPrometheusClientWithBasicAuth(client, config.Endpoint, &BasicAuthDetails{Username: config.Username, Password: config.Password})
+	opts := ClientOptions{
+		BearerToken: scrapeConfig.BearerToken,
+		Headers:     make(map[string]string),
+	}
+
+	if len(scrapeConfig.Username) > 0 && len(scrapeConfig.Password) > 0 {
+		opts.BasicAuth = &BasicAuthDetails{Username: scrapeConfig.Username, Password: scrapeConfig.Password}
+	}
+
+	if trusted {
+		for name, value := range defaults.Headers() {
+			opts.Headers[name] = value
+		}
+	}
+
+	for name, value := range scrapeConfig.Headers {
+		opts.Headers[name] = value
+	}
+
+	if len(scrapeConfig.TenantId) > 0 {
+		opts.Headers[TenantHeader] = scrapeConfig.TenantId
+	}
+
+	switch scrapeConfig.SourceType {
+	case db.PrometheusSource, "":
+		endpoint, err := withDefaultPath(scrapeConfig.Endpoint, "/api/v1/alerts")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewPrometheusClientWithOptions(client, endpoint, opts), nil
+
+	case db.PrometheusRulesSource:
+		endpoint, err := withDefaultPath(scrapeConfig.Endpoint, "/api/v1/rules")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRulesClient(client, endpoint, opts), nil
+
+	case db.AlertmanagerSource:
+		endpoint, err := withDefaultPath(scrapeConfig.Endpoint, "/api/v2/alerts")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewAlertmanagerClient(client, endpoint, opts), nil
+
+	case db.MimirSource:
+		endpoint, err := withDefaultPath(scrapeConfig.Endpoint, "/prometheus/api/v1/rules")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRulesClient(client, endpoint, opts), nil
+
+	default:
+		return nil, fmt.Errorf("unknown source type %s", scrapeConfig.SourceType)
 	}
+}
 
-	return NewPrometheusClient(client, config.Endpoint)
+// IsValidSourceType returns true if a Client can be created for the source type.
+func IsValidSourceType(sourceType db.SourceType) bool {
+	switch sourceType {
+	case db.PrometheusSource, db.PrometheusRulesSource, db.AlertmanagerSource, db.MimirSource:
+		return true
+	default:
+		return false
+	}
 }
 
-func (c *httpClient) GetAlerts() (Alerts, error) {
-	req, err := http.NewRequest("GET", c.endpoint, bytes.NewReader([]byte{}))
+func withDefaultPath(rawUrl string, path string) (string, error) {
+	u, err := url.Parse(rawUrl)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("invalid endpoint: %s", err.Error())
 	}
 
-	if c.basicAuthDetails != nil {
-		req.SetBasicAuth(c.basicAuthDetails.Username, c.basicAuthDetails.Password)
+	if len(u.Path) == 0 || u.Path == "/" {
+		u.Path = path
 	}
 
-	res, err := c.client.Do(req)
+	return u.String(), nil
+}
+
+// withQuery adds the query parameters to the URL, unless they've already been set.
+func withQuery(rawUrl string, params map[string]string) (string, error) {
+	u, err := url.Parse(rawUrl)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("invalid endpoint: %s", err.Error())
 	}
 
-	defer res.Body.Close()
+	query := u.Query()
+	for name, value := range params {
+		if !query.Has(name) {
+			query.Set(name, value)
+		}
+	}
 
-	resBytes, err := ioutil.ReadAll(res.Body)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// isTrustedHost returns true if the endpoint's host is one of the trusted hosts in the defaults.
+// Hosts can be trusted with or without a port.
+func isTrustedHost(endpoint string, defaults config.Prometheus) (bool, error) {
+	if defaults == nil {
+		return false, nil
+	}
+
+	u, err := url.Parse(endpoint)
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("invalid endpoint: %s", err.Error())
+	}
+
+	for _, host := range defaults.TrustedHosts() {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// NewTLSConfig creates a tls.Config from the scrape config's certificates.
+// If the endpoint's host is trusted, the files specified in the defaults are used for any certificates the scrape config doesn't have.
+func NewTLSConfig(tlsCfg db.TLSConfig, defaults config.Prometheus, trusted bool) (*tls.Config, error) {
+	var caFile, certFile, keyFile string
+	if defaults != nil && trusted {
+		caFile = defaults.CaFile()
+		certFile = defaults.CertFile()
+		keyFile = defaults.KeyFile()
+	}
+
+	insecureSkipVerify := false
+	if tlsCfg.InsecureSkipVerify != nil {
+		insecureSkipVerify = *tlsCfg.InsecureSkipVerify
+	} else if defaults != nil {
+		insecureSkipVerify = defaults.InsecureSkipVerify()
 	}
 
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	caCert := []byte(tlsCfg.CaCert)
+	if len(caCert) == 0 && len(caFile) > 0 {
+		b, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %s", err.Error())
+		}
+
+		caCert = b
+	}
+
+	if len(caCert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("ca certificate doesn't contain any valid PEM encoded certificates")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(tlsCfg.ClientCert) > 0 || len(tlsCfg.ClientKey) > 0 {
+		cert, err := tls.X509KeyPair([]byte(tlsCfg.ClientCert), []byte(tlsCfg.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %s", err.Error())
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err.Error())
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// ParseHeaders parses a comma-separated list of headers, E.g: "X-Scope-OrgID=tenant-1, X-Foo=bar".
+// Headers with no value are kept so that they can be used to remove existing headers.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("header \"%s\" must be in the format name=value", pair)
+		}
+
+		name = strings.TrimSpace(name)
+		if !isValidHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name \"%s\"", name)
+		}
+
+		headers[name] = strings.TrimSpace(value)
+	}
+
+	return headers, nil
+}
+
+func isValidHeaderName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune("()<>@,;:\\\"/[]?={}", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *httpClient) GetAlerts(ctx context.Context) (Alerts, error) {
 	var resData Response
-	err = json.Unmarshal(resBytes, &resData)
+	err := c.getJson(ctx, c.endpoint, &resData)
 	if err != nil {
 		return nil, err
 	}
@@ -99,17 +352,77 @@ func (c *httpClient) GetAlerts() (Alerts, error) {
 	return resData.Data.Alerts, nil
 }
 
-func FilterAlerts(alerts Alerts, inhibitedAlerts []string) (Alerts, error) {
+// getJson sends an authenticated GET request to the URL, and decodes the JSON response into v.
+func (c *httpClient) getJson(ctx context.Context, rawUrl string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawUrl, bytes.NewReader([]byte{}))
+	if err != nil {
+		return err
+	}
+
+	for name, value := range c.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if c.opts.BasicAuth != nil {
+		req.SetBasicAuth(c.opts.BasicAuth.Username, c.opts.BasicAuth.Password)
+	}
+
+	if len(c.opts.BearerToken) > 0 {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.opts.BearerToken))
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	resBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(resBytes, v)
+}
+
+// FilterAlerts removes any alerts which have been silenced by an active silence.
+func FilterAlerts(alerts Alerts, silences []db.Silence, now time.Time) (Alerts, error) {
 
 	var newAlerts Alerts
 	for _, alert := range alerts {
-		if !slices.HasMatching(inhibitedAlerts, func(inhibitedAlert string) bool {
-			alertName := alert.Labels["alertname"]
-			return inhibitedAlert == alertName
-		}) {
+		silenced, err := IsSilenced(alert, silences, now)
+		if err != nil {
+			return nil, err
+		}
+
+		if !silenced {
 			newAlerts = append(newAlerts, alert)
 		}
 	}
 
 	return newAlerts, nil
 }
+
+func IsSilenced(alert Alert, silences []db.Silence, now time.Time) (bool, error) {
+	for _, silence := range silences {
+		if !silence.IsActive(now) {
+			continue
+		}
+
+		matches, err := MatchesAll(silence.Matchers, alert.Labels)
+		if err != nil {
+			return false, fmt.Errorf("failed to evaluate silence %s: %s", silence.Id, err.Error())
+		}
+
+		if matches {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
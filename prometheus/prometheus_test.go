@@ -0,0 +1,236 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/pem"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/config"
+	"github.com/yukitsune/minialert/db"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const alertsResponse = `{"status":"success","data":{"alerts":[{"labels":{"alertname":"Foo"},"state":"firing"}]}}`
+
+func TestGetAlertsSendsBearerTokenAndHeaders(t *testing.T) {
+
+	// Arrange
+	var req *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		_, _ = w.Write([]byte(alertsResponse))
+	}))
+	defer server.Close()
+
+	v := viper.New()
+	v.Set("prometheus.trustedHosts", "other.internal, "+strings.TrimPrefix(server.URL, "http://"))
+	v.Set("prometheus.headers", map[string]string{"X-Foo": "default", "X-Bar": "default"})
+
+	scrapeConfig := &db.ScrapeConfig{
+		Endpoint:    server.URL,
+		BearerToken: "hunter2",
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant-1", "X-Foo": "override"},
+	}
+
+	client, err := NewClientFromScrapeConfig(scrapeConfig, config.NewConfigProvider(v).Prometheus())
+	assert.NoError(t, err)
+
+	// Act
+	alerts, err := client.GetAlerts(context.Background())
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "Bearer hunter2", req.Header.Get("Authorization"))
+	assert.Equal(t, "tenant-1", req.Header.Get("X-Scope-OrgID"))
+	assert.Equal(t, "override", req.Header.Get("X-Foo"))
+	assert.Equal(t, "default", req.Header.Get("X-Bar"))
+}
+
+func TestGetAlertsTrustsCaCert(t *testing.T) {
+
+	// Arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(alertsResponse))
+	}))
+	defer server.Close()
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	insecureSkipVerify := true
+
+	untrustedClient, err := NewClientFromScrapeConfig(&db.ScrapeConfig{Endpoint: server.URL}, nil)
+	assert.NoError(t, err)
+
+	trustedClient, err := NewClientFromScrapeConfig(&db.ScrapeConfig{
+		Endpoint: server.URL,
+		TLS:      db.TLSConfig{CaCert: string(caCert)},
+	}, nil)
+	assert.NoError(t, err)
+
+	insecureClient, err := NewClientFromScrapeConfig(&db.ScrapeConfig{
+		Endpoint: server.URL,
+		TLS:      db.TLSConfig{InsecureSkipVerify: &insecureSkipVerify},
+	}, nil)
+	assert.NoError(t, err)
+
+	// Act
+	_, untrustedErr := untrustedClient.GetAlerts(context.Background())
+	_, trustedErr := trustedClient.GetAlerts(context.Background())
+	_, insecureErr := insecureClient.GetAlerts(context.Background())
+
+	// Assert
+	assert.Error(t, untrustedErr)
+	assert.NoError(t, trustedErr)
+	assert.NoError(t, insecureErr)
+}
+
+func TestGetAlertsOnlySendsDefaultHeadersToTrustedHosts(t *testing.T) {
+
+	// Arrange
+	var req *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		_, _ = w.Write([]byte(alertsResponse))
+	}))
+	defer server.Close()
+
+	v := viper.New()
+	v.Set("prometheus.trustedHosts", "prometheus.internal")
+	v.Set("prometheus.headers", map[string]string{"X-Foo": "default"})
+
+	client, err := NewClientFromScrapeConfig(&db.ScrapeConfig{Endpoint: server.URL}, config.NewConfigProvider(v).Prometheus())
+	assert.NoError(t, err)
+
+	// Act
+	_, err = client.GetAlerts(context.Background())
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Empty(t, req.Header.Get("X-Foo"))
+}
+
+func TestNewTLSConfigPrefersScrapeConfigsInsecureSkipVerify(t *testing.T) {
+
+	// Arrange
+	v := viper.New()
+	v.Set("prometheus.tls.insecureSkipVerify", true)
+	defaults := config.NewConfigProvider(v).Prometheus()
+
+	verify := false
+
+	// Act
+	defaultConfig, err := NewTLSConfig(db.TLSConfig{}, defaults, false)
+	assert.NoError(t, err)
+
+	overriddenConfig, err := NewTLSConfig(db.TLSConfig{InsecureSkipVerify: &verify}, defaults, false)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.True(t, defaultConfig.InsecureSkipVerify)
+	assert.False(t, overriddenConfig.InsecureSkipVerify)
+}
+
+func TestNewTLSConfigRejectsInvalidCertificates(t *testing.T) {
+
+	tlsConfigs := []db.TLSConfig{
+		{CaCert: "foo"},
+		{ClientCert: "foo", ClientKey: "bar"},
+	}
+
+	for _, tlsConfig := range tlsConfigs {
+		// Act
+		_, err := NewTLSConfig(tlsConfig, nil, false)
+
+		// Assert
+		assert.Error(t, err)
+	}
+}
+
+func TestParseHeadersParsesHeaders(t *testing.T) {
+
+	// Arrange
+	input := "X-Scope-OrgID=tenant-1, X-Foo=a=b, X-Bar="
+
+	// Act
+	headers, err := ParseHeaders(input)
+	assert.NoError(t, err)
+
+	// Assert
+	expected := map[string]string{
+		"X-Scope-OrgID": "tenant-1",
+		"X-Foo":         "a=b",
+		"X-Bar":         "",
+	}
+
+	assert.Equal(t, expected, headers)
+}
+
+func TestParseHeadersRejectsInvalidHeaders(t *testing.T) {
+
+	inputs := []string{
+		"X-Foo",
+		"=bar",
+		"X Foo=bar",
+		"X-Foo:=bar",
+	}
+
+	for _, input := range inputs {
+		// Act
+		_, err := ParseHeaders(input)
+
+		// Assert
+		assert.Error(t, err, input)
+	}
+}
+
+func TestGetAlertsTimesOut(t *testing.T) {
+
+	// Arrange
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = w.Write([]byte(alertsResponse))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClientFromScrapeConfig(&db.ScrapeConfig{
+		Endpoint:      server.URL,
+		ScrapeTimeout: "10ms",
+	}, nil)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = client.GetAlerts(context.Background())
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestGetAlertsIsCancelledByContext(t *testing.T) {
+
+	// Arrange
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = w.Write([]byte(alertsResponse))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClientFromScrapeConfig(&db.ScrapeConfig{Endpoint: server.URL}, nil)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	// Act
+	_, err = client.GetAlerts(ctx)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+}
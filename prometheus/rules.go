@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+)
+
+type RulesResponse struct {
+	Data struct {
+		Groups []RuleGroup `json:"groups"`
+	} `json:"data"`
+	Status string `json:"status"`
+}
+
+type RuleGroup struct {
+	Name  string `json:"name"`
+	File  string `json:"file"`
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Alerts []Alert `json:"alerts"`
+}
+
+// rulesClient gets alerts from the Prometheus rules API, which is also served by Thanos, Mimir and Cortex.
+type rulesClient struct {
+	httpClient
+}
+
+func NewRulesClient(client http.Client, endpoint string, opts ClientOptions) Client {
+	return &rulesClient{
+		httpClient: httpClient{
+			client:   client,
+			endpoint: endpoint,
+			opts:     opts,
+		},
+	}
+}
+
+func (c *rulesClient) GetAlerts(ctx context.Context) (Alerts, error) {
+	endpoint, err := withQuery(c.endpoint, map[string]string{"type": "alert"})
+	if err != nil {
+		return nil, err
+	}
+
+	var resData RulesResponse
+	err = c.getJson(ctx, endpoint, &resData)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := Alerts{}
+	for _, group := range resData.Data.Groups {
+		for _, rule := range group.Rules {
+			if rule.Type != "alerting" {
+				continue
+			}
+
+			for _, alert := range rule.Alerts {
+				alert.RuleGroup = group.Name
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	return alerts, nil
+}
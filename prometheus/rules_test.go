@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const rulesResponse = `{
+	"status": "success",
+	"data": {
+		"groups": [
+			{
+				"name": "node",
+				"file": "/etc/prometheus/rules/node.yaml",
+				"rules": [
+					{"name": "node:cpu:rate", "type": "recording"},
+					{"name": "HostDown", "type": "alerting", "alerts": [{"labels": {"alertname": "HostDown", "instance": "a"}, "state": "firing"}]},
+					{"name": "HostHot", "type": "alerting", "alerts": []}
+				]
+			}
+		]
+	}
+}`
+
+func TestMimirSourceGetsAlertsFromRules(t *testing.T) {
+
+	// Arrange
+	var req *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		_, _ = w.Write([]byte(rulesResponse))
+	}))
+	defer server.Close()
+
+	scrapeConfig := &db.ScrapeConfig{
+		Endpoint:   server.URL,
+		SourceType: db.MimirSource,
+		TenantId:   "tenant-1",
+	}
+
+	client, err := NewClientFromScrapeConfig(scrapeConfig, nil)
+	assert.NoError(t, err)
+
+	// Act
+	alerts, err := client.GetAlerts(context.Background())
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "/prometheus/api/v1/rules", req.URL.Path)
+	assert.Equal(t, "alert", req.URL.Query().Get("type"))
+	assert.Equal(t, "tenant-1", req.Header.Get(TenantHeader))
+
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "HostDown", alerts[0].Labels["alertname"])
+	assert.Equal(t, "node", alerts[0].RuleGroup)
+}
+
+func TestRulesSourceKeepsEndpointPath(t *testing.T) {
+
+	// Arrange
+	var req *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		_, _ = w.Write([]byte(rulesResponse))
+	}))
+	defer server.Close()
+
+	scrapeConfig := &db.ScrapeConfig{
+		Endpoint:   server.URL + "/api/prom/api/v1/rules",
+		SourceType: db.PrometheusRulesSource,
+	}
+
+	client, err := NewClientFromScrapeConfig(scrapeConfig, nil)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = client.GetAlerts(context.Background())
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "/api/prom/api/v1/rules", req.URL.Path)
+}
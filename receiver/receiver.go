@@ -0,0 +1,312 @@
+package receiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/alerts"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/prometheus"
+	"github.com/yukitsune/minialert/scraper"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlertsPath = "/api/v2/alerts"
+
+	// DefaultResolveTimeout is how long an alert is considered firing for when no end time is provided.
+	// This is consistent with Alertmanager's default resolve_timeout.
+	DefaultResolveTimeout = 5 * time.Minute
+
+	maxRequestBytes = 10 << 20
+)
+
+// PostableAlert is an alert as sent by Prometheus to the Alertmanager API.
+type PostableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// Receiver implements the Alertmanager alert ingestion API.
+// Each time alerts are received, or received alerts expire, all active alerts for the scrape config are emitted as a scraper.ScrapeResult.
+type Receiver interface {
+	http.Handler
+	Chan() chan scraper.ScrapeResult
+	Expire(ctx context.Context, now time.Time)
+	Clear(guildId string, configName string)
+}
+
+type key string
+
+func newKey(guildId string, configName string) key {
+	return key(fmt.Sprintf("%s:%s", guildId, configName))
+}
+
+type receivedAlert struct {
+	alert  prometheus.Alert
+	endsAt time.Time
+}
+
+type receivedAlerts struct {
+	guildId    string
+	configName string
+	alerts     map[string]receivedAlert
+}
+
+func (a *receivedAlerts) snapshot() scraper.ScrapeResult {
+	res := scraper.ScrapeResult{
+		GuildId:          a.guildId,
+		ScrapeConfigName: a.configName,
+		Alerts:           prometheus.Alerts{},
+	}
+
+	for _, alert := range a.alerts {
+		res.Alerts = append(res.Alerts, alert.alert)
+	}
+
+	return res
+}
+
+type receiver struct {
+	repo        db.Repo
+	owns        func(guildId string, configName string) bool
+	logger      logrus.FieldLogger
+	resultsChan chan scraper.ScrapeResult
+
+	// sendMu is held from taking a snapshot until it has been sent, so that snapshots for the same scrape config are never sent out of order.
+	// mu is only held while the received alerts are read or updated, so that Clear doesn't wait for results to be handled.
+	sendMu sync.Mutex
+	mu     sync.Mutex
+	alerts map[key]*receivedAlerts
+}
+
+// NewReceiver creates a Receiver which only accepts alerts for the scrape configs that owns returns true for.
+func NewReceiver(repo db.Repo, owns func(guildId string, configName string) bool, logger logrus.FieldLogger) Receiver {
+	return &receiver{
+		repo:        repo,
+		owns:        owns,
+		logger:      logger,
+		resultsChan: make(chan scraper.ScrapeResult),
+		alerts:      make(map[key]*receivedAlerts),
+	}
+}
+
+// NewServer creates a http.Server which serves the Alertmanager API using the given Receiver.
+// Alerts are only accepted while isLeader returns true, as only the leader sends notifications.
+func NewServer(address string, receiver Receiver, isLeader func() bool) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(AlertsPath, leaderOnly(receiver, isLeader))
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// leaderOnly rejects requests while this replica isn't the leader, so that the sender retries against another replica.
+func leaderOnly(handler http.Handler, isLeader func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !isLeader() {
+			http.Error(w, "not the leader", http.StatusServiceUnavailable)
+			return
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func (r *receiver) Chan() chan scraper.ScrapeResult {
+	return r.resultsChan
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := getBearerToken(req)
+	if len(token) == 0 {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	guildId, scrapeConfig, err := handlers.GetScrapeConfigByReceiverToken(req.Context(), r.repo, token)
+	if err != nil {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	// Alerts for scrape configs owned by other replicas are rejected so that the sender retries against them
+	if !r.owns(guildId, scrapeConfig.Name) {
+		http.Error(w, "scrape config is owned by another replica", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctxLogger := r.logger.
+		WithField("guild_id", guildId).
+		WithField("scrape_config_name", scrapeConfig.Name)
+
+	var postableAlerts []PostableAlert
+	err = json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestBytes)).Decode(&postableAlerts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	for _, alert := range postableAlerts {
+		if len(alert.Labels) == 0 {
+			http.Error(w, "alerts must have at least one label", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctxLogger.Debugf("Received %d alerts", len(postableAlerts))
+
+	err = r.receive(req.Context(), guildId, scrapeConfig.Name, postableAlerts, time.Now())
+	if err != nil {
+		ctxLogger.Errorf("Failed to process received alerts: %s", err.Error())
+		http.Error(w, "failed to process alerts", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *receiver) receive(ctx context.Context, guildId string, configName string, postableAlerts []PostableAlert, now time.Time) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	snapshot := r.update(guildId, configName, postableAlerts, now)
+
+	select {
+	case r.resultsChan <- snapshot:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// update adds the received alerts to the scrape config's active alerts, and returns a snapshot of them.
+func (r *receiver) update(guildId string, configName string, postableAlerts []PostableAlert, now time.Time) scraper.ScrapeResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := newKey(guildId, configName)
+	received, ok := r.alerts[k]
+	if !ok {
+		received = &receivedAlerts{
+			guildId:    guildId,
+			configName: configName,
+			alerts:     make(map[string]receivedAlert),
+		}
+
+		r.alerts[k] = received
+	}
+
+	for _, postableAlert := range postableAlerts {
+		fingerprint := alerts.Fingerprint(postableAlert.Labels)
+
+		endsAt := postableAlert.EndsAt
+		if endsAt.IsZero() {
+			endsAt = now.Add(DefaultResolveTimeout)
+		}
+
+		if !endsAt.After(now) {
+			delete(received.alerts, fingerprint)
+			continue
+		}
+
+		startsAt := postableAlert.StartsAt
+		if startsAt.IsZero() {
+			startsAt = now
+		}
+
+		// Keep the original start time if the alert is already firing
+		if existing, ok := received.alerts[fingerprint]; ok && existing.alert.ActiveAt.Before(startsAt) {
+			startsAt = existing.alert.ActiveAt
+		}
+
+		received.alerts[fingerprint] = receivedAlert{
+			alert: prometheus.Alert{
+				ActiveAt:    startsAt,
+				Annotations: postableAlert.Annotations,
+				Labels:      postableAlert.Labels,
+				State:       "firing",
+			},
+			endsAt: endsAt,
+		}
+	}
+
+	return received.snapshot()
+}
+
+// Expire removes any received alerts which have ended, and emits the remaining active alerts for their scrape configs.
+func (r *receiver) Expire(ctx context.Context, now time.Time) {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	for _, snapshot := range r.expire(now) {
+		select {
+		case r.resultsChan <- snapshot:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// expire removes any received alerts which have ended, and returns snapshots of the scrape configs they were removed from.
+func (r *receiver) expire(now time.Time) []scraper.ScrapeResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var snapshots []scraper.ScrapeResult
+	for k, received := range r.alerts {
+		expired := false
+		for fingerprint, alert := range received.alerts {
+			if !alert.endsAt.After(now) {
+				delete(received.alerts, fingerprint)
+				expired = true
+			}
+		}
+
+		if !expired {
+			continue
+		}
+
+		snapshots = append(snapshots, received.snapshot())
+
+		if len(received.alerts) == 0 {
+			delete(r.alerts, k)
+		}
+	}
+
+	return snapshots
+}
+
+func (r *receiver) Clear(guildId string, configName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.alerts, newKey(guildId, configName))
+}
+
+func getBearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,195 @@
+package receiver
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/handlers"
+	"github.com/yukitsune/minialert/scraper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "token"
+
+func setupReceiver(t *testing.T) Receiver {
+	return setupShardedReceiver(t, func(_ string, _ string) bool { return true })
+}
+
+func setupShardedReceiver(t *testing.T, owns func(guildId string, configName string) bool) Receiver {
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+
+	guildConfig := &db.GuildConfig{
+		GuildId: "foo",
+		ScrapeConfigs: []db.ScrapeConfig{
+			{
+				Name:              "bar",
+				ReceiverTokenHash: handlers.HashReceiverToken(testToken),
+			},
+		},
+	}
+
+	err := repo.SetGuildConfig(ctx, guildConfig)
+	assert.NoError(t, err)
+
+	return NewReceiver(repo, owns, logger)
+}
+
+func postAlerts(receiver Receiver, token string, body string) (*httptest.ResponseRecorder, chan scraper.ScrapeResult) {
+	req := httptest.NewRequest(http.MethodPost, AlertsPath, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	results := make(chan scraper.ScrapeResult, 1)
+	go func() {
+		results <- <-receiver.Chan()
+	}()
+
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, req)
+	return rec, results
+}
+
+func TestReceiverRejectsInvalidToken(t *testing.T) {
+
+	// Arrange
+	receiver := setupReceiver(t)
+
+	// Act
+	rec, _ := postAlerts(receiver, "baz", `[{"labels": {"alertname": "foo"}}]`)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestReceiverEmitsReceivedAlerts(t *testing.T) {
+
+	// Arrange
+	receiver := setupReceiver(t)
+
+	// Act
+	rec, results := postAlerts(receiver, testToken, `[{"labels": {"alertname": "foo"}, "annotations": {"summary": "bar"}}]`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	result := <-results
+	assert.Equal(t, "foo", result.GuildId)
+	assert.Equal(t, "bar", result.ScrapeConfigName)
+	assert.Len(t, result.Alerts, 1)
+	assert.Equal(t, "foo", result.Alerts[0].Labels["alertname"])
+	assert.Equal(t, "bar", result.Alerts[0].Annotations["summary"])
+}
+
+func TestReceiverRemovesResolvedAlerts(t *testing.T) {
+
+	// Arrange
+	receiver := setupReceiver(t)
+	_, results := postAlerts(receiver, testToken, `[{"labels": {"alertname": "foo"}}, {"labels": {"alertname": "bar"}}]`)
+	<-results
+
+	// Act
+	endsAt := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	rec, results := postAlerts(receiver, testToken, `[{"labels": {"alertname": "foo"}, "endsAt": "`+endsAt+`"}]`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	result := <-results
+	assert.Len(t, result.Alerts, 1)
+	assert.Equal(t, "bar", result.Alerts[0].Labels["alertname"])
+}
+
+func TestExpireRemovesEndedAlerts(t *testing.T) {
+
+	// Arrange
+	receiver := setupReceiver(t)
+	_, results := postAlerts(receiver, testToken, `[{"labels": {"alertname": "foo"}}]`)
+	<-results
+
+	results = make(chan scraper.ScrapeResult, 1)
+	go func() {
+		results <- <-receiver.Chan()
+	}()
+
+	// Act
+	receiver.Expire(context.Background(), time.Now().Add(DefaultResolveTimeout+time.Second))
+
+	// Assert
+	result := <-results
+	assert.Empty(t, result.Alerts)
+}
+
+func TestServerRejectsAlertsWhenNotLeader(t *testing.T) {
+
+	// Arrange
+	receiver := setupReceiver(t)
+	server := NewServer(":0", receiver, func() bool { return false })
+
+	req := httptest.NewRequest(http.MethodPost, AlertsPath, strings.NewReader(`[{"labels": {"alertname": "foo"}}]`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	// Act
+	rec := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestReceiverRejectsAlertsForScrapeConfigsOwnedByOtherReplicas(t *testing.T) {
+
+	// Arrange
+	receiver := setupShardedReceiver(t, func(_ string, _ string) bool { return false })
+
+	// Act
+	rec, _ := postAlerts(receiver, testToken, `[{"labels": {"alertname": "foo"}}]`)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestClearDoesNotWaitForResultsToBeSent(t *testing.T) {
+
+	// Arrange
+	r := setupReceiver(t).(*receiver)
+
+	received := make(chan struct{})
+	go func() {
+		req := httptest.NewRequest(http.MethodPost, AlertsPath, strings.NewReader(`[{"labels": {"alertname": "foo"}}]`))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		close(received)
+	}()
+
+	// Nothing is reading the results, so once the alerts have been stored the result is waiting to be sent
+	assert.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		_, ok := r.alerts[newKey("foo", "bar")]
+		return ok
+	}, time.Second, time.Millisecond)
+
+	// Act
+	cleared := make(chan struct{})
+	go func() {
+		r.Clear("foo", "bar")
+		close(cleared)
+	}()
+
+	// Assert
+	select {
+	case <-cleared:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Clear waited for the result to be sent")
+	}
+
+	<-r.Chan()
+	<-received
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// jitterRand is seeded separately from the global source, which is always seeded with 1 unless seeded explicitly.
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Schedule describes when a scrape config's endpoint will next be scraped.
+type Schedule struct {
+	// Interval is the scrape config's usual interval.
+	Interval time.Duration
+
+	// Delay is the current delay between scrapes. This is longer than Interval while backing off.
+	Delay time.Duration
+
+	// ConsecutiveFailures is the number of scrapes which have failed since the last successful scrape.
+	ConsecutiveFailures int
+
+	NextScrape time.Time
+}
+
+// BackingOff returns true if scrapes are being delayed because of failures.
+func (s Schedule) BackingOff() bool {
+	return s.Delay > s.Interval
+}
+
+// backoffDelay doubles the interval for each consecutive failure, up to the maximum.
+// The delay is never shorter than the interval, even if the maximum is.
+func backoffDelay(interval time.Duration, maxBackoff time.Duration, consecutiveFailures int) time.Duration {
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+
+	delay := interval
+	for i := 0; i < consecutiveFailures; i++ {
+		if delay >= maxBackoff/2 {
+			return maxBackoff
+		}
+
+		delay *= 2
+	}
+
+	return delay
+}
+
+// startOffset returns a random delay within the interval, so that scrape loops started at the same time don't scrape in lockstep.
+func startOffset(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+
+	return time.Duration(jitterRand.Int63n(int64(interval)))
+}
@@ -0,0 +1,64 @@
+package scraper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelayDoublesUntilMaximum(t *testing.T) {
+
+	// Arrange
+	interval := 30 * time.Second
+	maxBackoff := 5 * time.Minute
+
+	expected := []time.Duration{
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		5 * time.Minute,
+		5 * time.Minute,
+	}
+
+	for failures, expectedDelay := range expected {
+		// Act
+		delay := backoffDelay(interval, maxBackoff, failures)
+
+		// Assert
+		assert.Equal(t, expectedDelay, delay, "failures: %d", failures)
+	}
+}
+
+func TestBackoffDelayIsNeverShorterThanInterval(t *testing.T) {
+
+	// Act
+	delay := backoffDelay(time.Hour, 10*time.Minute, 3)
+
+	// Assert
+	assert.Equal(t, time.Hour, delay)
+}
+
+func TestBackoffDelayDoesNotOverflow(t *testing.T) {
+
+	// Act
+	delay := backoffDelay(time.Minute, 10*time.Minute, 1000)
+
+	// Assert
+	assert.Equal(t, 10*time.Minute, delay)
+}
+
+func TestStartOffsetIsWithinInterval(t *testing.T) {
+
+	// Arrange
+	interval := time.Minute
+
+	for i := 0; i < 100; i++ {
+		// Act
+		offset := startOffset(interval)
+
+		// Assert
+		assert.GreaterOrEqual(t, offset, time.Duration(0))
+		assert.Less(t, offset, interval)
+	}
+}
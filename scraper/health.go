@@ -0,0 +1,99 @@
+package scraper
+
+import (
+	"sync"
+	"time"
+)
+
+// Health describes how recent scrapes of a scrape config's endpoint have gone.
+type Health struct {
+	ConsecutiveFailures int
+	LastSuccess         time.Time
+	LastError           string
+	LastErrorAt         time.Time
+
+	// Down is set once the number of consecutive failures reaches the failure threshold, and cleared after the next successful scrape.
+	Down bool
+}
+
+// HealthTracker keeps track of the Health of each scrape config's endpoint.
+type HealthTracker interface {
+	// RecordSuccess resets the scrape config's failures, and returns its Health from before the success.
+	// Returns true if the endpoint was down and has now recovered.
+	RecordSuccess(guildId string, configName string, now time.Time) (Health, bool)
+
+	// RecordFailure returns the scrape config's updated Health.
+	// Returns true if the failure caused the endpoint to be considered down.
+	RecordFailure(guildId string, configName string, err error, now time.Time) (Health, bool)
+
+	Get(guildId string, configName string) (Health, bool)
+	Clear(guildId string, configName string)
+}
+
+type inMemoryHealthTracker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	health           map[key]Health
+}
+
+func NewHealthTracker(failureThreshold int) HealthTracker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+
+	return &inMemoryHealthTracker{
+		failureThreshold: failureThreshold,
+		health:           make(map[key]Health),
+	}
+}
+
+func (t *inMemoryHealthTracker) RecordSuccess(guildId string, configName string, now time.Time) (Health, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := newKey(guildId, configName)
+	previous := t.health[k]
+
+	t.health[k] = Health{
+		LastSuccess: now,
+		LastError:   previous.LastError,
+		LastErrorAt: previous.LastErrorAt,
+	}
+
+	return previous, previous.Down
+}
+
+func (t *inMemoryHealthTracker) RecordFailure(guildId string, configName string, err error, now time.Time) (Health, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := newKey(guildId, configName)
+	health := t.health[k]
+
+	health.ConsecutiveFailures++
+	health.LastError = err.Error()
+	health.LastErrorAt = now
+
+	wentDown := !health.Down && health.ConsecutiveFailures >= t.failureThreshold
+	if wentDown {
+		health.Down = true
+	}
+
+	t.health[k] = health
+	return health, wentDown
+}
+
+func (t *inMemoryHealthTracker) Get(guildId string, configName string) (Health, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health, ok := t.health[newKey(guildId, configName)]
+	return health, ok
+}
+
+func (t *inMemoryHealthTracker) Clear(guildId string, configName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.health, newKey(guildId, configName))
+}
@@ -0,0 +1,58 @@
+package scraper
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHealthTrackerGoesDownAfterThreshold(t *testing.T) {
+
+	// Arrange
+	healthTracker := NewHealthTracker(3)
+	now := time.Now()
+	err := errors.New("connection refused")
+
+	// Act
+	_, firstDown := healthTracker.RecordFailure("foo", "bar", err, now)
+	_, secondDown := healthTracker.RecordFailure("foo", "bar", err, now)
+	health, thirdDown := healthTracker.RecordFailure("foo", "bar", err, now)
+	_, fourthDown := healthTracker.RecordFailure("foo", "bar", err, now)
+
+	// Assert
+	assert.False(t, firstDown)
+	assert.False(t, secondDown)
+	assert.True(t, thirdDown)
+	assert.False(t, fourthDown, "down should only be reported once")
+
+	assert.True(t, health.Down)
+	assert.Equal(t, 3, health.ConsecutiveFailures)
+	assert.Equal(t, "connection refused", health.LastError)
+}
+
+func TestHealthTrackerRecoversAfterSuccess(t *testing.T) {
+
+	// Arrange
+	healthTracker := NewHealthTracker(1)
+	now := time.Now()
+
+	_, down := healthTracker.RecordFailure("foo", "bar", errors.New("connection refused"), now)
+	assert.True(t, down)
+
+	// Act
+	previous, recovered := healthTracker.RecordSuccess("foo", "bar", now.Add(time.Minute))
+	_, recoveredAgain := healthTracker.RecordSuccess("foo", "bar", now.Add(2*time.Minute))
+
+	// Assert
+	assert.True(t, recovered)
+	assert.False(t, recoveredAgain)
+	assert.Equal(t, 1, previous.ConsecutiveFailures)
+
+	health, ok := healthTracker.Get("foo", "bar")
+	assert.True(t, ok)
+	assert.False(t, health.Down)
+	assert.Zero(t, health.ConsecutiveFailures)
+	assert.Equal(t, now.Add(2*time.Minute), health.LastSuccess)
+	assert.Equal(t, "connection refused", health.LastError)
+}
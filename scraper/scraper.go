@@ -1,23 +1,51 @@
 package scraper
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/yukitsune/minialert/db"
 	"github.com/yukitsune/minialert/prometheus"
+	"reflect"
+	"sync"
 	"time"
 )
 
+// DefaultInterval is used when a scrape config's interval is missing or invalid.
+const DefaultInterval = time.Minute
+
+// ErrNotRunning is returned when stopping a scraper which isn't running, E.g: because it's running on another replica.
+var ErrNotRunning = errors.New("no scraper running")
+
 type ScrapeManager interface {
+	// Start scrapes the scrape config's endpoint in the background until it's stopped, replacing any scraper already running for it.
 	Start(guildId string, config *db.ScrapeConfig)
 	Chan() chan ScrapeResult
+
+	// Restart replaces the scraper for the scrape config with one using its latest settings.
+	// A scraper is started if none was running, E.g: because the scrape config was created using another replica.
 	Restart(guildId string, config *db.ScrapeConfig) error
+
+	// Stop stops scraping the scrape config's endpoint, cancelling any in-flight scrape and waiting for it to finish.
 	Stop(guildId string, configName string) error
+
+	// StopAll stops every scraper, cancelling any in-flight scrapes and waiting for them to finish or for the context to be done.
+	// Scrapers can't be started again afterwards.
+	StopAll(ctx context.Context) error
+
+	// Reconcile makes the running scrapers match the given scrape configs, keyed by guild ID.
+	// Scrapers are started for scrape configs which aren't being scraped, restarted if their scrape config has changed, and stopped if their scrape config isn't given.
+	Reconcile(scrapeConfigs map[string][]db.ScrapeConfig)
+
+	// Schedule returns when the scrape config's endpoint will next be scraped.
+	// Returns false if the scrape config isn't being scraped.
+	Schedule(guildId string, configName string) (Schedule, bool)
 }
 
 type key string
 
-func newQuitterKey(guildId string, configName string) key {
+func newKey(guildId string, configName string) key {
 	return key(fmt.Sprintf("%s:%s", guildId, configName))
 }
 
@@ -25,34 +53,104 @@ type ScrapeResult struct {
 	GuildId          string
 	ScrapeConfigName string
 	Alerts           prometheus.Alerts
+
+	// Err is set if the scrape failed, in which case Alerts is empty.
+	Err error
+}
+
+// scrapeLoop is a scraper running in the background.
+type scrapeLoop struct {
+	guildId string
+	config  db.ScrapeConfig
+	cancel  context.CancelFunc
+
+	// done is closed once the scraper has stopped
+	done chan struct{}
+
+	// schedule is guarded by the scrapeManager's mutex
+	schedule    Schedule
+	hasSchedule bool
 }
 
 type scrapeManager struct {
 	clientFactory prometheus.ClientFactory
+	minInterval   time.Duration
+	maxBackoff    time.Duration
 	logger        logrus.FieldLogger
 	resultsChan   chan ScrapeResult
-	quitters      map[key]func()
+
+	// ctx is the parent of every scraper's context, and is cancelled by StopAll
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	loops map[key]*scrapeLoop
 }
 
-func NewScrapeManager(clientFactory prometheus.ClientFactory, logger logrus.FieldLogger) ScrapeManager {
+// NewScrapeManager creates a ScrapeManager which never scrapes an endpoint more often than the minimum interval.
+// Endpoints which fail to be scraped are scraped less often, up to the maximum backoff.
+func NewScrapeManager(clientFactory prometheus.ClientFactory, minInterval time.Duration, maxBackoff time.Duration, logger logrus.FieldLogger) ScrapeManager {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &scrapeManager{
 		clientFactory: clientFactory,
+		minInterval:   minInterval,
+		maxBackoff:    maxBackoff,
 		logger:        logger,
 		resultsChan:   make(chan ScrapeResult),
-		quitters:      make(map[key]func()),
+		ctx:           ctx,
+		cancel:        cancel,
+		loops:         make(map[key]*scrapeLoop),
 	}
 }
 
 func (m *scrapeManager) Start(guildId string, config *db.ScrapeConfig) {
-	quit := make(chan bool)
-	key := newQuitterKey(guildId, config.Name)
-	m.quitters[key] = func() {
-		quit <- true
+	k := newKey(guildId, config.Name)
+	scrapeLogger := m.logger.WithField("scrape_config_name", config.Name)
+
+	m.mu.Lock()
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		scrapeLogger.Warnln("Scrapers have been stopped, not starting scraper")
+		return
 	}
 
-	scrapeLogger := m.logger.WithField("scrape_config_name", config.Name)
+	existing, hasExisting := m.loops[k]
 
-	go m.scrape(guildId, config, scrapeLogger, quit)
+	// The scraper gets its own copy so that changes made to the config after starting can't race with it
+	ctx, cancel := context.WithCancel(m.ctx)
+	loop := &scrapeLoop{
+		guildId: guildId,
+		config:  *config,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	// Alerts are pushed to the receiver instead, so there's nothing to schedule
+	if !config.ReceiverEnabled() {
+		interval := m.interval(config)
+
+		// The first scrape is offset so that scrape configs started at the same time, E.g: when the bot starts, don't all scrape at once
+		loop.schedule = Schedule{
+			Interval:   interval,
+			Delay:      interval,
+			NextScrape: time.Now().Add(startOffset(interval)),
+		}
+
+		loop.hasSchedule = true
+	}
+
+	schedule := loop.schedule
+	m.loops[k] = loop
+	m.mu.Unlock()
+
+	if hasExisting {
+		existing.stop()
+	}
+
+	go func() {
+		defer close(loop.done)
+		m.scrape(ctx, guildId, &loop.config, loop, schedule, scrapeLogger)
+	}()
 }
 
 func (m *scrapeManager) Chan() chan ScrapeResult {
@@ -60,58 +158,200 @@ func (m *scrapeManager) Chan() chan ScrapeResult {
 }
 
 func (m *scrapeManager) Restart(guildId string, config *db.ScrapeConfig) error {
-	if err := m.Stop(guildId, config.Name); err != nil {
-		return err
-	}
-
 	m.Start(guildId, config)
 	return nil
 }
 
 func (m *scrapeManager) Stop(guildId string, name string) error {
-	key := newQuitterKey(guildId, name)
-	quit, ok := m.quitters[key]
+	if !m.stop(newKey(guildId, name)) {
+		return fmt.Errorf("%w for %s in guild %s", ErrNotRunning, name, guildId)
+	}
+
+	return nil
+}
+
+// stop returns false if there's no scraper running for the key.
+func (m *scrapeManager) stop(k key) bool {
+	m.mu.Lock()
+	loop, ok := m.loops[k]
+	if ok {
+		delete(m.loops, k)
+	}
+	m.mu.Unlock()
+
 	if !ok {
-		return fmt.Errorf("no scrapers running for %s in guild %s", name, guildId)
+		return false
+	}
+
+	loop.stop()
+	return true
+}
+
+func (m *scrapeManager) Reconcile(scrapeConfigs map[string][]db.ScrapeConfig) {
+	desired := make(map[key]*scrapeLoop)
+	for guildId, configs := range scrapeConfigs {
+		for _, config := range configs {
+			desired[newKey(guildId, config.Name)] = &scrapeLoop{
+				guildId: guildId,
+				config:  config,
+			}
+		}
+	}
+
+	var stale []key
+	var changed []*scrapeLoop
+
+	m.mu.Lock()
+	for k := range m.loops {
+		if _, ok := desired[k]; !ok {
+			stale = append(stale, k)
+		}
+	}
+
+	for k, target := range desired {
+		loop, ok := m.loops[k]
+		if !ok || !reflect.DeepEqual(loop.config, target.config) {
+			changed = append(changed, target)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, k := range stale {
+		m.stop(k)
+	}
+
+	// Starting replaces any scraper already running with the old config
+	for _, target := range changed {
+		m.Start(target.guildId, &target.config)
+	}
+}
+
+func (m *scrapeManager) StopAll(ctx context.Context) error {
+	m.mu.Lock()
+	m.cancel()
+	loops := m.loops
+	m.loops = make(map[key]*scrapeLoop)
+	m.mu.Unlock()
+
+	for _, loop := range loops {
+		select {
+		case <-loop.done:
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for scrapers to stop: %s", ctx.Err().Error())
+		}
 	}
 
-	quit()
-	delete(m.quitters, key)
 	return nil
 }
 
-func (m *scrapeManager) scrape(guildId string, config *db.ScrapeConfig, logger logrus.FieldLogger, quitChan chan bool) {
-	dur := time.Duration(config.ScrapeIntervalMinutes) * time.Minute
-	client := m.clientFactory(config)
+func (m *scrapeManager) Schedule(guildId string, configName string) (Schedule, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	loop, ok := m.loops[newKey(guildId, configName)]
+	if !ok || !loop.hasSchedule {
+		return Schedule{}, false
+	}
+
+	return loop.schedule, true
+}
+
+func (m *scrapeManager) setSchedule(loop *scrapeLoop, schedule Schedule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	loop.schedule = schedule
+	loop.hasSchedule = true
+}
+
+func (l *scrapeLoop) stop() {
+	l.cancel()
+	<-l.done
+}
 
+func (m *scrapeManager) scrape(ctx context.Context, guildId string, config *db.ScrapeConfig, loop *scrapeLoop, schedule Schedule, logger logrus.FieldLogger) {
 	ctxLogger := logger.
 		WithField("guild_id", guildId).
 		WithField("scrape_config_name", config.Name)
 
+	// Alerts are pushed to the receiver instead, so there's nothing to scrape
+	if config.ReceiverEnabled() {
+		ctxLogger.Debug("Receiver enabled, scraper idle")
+		<-ctx.Done()
+		ctxLogger.Debug("Scraper stopped")
+		return
+	}
+
+	// Failing to create the client is reported each time a scrape would've occurred, so that it's tracked like any other failure
+	client, clientErr := m.clientFactory(config)
+	if clientErr != nil {
+		ctxLogger.Errorf("Failed to create client: %s", clientErr.Error())
+	}
+
 	ctxLogger.Debug("Scraper started")
 
+	timer := time.NewTimer(time.Until(schedule.NextScrape))
+	defer timer.Stop()
+
 	for {
 		select {
-		case <-time.Tick(dur):
-
-			ctxLogger.Debug("Beginning scrape")
-			alerts, err := client.GetAlerts()
-			if err != nil {
-				ctxLogger.Errorf("Error occurred while scraping: %s", err.Error())
-				continue
-			}
+		case <-timer.C:
 
 			res := ScrapeResult{
 				GuildId:          guildId,
 				ScrapeConfigName: config.Name,
-				Alerts:           alerts,
+				Err:              clientErr,
+			}
+
+			if clientErr == nil {
+				ctxLogger.Debug("Beginning scrape")
+				alerts, err := client.GetAlerts(ctx)
+				if err != nil {
+					ctxLogger.Errorf("Error occurred while scraping: %s", err.Error())
+					res.Err = err
+				}
+
+				res.Alerts = alerts
+			}
+
+			if res.Err != nil {
+				schedule.ConsecutiveFailures++
+			} else {
+				schedule.ConsecutiveFailures = 0
+			}
+
+			schedule.Delay = backoffDelay(schedule.Interval, m.maxBackoff, schedule.ConsecutiveFailures)
+			if schedule.BackingOff() {
+				ctxLogger.Debugf("Backing off, next scrape in %s", schedule.Delay)
 			}
 
-			m.Chan() <- res
+			timer.Reset(schedule.Delay)
+			schedule.NextScrape = time.Now().Add(schedule.Delay)
+			m.setSchedule(loop, schedule)
 
-		case <-quitChan:
+			select {
+			case m.Chan() <- res:
+			case <-ctx.Done():
+				ctxLogger.Debug("Scraper stopped")
+				return
+			}
+
+		case <-ctx.Done():
 			ctxLogger.Debug("Scraper stopped")
 			return
 		}
 	}
 }
+
+func (m *scrapeManager) interval(config *db.ScrapeConfig) time.Duration {
+	interval := config.Interval()
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	if interval < m.minInterval {
+		interval = m.minInterval
+	}
+
+	return interval
+}
@@ -0,0 +1,276 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"github.com/yukitsune/minialert/prometheus"
+	"sync"
+	"testing"
+	"time"
+)
+
+type FakePrometheusClient struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (f *FakePrometheusClient) GetAlerts(_ context.Context) (prometheus.Alerts, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return prometheus.Alerts{}, f.err
+}
+
+func (f *FakePrometheusClient) SetErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+// BlockingPrometheusClient blocks each scrape until it's released.
+type BlockingPrometheusClient struct {
+	Scraping chan struct{}
+	Release  chan struct{}
+}
+
+func (f *BlockingPrometheusClient) GetAlerts(_ context.Context) (prometheus.Alerts, error) {
+	f.Scraping <- struct{}{}
+	<-f.Release
+	return prometheus.Alerts{}, nil
+}
+
+// CancellablePrometheusClient blocks each scrape until the context is done.
+type CancellablePrometheusClient struct {
+	Scraping chan struct{}
+}
+
+func (f *CancellablePrometheusClient) GetAlerts(ctx context.Context) (prometheus.Alerts, error) {
+	f.Scraping <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newTestScrapeManager(client prometheus.Client) ScrapeManager {
+	clientFactory := func(_ *db.ScrapeConfig) (prometheus.Client, error) {
+		return client, nil
+	}
+
+	return NewScrapeManager(clientFactory, 0, time.Second, logrus.New())
+}
+
+func TestScraperBacksOffWhileFailing(t *testing.T) {
+
+	// Arrange
+	client := &FakePrometheusClient{err: errors.New("connection refused")}
+	clientFactory := func(_ *db.ScrapeConfig) (prometheus.Client, error) {
+		return client, nil
+	}
+
+	scrapeManager := NewScrapeManager(clientFactory, 0, 40*time.Millisecond, logrus.New())
+	scrapeConfig := &db.ScrapeConfig{
+		Name:           "bar",
+		ScrapeInterval: "10ms",
+	}
+
+	// Act
+	scrapeManager.Start("foo", scrapeConfig)
+	defer scrapeManager.Stop("foo", "bar")
+
+	for i := 0; i < 3; i++ {
+		res := <-scrapeManager.Chan()
+		assert.Error(t, res.Err)
+	}
+
+	backingOff, ok := scrapeManager.Schedule("foo", "bar")
+	assert.True(t, ok)
+
+	client.SetErr(nil)
+	for res := range scrapeManager.Chan() {
+		if res.Err == nil {
+			break
+		}
+	}
+
+	recovered, ok := scrapeManager.Schedule("foo", "bar")
+	assert.True(t, ok)
+
+	// Assert
+	assert.True(t, backingOff.BackingOff())
+	assert.Equal(t, 3, backingOff.ConsecutiveFailures)
+	assert.Equal(t, 40*time.Millisecond, backingOff.Delay)
+
+	assert.False(t, recovered.BackingOff())
+	assert.Zero(t, recovered.ConsecutiveFailures)
+	assert.Equal(t, 10*time.Millisecond, recovered.Delay)
+}
+
+func TestScrapeManagerIsSafeForConcurrentUse(t *testing.T) {
+
+	// Arrange
+	scrapeManager := newTestScrapeManager(&FakePrometheusClient{})
+
+	// Results aren't of interest, they just need to be drained
+	go func() {
+		for range scrapeManager.Chan() {
+		}
+	}()
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			scrapeConfig := &db.ScrapeConfig{
+				Name:           fmt.Sprintf("config-%d", i%3),
+				ScrapeInterval: "1ms",
+			}
+
+			for j := 0; j < 20; j++ {
+				scrapeManager.Start("foo", scrapeConfig)
+				scrapeManager.Schedule("foo", scrapeConfig.Name)
+				_ = scrapeManager.Restart("foo", scrapeConfig)
+				_ = scrapeManager.Stop("foo", scrapeConfig.Name)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := scrapeManager.StopAll(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, ok := scrapeManager.Schedule("foo", fmt.Sprintf("config-%d", i))
+		assert.False(t, ok)
+	}
+}
+
+func TestStopAllWaitsForInFlightScrapes(t *testing.T) {
+
+	// Arrange
+	client := &BlockingPrometheusClient{
+		Scraping: make(chan struct{}),
+		Release:  make(chan struct{}),
+	}
+
+	scrapeManager := newTestScrapeManager(client)
+	scrapeManager.Start("foo", &db.ScrapeConfig{Name: "bar", ScrapeInterval: "1ms"})
+
+	<-client.Scraping
+
+	// Act
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	timeoutErr := scrapeManager.StopAll(timeoutCtx)
+
+	close(client.Release)
+	err := scrapeManager.StopAll(context.Background())
+
+	// Assert
+	assert.Error(t, timeoutErr, "in-flight scrape should be waited for")
+	assert.NoError(t, err)
+}
+
+func TestStopAllPreventsScrapersStarting(t *testing.T) {
+
+	// Arrange
+	scrapeManager := newTestScrapeManager(&FakePrometheusClient{})
+
+	err := scrapeManager.StopAll(context.Background())
+	assert.NoError(t, err)
+
+	// Act
+	scrapeManager.Start("foo", &db.ScrapeConfig{Name: "bar", ScrapeInterval: "1ms"})
+
+	// Assert
+	err = scrapeManager.Stop("foo", "bar")
+	assert.Error(t, err)
+}
+
+func TestStopAllCancelsInFlightScrapes(t *testing.T) {
+
+	// Arrange
+	client := &CancellablePrometheusClient{
+		Scraping: make(chan struct{}),
+	}
+
+	scrapeManager := newTestScrapeManager(client)
+	scrapeManager.Start("foo", &db.ScrapeConfig{Name: "bar", ScrapeInterval: "1ms"})
+
+	<-client.Scraping
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := scrapeManager.StopAll(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestReconcileStartsRestartsAndStopsScrapers(t *testing.T) {
+
+	// Arrange
+	scrapeManager := newTestScrapeManager(&FakePrometheusClient{})
+	go func() {
+		for range scrapeManager.Chan() {
+		}
+	}()
+
+	defer scrapeManager.StopAll(context.Background())
+
+	scrapeManager.Start("foo", &db.ScrapeConfig{Name: "bar", ScrapeInterval: "1m"})
+	scrapeManager.Start("foo", &db.ScrapeConfig{Name: "baz", ScrapeInterval: "1m"})
+
+	// Act
+	scrapeManager.Reconcile(map[string][]db.ScrapeConfig{
+		"foo": {
+			{Name: "bar", ScrapeInterval: "2m"},
+			{Name: "qux", ScrapeInterval: "1m"},
+		},
+	})
+
+	// Assert
+	bar, barRunning := scrapeManager.Schedule("foo", "bar")
+	_, bazRunning := scrapeManager.Schedule("foo", "baz")
+	_, quxRunning := scrapeManager.Schedule("foo", "qux")
+
+	assert.True(t, barRunning)
+	assert.Equal(t, 2*time.Minute, bar.Interval, "changed scrape config should be restarted")
+	assert.False(t, bazRunning, "removed scrape config should be stopped")
+	assert.True(t, quxRunning, "new scrape config should be started")
+}
+
+func TestRestartStartsScraperWhenNoneIsRunning(t *testing.T) {
+
+	// Arrange
+	scrapeManager := newTestScrapeManager(&FakePrometheusClient{})
+	go func() {
+		for range scrapeManager.Chan() {
+		}
+	}()
+
+	defer scrapeManager.StopAll(context.Background())
+
+	// Act
+	err := scrapeManager.Restart("foo", &db.ScrapeConfig{Name: "bar", ScrapeInterval: "1m"})
+
+	// Assert
+	assert.NoError(t, err)
+
+	_, running := scrapeManager.Schedule("foo", "bar")
+	assert.True(t, running)
+}
@@ -0,0 +1,347 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/yukitsune/minialert/config"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// KeySize is the size of keys in bytes. Keys are used for AES-256.
+	KeySize = 32
+
+	// prefix identifies encrypted values, and the format they were encrypted with.
+	prefix = "enc:v1:"
+
+	separator = "."
+)
+
+// Key is a key-encryption key.
+// Each value is encrypted with its own randomly generated data key, which is then encrypted with the Key and stored alongside the value.
+type Key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewKey creates a Key from KeySize random bytes.
+func NewKey(raw []byte) (*Key, error) {
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(raw))
+	}
+
+	aead, err := newAead(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ID is stored with each value so that values encrypted with a different key can be detected
+	hash := sha256.Sum256(raw)
+
+	return &Key{
+		id:   hex.EncodeToString(hash[:4]),
+		aead: aead,
+	}, nil
+}
+
+// ParseKey creates a Key from its base64 encoded form.
+func ParseKey(encoded string) (*Key, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64 encoded: %s", err.Error())
+	}
+
+	return NewKey(raw)
+}
+
+// ReadKeyFile reads a base64 encoded Key from a file.
+func ReadKeyFile(path string) (*Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %s", err.Error())
+	}
+
+	return ParseKey(string(b))
+}
+
+// LoadKey loads the Key from the config, or from the key file it refers to.
+// Returns nil if no key has been configured.
+func LoadKey(cfg config.Encryption) (*Key, error) {
+	if len(cfg.Key()) > 0 {
+		return ParseKey(cfg.Key())
+	}
+
+	if len(cfg.KeyFile()) > 0 {
+		return ReadKeyFile(cfg.KeyFile())
+	}
+
+	return nil, nil
+}
+
+// GenerateKey generates a new random key, returning it in its base64 encoded form.
+func GenerateKey() (string, error) {
+	raw, err := randomBytes(KeySize)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func (k *Key) Id() string {
+	return k.id
+}
+
+// Encrypt encrypts the plaintext with a new data key.
+func (k *Key) Encrypt(plaintext string) (string, error) {
+	dataKey, err := randomBytes(KeySize)
+	if err != nil {
+		return "", err
+	}
+
+	dataAead, err := newAead(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dataAead, []byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt value: %s", err.Error())
+	}
+
+	wrappedDataKey, err := seal(k.aead, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt data key: %s", err.Error())
+	}
+
+	return prefix + strings.Join([]string{
+		k.id,
+		base64.RawStdEncoding.EncodeToString(wrappedDataKey),
+		base64.RawStdEncoding.EncodeToString(ciphertext),
+	}, separator), nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt.
+// Values which aren't encrypted are returned as-is, so that secrets stored before encryption was enabled can still be read.
+func (k *Key) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), separator)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	if parts[0] != k.id {
+		return "", fmt.Errorf("value was encrypted with a different key (%s)", parts[0])
+	}
+
+	wrappedDataKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed data key: %s", err.Error())
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %s", err.Error())
+	}
+
+	dataKey, err := open(k.aead, wrappedDataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt data key: %s", err.Error())
+	}
+
+	dataAead, err := newAead(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataAead, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %s", err.Error())
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted returns true if the value was encrypted by a Key.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// keyId returns the ID of the Key the value was encrypted with.
+// Returns false if the value isn't encrypted.
+func keyId(value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), separator)
+	return id, true
+}
+
+// IsCurrent returns true if the value was encrypted with this Key.
+func (k *Key) IsCurrent(value string) bool {
+	id, ok := keyId(value)
+	return ok && id == k.id
+}
+
+// Keyring encrypts values with its current Key, and decrypts values encrypted with either its current Key or any of its previous keys.
+// This allows secrets to be read while they're being re-encrypted with a new Key.
+type Keyring struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+func NewKeyring(current *Key, previous ...*Key) *Keyring {
+	keys := map[string]*Key{current.id: current}
+	for _, key := range previous {
+		if _, ok := keys[key.id]; !ok {
+			keys[key.id] = key
+		}
+	}
+
+	return &Keyring{
+		current: current,
+		keys:    keys,
+	}
+}
+
+// LoadKeyring loads the current Key and any previous keys from the config.
+// Returns nil if no key has been configured.
+func LoadKeyring(cfg config.Encryption) (*Keyring, error) {
+	current, err := LoadKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	previousKeys, err := loadPreviousKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		if len(previousKeys) > 0 {
+			return nil, fmt.Errorf("previous keys can only be used alongside a key")
+		}
+
+		return nil, nil
+	}
+
+	return NewKeyring(current, previousKeys...), nil
+}
+
+func loadPreviousKeys(cfg config.Encryption) ([]*Key, error) {
+	var keys []*Key
+	for i, encoded := range cfg.PreviousKeys() {
+		key, err := ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("previous key %d: %s", i+1, err.Error())
+		}
+
+		keys = append(keys, key)
+	}
+
+	for _, path := range cfg.PreviousKeyFiles() {
+		key, err := ReadKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("previous key file %s: %s", path, err.Error())
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Current returns the Key used to encrypt values.
+func (k *Keyring) Current() *Key {
+	return k.current
+}
+
+// Encrypt encrypts the plaintext with the current Key.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	return k.current.Encrypt(plaintext)
+}
+
+// Decrypt decrypts a value encrypted with any of the keyring's keys.
+// Values which aren't encrypted are returned as-is.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	id, ok := keyId(value)
+	if !ok {
+		return value, nil
+	}
+
+	key, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("value was encrypted with an unknown key (%s)", id)
+	}
+
+	return key.Decrypt(value)
+}
+
+// IsCurrent returns true if the value was encrypted with the current Key.
+func (k *Keyring) IsCurrent(value string) bool {
+	return k.current.IsCurrent(value)
+}
+
+// Rotate returns a Keyring which encrypts values with the new Key, and can still decrypt values encrypted with any of this keyring's keys.
+// Secrets which haven't been encrypted yet can be rotated using a nil Keyring.
+func (k *Keyring) Rotate(newKey *Key) *Keyring {
+	if k == nil {
+		return NewKeyring(newKey)
+	}
+
+	var previous []*Key
+	for _, key := range k.keys {
+		previous = append(previous, key)
+	}
+
+	return NewKeyring(newKey, previous...)
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext, prepending the random nonce to the ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce, err := randomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(rand.Reader, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %s", err.Error())
+	}
+
+	return b, nil
+}
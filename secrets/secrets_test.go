@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestKey(t *testing.T) *Key {
+	encoded, err := GenerateKey()
+	assert.NoError(t, err)
+
+	key, err := ParseKey(encoded)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+
+	// Arrange
+	key := newTestKey(t)
+	plaintext := "hunter2"
+
+	// Act
+	ciphertext, err := key.Encrypt(plaintext)
+	assert.NoError(t, err)
+
+	decrypted, err := key.Decrypt(ciphertext)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.True(t, IsEncrypted(ciphertext))
+	assert.NotContains(t, ciphertext, plaintext)
+	assert.Equal(t, plaintext, decrypted)
+}
+
+func TestEncryptUsesNewDataKeyEachTime(t *testing.T) {
+
+	// Arrange
+	key := newTestKey(t)
+
+	// Act
+	first, err := key.Encrypt("hunter2")
+	assert.NoError(t, err)
+
+	second, err := key.Encrypt("hunter2")
+	assert.NoError(t, err)
+
+	// Assert
+	assert.NotEqual(t, first, second)
+}
+
+func TestDecryptFailsWithDifferentKey(t *testing.T) {
+
+	// Arrange
+	key := newTestKey(t)
+	otherKey := newTestKey(t)
+
+	ciphertext, err := key.Encrypt("hunter2")
+	assert.NoError(t, err)
+
+	// Act
+	_, err = otherKey.Decrypt(ciphertext)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestDecryptReturnsPlaintextValues(t *testing.T) {
+
+	// Arrange
+	key := newTestKey(t)
+
+	// Act
+	decrypted, err := key.Decrypt("hunter2")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", decrypted)
+}
+
+func TestParseKeyRejectsShortKeys(t *testing.T) {
+
+	// Act
+	_, err := ParseKey("c2hvcnQ=")
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestKeyringDecryptsValuesEncryptedWithPreviousKeys(t *testing.T) {
+
+	// Arrange
+	previousKey := newTestKey(t)
+	currentKey := newTestKey(t)
+	keyring := NewKeyring(previousKey).Rotate(currentKey)
+
+	previousCiphertext, err := previousKey.Encrypt("hunter2")
+	assert.NoError(t, err)
+
+	unknownCiphertext, err := newTestKey(t).Encrypt("hunter2")
+	assert.NoError(t, err)
+
+	// Act
+	currentCiphertext, err := keyring.Encrypt("hunter3")
+	assert.NoError(t, err)
+
+	decryptedPrevious, err := keyring.Decrypt(previousCiphertext)
+	assert.NoError(t, err)
+
+	decryptedCurrent, err := keyring.Decrypt(currentCiphertext)
+	assert.NoError(t, err)
+
+	_, unknownErr := keyring.Decrypt(unknownCiphertext)
+
+	// Assert
+	assert.Equal(t, "hunter2", decryptedPrevious)
+	assert.Equal(t, "hunter3", decryptedCurrent)
+	assert.Error(t, unknownErr)
+
+	assert.True(t, keyring.IsCurrent(currentCiphertext))
+	assert.False(t, keyring.IsCurrent(previousCiphertext))
+	assert.False(t, keyring.IsCurrent("hunter2"))
+}
@@ -0,0 +1,92 @@
+package shard
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// virtualNodes is how many points each member has on the ring.
+// More points spread keys more evenly between members.
+const virtualNodes = 128
+
+// Ring assigns keys to members using consistent hashing, so that only around 1/N of the keys move when a member joins or leaves.
+type Ring struct {
+	members []string
+	points  []uint64
+	owners  map[uint64]string
+}
+
+// NewRing creates a Ring with the given members. Duplicate members are ignored.
+func NewRing(members []string) *Ring {
+	r := &Ring{
+		owners: make(map[uint64]string),
+	}
+
+	seen := make(map[string]bool)
+	for _, member := range members {
+		if seen[member] {
+			continue
+		}
+
+		seen[member] = true
+		r.members = append(r.members, member)
+
+		for i := 0; i < virtualNodes; i++ {
+			point := hash(fmt.Sprintf("%s#%d", member, i))
+
+			// Collisions are vanishingly rare, but the point is given to the lowest member so that every replica agrees on the owner
+			if owner, ok := r.owners[point]; ok && owner < member {
+				continue
+			}
+
+			if _, ok := r.owners[point]; !ok {
+				r.points = append(r.points, point)
+			}
+
+			r.owners[point] = member
+		}
+	}
+
+	sort.Strings(r.members)
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i] < r.points[j]
+	})
+
+	return r
+}
+
+// Owner returns the member which owns the key, or an empty string if the ring has no members.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= h
+	})
+
+	// Keys after the last point belong to the first
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.owners[r.points[i]]
+}
+
+// Members returns the ring's members, sorted.
+func (r *Ring) Members() []string {
+	return r.members
+}
+
+// Key returns the key used to place a scrape config on the ring.
+func Key(guildId string, configName string) string {
+	return fmt.Sprintf("%s:%s", guildId, configName)
+}
+
+func hash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
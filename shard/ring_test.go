@@ -0,0 +1,82 @@
+package shard
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testKeys(n int) []string {
+	keys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		keys = append(keys, Key(fmt.Sprintf("guild-%d", i%50), fmt.Sprintf("config-%d", i)))
+	}
+
+	return keys
+}
+
+func TestRingSpreadsKeysBetweenMembers(t *testing.T) {
+
+	// Arrange
+	ring := NewRing([]string{"foo", "bar", "baz"})
+	keys := testKeys(3000)
+
+	// Act
+	counts := make(map[string]int)
+	for _, key := range keys {
+		counts[ring.Owner(key)]++
+	}
+
+	// Assert
+	assert.Len(t, counts, 3)
+	for member, count := range counts {
+		assert.InDelta(t, 1000, count, 300, member)
+	}
+}
+
+func TestRingOnlyMovesKeysOwnedByLeavingMember(t *testing.T) {
+
+	// Arrange
+	before := NewRing([]string{"foo", "bar", "baz"})
+	after := NewRing([]string{"foo", "baz"})
+	keys := testKeys(3000)
+
+	for _, key := range keys {
+		// Act
+		previousOwner := before.Owner(key)
+		owner := after.Owner(key)
+
+		// Assert
+		if previousOwner != "bar" {
+			assert.Equal(t, previousOwner, owner, key)
+		}
+	}
+}
+
+func TestRingIsIndependentOfMemberOrder(t *testing.T) {
+
+	// Arrange
+	first := NewRing([]string{"foo", "bar", "baz"})
+	second := NewRing([]string{"baz", "foo", "bar", "foo"})
+
+	// Act
+	for _, key := range testKeys(100) {
+
+		// Assert
+		assert.Equal(t, first.Owner(key), second.Owner(key), key)
+	}
+
+	assert.Equal(t, []string{"bar", "baz", "foo"}, second.Members())
+}
+
+func TestEmptyRingHasNoOwners(t *testing.T) {
+
+	// Arrange
+	ring := NewRing(nil)
+
+	// Act
+	owner := ring.Owner(Key("foo", "bar"))
+
+	// Assert
+	assert.Empty(t, owner)
+}
@@ -0,0 +1,207 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/yukitsune/minialert/config"
+	"github.com/yukitsune/minialert/db"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// leaveTimeout is how long to wait for this replica to be removed from the members when stopping.
+const leaveTimeout = 5 * time.Second
+
+// Membership keeps track of the running replicas, and decides which of them owns each scrape config.
+type Membership interface {
+	// Run sends heartbeats until the context is done, then leaves so that the other replicas can take over this replica's scrape configs.
+	// onRebalance is called each time the scrape configs owned by this replica may have changed.
+	Run(ctx context.Context, onRebalance func())
+	Owns(guildId string, configName string) bool
+	Members() []string
+	Id() string
+}
+
+// NewMembership creates a heartbeat-based Membership if sharding is enabled.
+// Otherwise, the returned Membership owns every scrape config.
+func NewMembership(cfg config.Sharding, repo db.Repo, logger logrus.FieldLogger) (Membership, error) {
+	if !cfg.Enabled() {
+		return NewStaticMembership(), nil
+	}
+
+	id := cfg.Id()
+	if len(id) == 0 {
+		var err error
+		id, err = db.NewReplicaId()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.HeartbeatInterval() <= 0 || cfg.HeartbeatInterval() >= cfg.MemberTimeout() {
+		return nil, fmt.Errorf("sharding heartbeat interval must be positive and shorter than the member timeout")
+	}
+
+	return NewHeartbeatMembership(repo, id, cfg.HeartbeatInterval(), cfg.MemberTimeout(), logger), nil
+}
+
+type heartbeatMembership struct {
+	repo              db.Repo
+	id                string
+	heartbeatInterval time.Duration
+	memberTimeout     time.Duration
+	logger            logrus.FieldLogger
+
+	mu   sync.RWMutex
+	ring *Ring
+}
+
+// NewHeartbeatMembership creates a Membership which sends a heartbeat every heartbeatInterval.
+// Replicas which haven't sent a heartbeat within the member timeout are considered to have left, and their scrape configs are shared between the remaining replicas.
+func NewHeartbeatMembership(repo db.Repo, id string, heartbeatInterval time.Duration, memberTimeout time.Duration, logger logrus.FieldLogger) Membership {
+	return &heartbeatMembership{
+		repo:              repo,
+		id:                id,
+		heartbeatInterval: heartbeatInterval,
+		memberTimeout:     memberTimeout,
+		logger:            logger.WithField("replica_id", id),
+		ring:              NewRing(nil),
+	}
+}
+
+func (m *heartbeatMembership) Run(ctx context.Context, onRebalance func()) {
+	ticker := time.NewTicker(m.heartbeatInterval)
+	defer ticker.Stop()
+
+	var lastHeartbeat time.Time
+
+	for {
+		// A slow database can't keep this replica scraping its shard after the other replicas have taken it over
+		attemptCtx, cancel := context.WithTimeout(ctx, m.heartbeatInterval)
+
+		now := time.Now()
+		err := m.repo.Heartbeat(attemptCtx, m.id, now, m.memberTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				m.logger.Errorf("Failed to send heartbeat: %s", err.Error())
+			}
+		} else {
+			lastHeartbeat = now
+		}
+
+		// If heartbeats can't be sent, this replica gives up its scrape configs before the other replicas take them over
+		heartbeatDeadline := lastHeartbeat.Add(m.memberTimeout - m.heartbeatInterval)
+		if lastHeartbeat.IsZero() || !now.Before(heartbeatDeadline) {
+			m.setMembers(nil, onRebalance)
+		} else if members, err := m.getMembers(attemptCtx, now); err != nil {
+			// The current members are kept, since the other replicas will still be sharing the scrape configs the same way
+			if ctx.Err() == nil {
+				m.logger.Errorf("Failed to get members: %s", err.Error())
+			}
+		} else {
+			m.setMembers(members, onRebalance)
+		}
+
+		cancel()
+
+		select {
+		case <-ticker.C:
+
+		case <-ctx.Done():
+			m.leave(onRebalance)
+			return
+		}
+	}
+}
+
+func (m *heartbeatMembership) getMembers(ctx context.Context, now time.Time) ([]string, error) {
+	members, err := m.repo.GetMembers(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// This replica is always included, in case the heartbeat it just sent isn't visible yet
+	ids := []string{m.id}
+	for _, member := range members {
+		ids = append(ids, member.Id)
+	}
+
+	return ids, nil
+}
+
+func (m *heartbeatMembership) setMembers(members []string, onRebalance func()) {
+	ring := NewRing(members)
+
+	m.mu.Lock()
+	changed := !reflect.DeepEqual(m.ring.Members(), ring.Members())
+	if changed {
+		m.ring = ring
+	}
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	m.logger.Infof("⚖️ Rebalancing between %d replicas", len(ring.Members()))
+	onRebalance()
+}
+
+// leave gives up this replica's scrape configs, then removes it from the members so that the other replicas can take over without waiting for it to time out.
+func (m *heartbeatMembership) leave(onRebalance func()) {
+	m.setMembers(nil, onRebalance)
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaveTimeout)
+	defer cancel()
+
+	err := m.repo.RemoveMember(ctx, m.id)
+	if err != nil {
+		m.logger.Errorf("Failed to leave: %s", err.Error())
+		return
+	}
+
+	m.logger.Infoln("Left the replicas")
+}
+
+func (m *heartbeatMembership) Owns(guildId string, configName string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ring.Owner(Key(guildId, configName)) == m.id
+}
+
+func (m *heartbeatMembership) Members() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ring.Members()
+}
+
+func (m *heartbeatMembership) Id() string {
+	return m.id
+}
+
+type staticMembership struct{}
+
+// NewStaticMembership creates a Membership which owns every scrape config, for when scrape configs aren't sharded.
+func NewStaticMembership() Membership {
+	return &staticMembership{}
+}
+
+func (m *staticMembership) Run(ctx context.Context, _ func()) {
+	<-ctx.Done()
+}
+
+func (m *staticMembership) Owns(_ string, _ string) bool {
+	return true
+}
+
+func (m *staticMembership) Members() []string {
+	return nil
+}
+
+func (m *staticMembership) Id() string {
+	return ""
+}
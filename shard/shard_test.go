@@ -0,0 +1,163 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/yukitsune/minialert/db"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testHeartbeatInterval = 20 * time.Millisecond
+	testMemberTimeout     = 100 * time.Millisecond
+	testWaitFor           = 2 * time.Second
+)
+
+// FailingRepo fails to send heartbeats while it is set to fail.
+type FailingRepo struct {
+	db.Repo
+	failing int32
+}
+
+func (r *FailingRepo) SetFailing(failing bool) {
+	var v int32
+	if failing {
+		v = 1
+	}
+
+	atomic.StoreInt32(&r.failing, v)
+}
+
+func (r *FailingRepo) Heartbeat(ctx context.Context, memberId string, now time.Time, ttl time.Duration) error {
+	if atomic.LoadInt32(&r.failing) == 1 {
+		return errors.New("connection refused")
+	}
+
+	return r.Repo.Heartbeat(ctx, memberId, now, ttl)
+}
+
+func runMembership(ctx context.Context, wg *sync.WaitGroup, membership Membership) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		membership.Run(ctx, func() {})
+	}()
+}
+
+// ownedBy returns true once each key is owned by exactly one of the memberships, and every membership owns at least one key.
+func ownedBy(keys [][2]string, memberships ...Membership) func() bool {
+	return func() bool {
+		owned := make(map[Membership]int)
+		for _, key := range keys {
+			owners := 0
+			for _, membership := range memberships {
+				if membership.Owns(key[0], key[1]) {
+					owners++
+					owned[membership]++
+				}
+			}
+
+			if owners != 1 {
+				return false
+			}
+		}
+
+		return len(owned) == len(memberships)
+	}
+}
+
+func testScrapeConfigs() [][2]string {
+	var keys [][2]string
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		keys = append(keys, [2]string{"guild", key})
+	}
+
+	return keys
+}
+
+func TestHeartbeatMembershipRebalancesWhenReplicasJoinAndLeave(t *testing.T) {
+
+	// Arrange
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	keys := testScrapeConfigs()
+
+	first := NewHeartbeatMembership(repo, "foo", testHeartbeatInterval, testMemberTimeout, logger)
+	second := NewHeartbeatMembership(repo, "bar", testHeartbeatInterval, testMemberTimeout, logger)
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	defer stopFirst()
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+
+	var wg sync.WaitGroup
+	runMembership(firstCtx, &wg, first)
+	assert.Eventually(t, ownedBy(keys, first), testWaitFor, testHeartbeatInterval)
+
+	// Act
+	runMembership(secondCtx, &wg, second)
+
+	// Assert
+	assert.Eventually(t, ownedBy(keys, first, second), testWaitFor, testHeartbeatInterval)
+
+	// Act
+	stopFirst()
+
+	// Assert
+	assert.Eventually(t, ownedBy(keys, second), testWaitFor, testHeartbeatInterval)
+
+	stopSecond()
+	wg.Wait()
+
+	members, err := repo.GetMembers(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, members, "replicas should leave when they stop")
+}
+
+func TestHeartbeatMembershipGivesUpScrapeConfigsWhenHeartbeatsFail(t *testing.T) {
+
+	// Arrange
+	logger := logrus.New()
+	repo := db.SetupInMemoryDatabase(logger)
+	failingRepo := &FailingRepo{Repo: repo}
+	keys := testScrapeConfigs()
+
+	first := NewHeartbeatMembership(failingRepo, "foo", testHeartbeatInterval, testMemberTimeout, logger)
+	second := NewHeartbeatMembership(repo, "bar", testHeartbeatInterval, testMemberTimeout, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	runMembership(ctx, &wg, first)
+	runMembership(ctx, &wg, second)
+	assert.Eventually(t, ownedBy(keys, first, second), testWaitFor, testHeartbeatInterval)
+
+	// Act
+	failingRepo.SetFailing(true)
+
+	// Assert
+	assert.Eventually(t, func() bool { return len(first.Members()) == 0 }, testWaitFor, testHeartbeatInterval)
+	assert.Eventually(t, ownedBy(keys, second), testWaitFor, testHeartbeatInterval)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestStaticMembershipOwnsEverything(t *testing.T) {
+
+	// Arrange
+	membership := NewStaticMembership()
+
+	// Act
+	owns := membership.Owns("foo", "bar")
+
+	// Assert
+	assert.True(t, owns)
+}
@@ -11,14 +11,15 @@ func HasMatching[T any](ts []T, fn func(v T) bool) bool {
 }
 
 func RemoveMatches[T any](s []T, match func(t T) bool) []T {
-	for i, t := range s {
-		if match(t) {
-			s[i] = s[len(s)-1]
-			s = s[:len(s)-1]
+	n := 0
+	for _, t := range s {
+		if !match(t) {
+			s[n] = t
+			n++
 		}
 	}
 
-	return s
+	return s[:n]
 }
 
 func FindMatching[T any](s []T, match func(t T) bool) (*T, bool) {
@@ -40,3 +41,14 @@ func Contains[T comparable](s []T, exp T) bool {
 
 	return false
 }
+
+func Filter[T any](s []T, match func(t T) bool) []T {
+	var filtered []T
+	for _, t := range s {
+		if match(t) {
+			filtered = append(filtered, t)
+		}
+	}
+
+	return filtered
+}